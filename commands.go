@@ -2,13 +2,17 @@ package main
 
 import (
 	"fmt"
-	// "path/filepath"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/ArturC03/r2d2"
 	"github.com/ArturC03/r2d2Styles"
 )
 
-const Version = "0.2.3"
+// Version of the R2D2 Language, release builds set it to their tag with -ldflags "-X main.Version=..."
+var Version = "0.2.3"
 
 // ShowVersion displays the version of the R2D2 Language.
 func ShowVersion() {
@@ -17,7 +21,12 @@ func ShowVersion() {
 
 // Build simulates the compilation process.
 func Build(r2d2Code string, filename string) (err error) {
-	err = r2d2.BuildCode(r2d2Code, filename)
+	code, err := resolvePackageImports(r2d2Code)
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+	err = r2d2.BuildCode(code, filename)
 
 	if err != nil {
 		return err
@@ -27,7 +36,12 @@ func Build(r2d2Code string, filename string) (err error) {
 
 // Run simulates the execution process.
 func Run(r2d2Code string) (err error) {
-	err = r2d2.RunCode(r2d2Code)
+	code, err := resolvePackageImports(r2d2Code)
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+	err = r2d2.RunCode(code)
 
 	if err != nil {
 		return err
@@ -35,9 +49,163 @@ func Run(r2d2Code string) (err error) {
 	return nil
 }
 
-// UnknownCommand shows an error message when an unknown command is entered.
+// Check compiles the code without keeping any output, only reporting errors.
+func Check(r2d2Code string) (err error) {
+	code, err := resolvePackageImports(r2d2Code)
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+	_, err = transpileJsIn(code, "")
+	return err
+}
+
+// TranspileJs compiles the code and returns the generated JavaScript.
+// Relative `use` paths are resolved from the current directory.
+func TranspileJs(r2d2Code string) (js string, err error) {
+	code, err := resolvePackageImports(r2d2Code)
+	if err != nil {
+		return "", err
+	}
+	return transpileJsIn(code, "")
+}
+
+// transpileJsIn compiles code, with its package imports already resolved, as a file
+// of dir, so its relative `use` paths resolve the same as for a file saved there.
+// Code that isn't from a file has an empty dir: it's compiled in a directory of its
+// own, with its relative `use` paths made absolute from the current directory.
+// The temporary files are removed.
+func transpileJsIn(code string, dir string) (js string, err error) {
+	if dir == "" {
+		if dir, err = os.MkdirTemp("", "r2d2-js-"); err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(dir)
+		code = absoluteUsePaths(code)
+	}
+
+	file, err := os.CreateTemp(dir, ".r2d2-js-*.r2d2")
+	if err != nil {
+		return "", err
+	}
+	path := file.Name()
+	jsPath := strings.TrimSuffix(path, ".r2d2") + ".js"
+	defer os.Remove(path)
+	defer os.Remove(jsPath)
+
+	_, err = file.WriteString(code)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := r2d2.BuildJsFile(code, path); err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(jsPath)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// absoluteUsePaths rewrites the relative `use` paths of code naming a file of the
+// current directory to absolute paths, leaving the others to the compiler
+func absoluteUsePaths(code string) string {
+	return useDeclPattern.ReplaceAllStringFunc(code, func(decl string) string {
+		usePath := useDeclPattern.FindStringSubmatch(decl)[1]
+		if filepath.IsAbs(filepath.FromSlash(usePath)) {
+			return decl
+		}
+
+		path, err := filepath.Abs(filepath.FromSlash(usePath))
+		if err != nil {
+			return decl
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			return decl
+		}
+		return strings.Replace(decl, `"`+usePath+`"`, fmt.Sprintf("%q", filepath.ToSlash(path)), 1)
+	})
+}
+
+// WriteJs compiles the code and writes the generated JavaScript to w.
+// Anything the compiler prints goes to stderr so w only gets the JavaScript.
+func WriteJs(r2d2Code string, w io.Writer) (err error) {
+	code, err := resolvePackageImports(r2d2Code)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, ErrorMessage(err.Error()))
+		return err
+	}
+
+	var js string
+	compilerOutput, err := captureStdout(func() error {
+		js, err = transpileJsIn(code, "")
+		return err
+	})
+	fmt.Fprint(os.Stderr, compilerOutput)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, js)
+	return err
+}
+
+// captureStdout runs fn while collecting what it prints, so the compiler can't break the TUI
+func captureStdout(fn func() error) (string, error) {
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", fn()
+	}
+	os.Stdout = w
+
+	captured := make(chan string)
+	go func() {
+		var sb strings.Builder
+		io.Copy(&sb, r)
+		captured <- sb.String()
+	}()
+
+	fnErr := fn()
+
+	w.Close()
+	os.Stdout = old
+	return <-captured, fnErr
+}
+
+// Eval runs a snippet of code, wrapping it into a module when it isn't a full program.
+// Expressions have their value printed.
+func Eval(code string) (err error) {
+	return Run(evalSource(code))
+}
+
+// evalSource turns a snippet into a full program
+func evalSource(code string) string {
+	kind := classifyReplInput(code)
+	if kind == entryTopLevel || kind == entryImport {
+		return code
+	}
+
+	source, _ := newReplSession().source(code, kind)
+	return source
+}
+
+// UnknownCommand shows an error message when an unknown command is entered,
+// pointing at it on the command line and at the closest command.
 func UnknownCommand(cmd string, errArgIndex int) {
 	fmt.Println(("Unknown command: " + cmd))
+	if errArgIndex < len(os.Args) && os.Args[errArgIndex] == cmd {
+		fmt.Println(markArgument(os.Args, errArgIndex))
+	}
+	if s := suggest(cmd, commandNames()); s != "" {
+		fmt.Println(HelpMessage("Did you mean '" + s + "'?"))
+	}
 	fmt.Println(HelpMessage("Run 'r2d2 help' for usage."))
 }
 
@@ -57,7 +225,12 @@ func HelpMessage(message string) string {
 }
 
 func BuildJs(input string, filename string) (err error) {
-	err = r2d2.BuildJsFile(input, filename)
+	code, err := resolvePackageImports(input)
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+	err = r2d2.BuildJsFile(code, filename)
 
 	if err != nil {
 		return err
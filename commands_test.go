@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -62,6 +63,12 @@ func TestUnknownCommand(t *testing.T) {
 			errArgIndex:  1,
 			expectOutput: []string{"Unknown command: test", "Run 'r2d2 help' for usage."},
 		},
+		{
+			name:         "Misspelled command",
+			command:      "biuld",
+			errArgIndex:  1,
+			expectOutput: []string{"Unknown command: biuld", "Did you mean 'build'?"},
+		},
 		{
 			name:         "Empty command",
 			command:      "",
@@ -680,3 +687,105 @@ func TestBuildJs(t *testing.T) {
 		})
 	}
 }
+
+// Test how eval snippets are turned into programs
+func TestEvalSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		contains []string
+	}{
+		{
+			name:     "Expression is printed",
+			code:     "1 + 2",
+			contains: []string{"module repl", "export fn main()", "console.log(1 + 2);"},
+		},
+		{
+			name:     "Statement runs as is",
+			code:     "console.log(\"Hello\");",
+			contains: []string{"module repl", "console.log(\"Hello\");"},
+		},
+		{
+			name:     "Full program is kept",
+			code:     "module Test { export fn main() { } }",
+			contains: []string{"module Test { export fn main() { } }"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := evalSource(tt.code)
+			for _, expected := range tt.contains {
+				if !strings.Contains(source, expected) {
+					t.Errorf("evalSource(%q) should contain %q, got:\n%s", tt.code, expected, source)
+				}
+			}
+		})
+	}
+
+	full := "module Test { export fn main() { } }"
+	if evalSource(full) != full {
+		t.Errorf("evalSource() should not wrap a full program, got:\n%s", evalSource(full))
+	}
+}
+
+// Test that WriteJs writes only the generated JavaScript
+func TestWriteJs(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteJs("module Test { export fn main() { console.log(\"Hello\"); } }", &buf)
+	if err != nil {
+		t.Fatalf("WriteJs() error = %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "Hello") {
+		t.Errorf("WriteJs() output should contain the program, got: %q", buf.String())
+	}
+}
+
+// Test that code not read from a file is compiled outside the working directory
+func TestTranspileJsLeavesNoFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	if _, err := TranspileJs("module Test { export fn main() { } }"); err != nil {
+		t.Fatalf("TranspileJs() error = %v", err)
+	}
+	if err := Check("module Test { export fn main() { } }"); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("the working directory has %d file(s), want none", len(entries))
+	}
+}
+
+// Test that relative `use` paths of typed code keep pointing into the working directory
+func TestAbsoluteUsePaths(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lib", "util.r2d2"), []byte("module Util { }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	util := filepath.ToSlash(filepath.Join(dir, "lib", "util.r2d2"))
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{`use "lib/util.r2d2";`, `use "` + util + `";`},
+		{`use "./lib/util.r2d2";`, `use "` + util + `";`},
+		{`use "` + util + `";`, `use "` + util + `";`},
+		{`use "std.r2d2";`, `use "std.r2d2";`},
+		{`use "lib";`, `use "lib";`},
+	}
+
+	for _, tt := range tests {
+		if got := absoluteUsePaths(tt.code); got != tt.want {
+			t.Errorf("absoluteUsePaths(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,527 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Kinds of values a flag takes, from its type
+const (
+	valueNone    = ""        // a switch
+	valueFile    = "file"    // any file
+	valueDir     = "dir"     // a directory
+	valueChoices = "choices" // one of the words of its type, md|html|json
+	valueOther   = "other"   // anything, nothing to complete
+)
+
+// valueKind tells how the value of the flag is completed
+func (f Flag) valueKind() string {
+	switch {
+	case f.typ == "":
+		return valueNone
+	case f.typ == "file":
+		return valueFile
+	case f.typ == "dir":
+		return valueDir
+	case strings.Contains(f.typ, "|"):
+		return valueChoices
+	}
+	return valueOther
+}
+
+// completionFlags lists the flags of a command for the completions, --help included
+func completionFlags(cmd Command) []Flag {
+	return append(append([]Flag{}, cmd.flags...), Flag{name: "--help", description: "Shows the page of the command"})
+}
+
+// completionWords lists the fixed words the arguments of a command can be
+func completionWords(cmd Command) []string {
+	words := append([]string{}, cmd.choices...)
+	if cmd.args == argCommands {
+		words = append(words, commandNames()...)
+	}
+	return words
+}
+
+// subChoiceNames returns the choices of a command followed by their own words, sorted
+func subChoiceNames(cmd Command) []string {
+	names := make([]string, 0, len(cmd.subChoices))
+	for name := range cmd.subChoices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CompletionScript returns the completion script of a shell, generated from
+// the commands table
+func CompletionScript(shell string) (string, error) {
+	switch shell {
+	case "bash":
+		return bashCompletion(), nil
+	case "zsh":
+		return zshCompletion(), nil
+	case "fish":
+		return fishCompletion(), nil
+	case "powershell":
+		return powershellCompletion(), nil
+	}
+	return "", fmt.Errorf("unknown shell %q, use one of %s", shell, strings.Join(completionShells, ", "))
+}
+
+// Completion prints the completion script of a shell
+func Completion(shell string) error {
+	script, err := CompletionScript(shell)
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		if s := suggest(shell, completionShells); s != "" {
+			fmt.Println(HelpMessage("Did you mean '" + s + "'?"))
+		}
+		return err
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// completionMaxDepth is how many directories deep completionSources looks, so
+// completing in a large tree stays quick
+const completionMaxDepth = 4
+
+// completionSources lists the .r2d2 files of root whose symbols are completed,
+// leaving out vendor/, node_modules/, dot-directories and deeply nested files
+func completionSources(root string) []string {
+	var files []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && path != root {
+			name := d.Name()
+			if strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules" {
+				return filepath.SkipDir
+			}
+			if rel, _ := filepath.Rel(root, path); strings.Count(rel, string(filepath.Separator)) >= completionMaxDepth {
+				return filepath.SkipDir
+			}
+		}
+		if !d.IsDir() && filepath.Ext(path) == ".r2d2" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files
+}
+
+// completionValues returns the values the scripts ask for with 'r2d2 __complete <kind>',
+// from the project of the working directory
+func completionValues(kind string) []string {
+	var values []string
+
+	switch kind {
+	case argSymbols:
+		root, err := findProjectRoot(".")
+		if err != nil {
+			root = "."
+		}
+		paths := completionSources(root)
+		// The std library of the project is already among its files
+		if std := findStdLibrary(root); std != "" && !slices.Contains(paths, std) {
+			paths = append(paths, std)
+		}
+		modules, _ := collectDocModules(paths)
+		for _, module := range modules {
+			values = append(values, module.Name)
+			for _, fn := range module.Functions {
+				values = append(values, module.Name+"."+fn.Name)
+			}
+			for _, field := range module.Fields {
+				values = append(values, module.Name+"."+field.Name)
+			}
+		}
+
+	case argPackages:
+		root, err := findProjectRoot(".")
+		if err != nil {
+			return nil
+		}
+		manifest, err := loadManifest(root)
+		if err != nil {
+			return nil
+		}
+		for name := range manifest.Dependencies {
+			values = append(values, name)
+		}
+
+	case argCommands:
+		values = commandNames()
+	}
+
+	// Modules of the same name in several files come once
+	sort.Strings(values)
+	return slices.Compact(values)
+}
+
+// PrintCompletionValues prints one value per line for the completion scripts
+func PrintCompletionValues(kind string) {
+	for _, value := range completionValues(kind) {
+		fmt.Fprintln(os.Stdout, value)
+	}
+}
+
+// bashCompletion writes the script for bash, read by bash-completion or sourced
+func bashCompletion() string {
+	var sb strings.Builder
+	sb.WriteString(`# bash completion for r2d2, generated by 'r2d2 completion bash'
+
+# .r2d2 files and directories starting with $1
+_r2d2_files() {
+    COMPREPLY+=($(compgen -f -X '!*.r2d2' -- "$1") $(compgen -d -- "$1"))
+}
+
+_r2d2() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    COMPREPLY=()
+
+    if [ "$COMP_CWORD" -eq 1 ]; then
+`)
+	fmt.Fprintf(&sb, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	sb.WriteString(`        return
+    fi
+
+    case "${COMP_WORDS[1]}" in
+`)
+
+	for _, cmd := range commands {
+		fmt.Fprintf(&sb, "    %s)\n", strings.Join(append([]string{cmd.name}, cmd.aliases...), "|"))
+
+		var values []string
+		for _, flag := range cmd.flags {
+			switch flag.valueKind() {
+			case valueFile:
+				values = append(values, fmt.Sprintf("        %s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;", flag.name))
+			case valueDir:
+				values = append(values, fmt.Sprintf("        %s) COMPREPLY=($(compgen -d -- \"$cur\")); return ;;", flag.name))
+			case valueChoices:
+				values = append(values, fmt.Sprintf("        %s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;", flag.name, strings.ReplaceAll(flag.typ, "|", " ")))
+			case valueOther:
+				values = append(values, fmt.Sprintf("        %s) return ;;", flag.name))
+			}
+		}
+		if len(values) > 0 {
+			sb.WriteString("        case \"$prev\" in\n" + strings.Join(values, "\n") + "\n        esac\n")
+		}
+		for _, choice := range subChoiceNames(cmd) {
+			fmt.Fprintf(&sb, "        if [ \"$COMP_CWORD\" -ge 3 ] && [ \"${COMP_WORDS[2]}\" = %s ]; then\n            [ \"$COMP_CWORD\" -eq 3 ] && COMPREPLY=($(compgen -W %q -- \"$cur\"))\n            return\n        fi\n", choice, strings.Join(cmd.subChoices[choice], " "))
+		}
+
+		var flags []string
+		for _, flag := range completionFlags(cmd) {
+			flags = append(flags, flag.name)
+		}
+		if !cmd.takesCode {
+			fmt.Fprintf(&sb, "        if [[ \"$cur\" == -* ]]; then\n            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n            return\n        fi\n", strings.Join(flags, " "))
+		}
+
+		if words := completionWords(cmd); len(words) > 0 {
+			fmt.Fprintf(&sb, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(words, " "))
+		}
+		switch cmd.args {
+		case argFiles:
+			sb.WriteString("        _r2d2_files \"$cur\"\n")
+		case argSymbols:
+			sb.WriteString("        COMPREPLY=($(compgen -W \"$(r2d2 __complete symbols 2>/dev/null)\" -- \"$cur\"))\n        _r2d2_files \"$cur\"\n")
+		case argPackages:
+			sb.WriteString("        COMPREPLY=($(compgen -W \"$(r2d2 __complete packages 2>/dev/null)\" -- \"$cur\"))\n")
+		}
+		sb.WriteString("        ;;\n")
+	}
+
+	sb.WriteString(`    esac
+}
+
+complete -o filenames -F _r2d2 r2d2
+`)
+	return sb.String()
+}
+
+// zshQuote quotes a word for zsh
+func zshQuote(word string) string {
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+// zshDescribed returns _describe entries, name:description
+func zshDescribed(name string, description string) string {
+	return zshQuote(strings.ReplaceAll(name, ":", `\:`) + ":" + description)
+}
+
+// zshCompletion writes the script for zsh, autoloaded from $fpath or sourced
+func zshCompletion() string {
+	var sb strings.Builder
+	sb.WriteString(`#compdef r2d2
+# zsh completion for r2d2, generated by 'r2d2 completion zsh'
+
+_r2d2() {
+    local cur="${words[CURRENT]}" prev="${words[CURRENT-1]}"
+
+    if (( CURRENT == 2 )); then
+        local -a cmds
+        cmds=(
+`)
+	for _, cmd := range commands {
+		sb.WriteString("            " + zshDescribed(cmd.name, cmd.description) + "\n")
+	}
+	sb.WriteString(`        )
+        _describe 'command' cmds
+        return
+    fi
+
+    case "${words[2]}" in
+`)
+
+	for _, cmd := range commands {
+		fmt.Fprintf(&sb, "    %s)\n", strings.Join(append([]string{cmd.name}, cmd.aliases...), "|"))
+
+		var values []string
+		for _, flag := range cmd.flags {
+			switch flag.valueKind() {
+			case valueFile:
+				values = append(values, fmt.Sprintf("        %s) _files; return ;;", flag.name))
+			case valueDir:
+				values = append(values, fmt.Sprintf("        %s) _files -/; return ;;", flag.name))
+			case valueChoices:
+				values = append(values, fmt.Sprintf("        %s) compadd -- %s; return ;;", flag.name, strings.ReplaceAll(flag.typ, "|", " ")))
+			case valueOther:
+				values = append(values, fmt.Sprintf("        %s) return ;;", flag.name))
+			}
+		}
+		if len(values) > 0 {
+			sb.WriteString("        case \"$prev\" in\n" + strings.Join(values, "\n") + "\n        esac\n")
+		}
+		for _, choice := range subChoiceNames(cmd) {
+			fmt.Fprintf(&sb, "        if (( CURRENT >= 4 )) && [[ \"${words[3]}\" == %s ]]; then\n            (( CURRENT == 4 )) && compadd -- %s\n            return\n        fi\n", choice, strings.Join(cmd.subChoices[choice], " "))
+		}
+
+		if !cmd.takesCode {
+			var flags []string
+			for _, flag := range completionFlags(cmd) {
+				flags = append(flags, zshDescribed(flag.name, flag.description))
+			}
+			fmt.Fprintf(&sb, "        if [[ \"$cur\" == -* ]]; then\n            local -a flags\n            flags=(%s)\n            _describe 'flag' flags\n            return\n        fi\n", strings.Join(flags, " "))
+		}
+
+		if words := completionWords(cmd); len(words) > 0 {
+			fmt.Fprintf(&sb, "        compadd -- %s\n", strings.Join(words, " "))
+		}
+		switch cmd.args {
+		case argFiles:
+			sb.WriteString("        _files -g '*.r2d2'\n")
+		case argSymbols:
+			sb.WriteString("        compadd -- ${(f)\"$(r2d2 __complete symbols 2>/dev/null)\"}\n        _files -g '*.r2d2'\n")
+		case argPackages:
+			sb.WriteString("        compadd -- ${(f)\"$(r2d2 __complete packages 2>/dev/null)\"}\n")
+		}
+		sb.WriteString("        ;;\n")
+	}
+
+	sb.WriteString(`    esac
+}
+
+# Autoloaded from $fpath the file is the body of _r2d2, sourced it registers it
+if [ "$funcstack[1]" = "_r2d2" ]; then
+    _r2d2 "$@"
+else
+    compdef _r2d2 r2d2
+fi
+`)
+	return sb.String()
+}
+
+// fishQuote quotes a word for fish
+func fishQuote(word string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(word, `\`, `\\`), "'", `\'`) + "'"
+}
+
+// fishCompletion writes the script for fish, read from its completions directory
+func fishCompletion() string {
+	var sb strings.Builder
+	sb.WriteString(`# fish completion for r2d2, generated by 'r2d2 completion fish'
+
+# Files are only offered where the commands take them
+complete -c r2d2 -f
+`)
+	for _, cmd := range commands {
+		fmt.Fprintf(&sb, "complete -c r2d2 -n __fish_use_subcommand -a %s -d %s\n", cmd.name, fishQuote(cmd.description))
+	}
+
+	for _, cmd := range commands {
+		fmt.Fprintf(&sb, "\n# %s\n", cmd.name)
+		condition := "__fish_seen_subcommand_from " + cmd.name
+		for _, choice := range subChoiceNames(cmd) {
+			fmt.Fprintf(&sb, "complete -c r2d2 -n %s -a %s\n", fishQuote(condition+"; and __fish_seen_subcommand_from "+choice), fishQuote(strings.Join(cmd.subChoices[choice], " ")))
+		}
+		if choices := subChoiceNames(cmd); len(choices) > 0 {
+			// The words of a choice replace those of the command
+			condition += "; and not __fish_seen_subcommand_from " + strings.Join(choices, " ")
+		}
+		condition = fishQuote(condition)
+
+		if !cmd.takesCode {
+			for _, flag := range completionFlags(cmd) {
+				option := "-l " + strings.TrimLeft(flag.name, "-")
+				if !strings.HasPrefix(flag.name, "--") {
+					option = "-s " + strings.TrimLeft(flag.name, "-")
+				}
+				switch flag.valueKind() {
+				case valueFile:
+					option += " -r -F"
+				case valueDir:
+					option += " -x -a '(__fish_complete_directories)'"
+				case valueChoices:
+					option += " -x -a " + fishQuote(strings.ReplaceAll(flag.typ, "|", " "))
+				case valueOther:
+					option += " -x"
+				}
+				fmt.Fprintf(&sb, "complete -c r2d2 -n %s %s -d %s\n", condition, option, fishQuote(flag.description))
+			}
+		}
+
+		if words := completionWords(cmd); len(words) > 0 {
+			fmt.Fprintf(&sb, "complete -c r2d2 -n %s -a %s\n", condition, fishQuote(strings.Join(words, " ")))
+		}
+		switch cmd.args {
+		case argFiles:
+			fmt.Fprintf(&sb, "complete -c r2d2 -n %s -a '(__fish_complete_suffix .r2d2)'\n", condition)
+		case argSymbols:
+			fmt.Fprintf(&sb, "complete -c r2d2 -n %s -a '(r2d2 __complete symbols 2>/dev/null)'\n", condition)
+			fmt.Fprintf(&sb, "complete -c r2d2 -n %s -a '(__fish_complete_suffix .r2d2)'\n", condition)
+		case argPackages:
+			fmt.Fprintf(&sb, "complete -c r2d2 -n %s -a '(r2d2 __complete packages 2>/dev/null)'\n", condition)
+		}
+	}
+	return sb.String()
+}
+
+// powershellQuote quotes a word for PowerShell
+func powershellQuote(word string) string {
+	return "'" + strings.ReplaceAll(word, "'", "''") + "'"
+}
+
+// powershellList returns the words as a PowerShell array
+func powershellList(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = powershellQuote(word)
+	}
+	return "@(" + strings.Join(quoted, ", ") + ")"
+}
+
+// powershellCompletion writes the script for PowerShell, added to $PROFILE
+func powershellCompletion() string {
+	var sb strings.Builder
+	sb.WriteString(`# PowerShell completion for r2d2, generated by 'r2d2 completion powershell'
+
+Register-ArgumentCompleter -Native -CommandName r2d2 -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    # Paths starting with $word: .r2d2 files and directories, any file, or directories
+    function Get-R2D2Paths($word, $kind) {
+        $parent = ''
+        $slash = $word.LastIndexOfAny([char[]]'\/')
+        if ($slash -ge 0) { $parent = $word.Substring(0, $slash + 1) }
+        Get-ChildItem -Path "$word*" -ErrorAction SilentlyContinue |
+            Where-Object { $_.PSIsContainer -or $kind -eq 'file' -or ($kind -eq 'r2d2' -and $_.Extension -eq '.r2d2') } |
+            ForEach-Object { $parent + $_.Name }
+    }
+
+    $words = @($commandAst.CommandElements | ForEach-Object { $_.ToString() })
+    # An empty word being completed isn't one of the elements yet
+    if ($wordToComplete -eq '') { $words += '' }
+    $prev = ''
+    if ($words.Count -ge 2) { $prev = $words[$words.Count - 2] }
+
+    $candidates = @()
+    if ($words.Count -le 2) {
+`)
+	fmt.Fprintf(&sb, "        $candidates = %s\n", powershellList(commandNames()))
+	sb.WriteString(`    } else {
+        switch ($words[1]) {
+`)
+
+	for _, cmd := range commands {
+		fmt.Fprintf(&sb, "            %s {\n", powershellQuote(cmd.name))
+
+		// The value of a flag first, then flags, then the arguments
+		var branches [][2]string // condition and body
+		for _, choice := range subChoiceNames(cmd) {
+			branches = append(branches, [2]string{"$words.Count -ge 4 -and $words[2] -ceq " + powershellQuote(choice), "if ($words.Count -eq 4) { $candidates = " + powershellList(cmd.subChoices[choice]) + " }"})
+		}
+		for _, flag := range cmd.flags {
+			condition := "$prev -ceq " + powershellQuote(flag.name)
+			switch flag.valueKind() {
+			case valueFile:
+				branches = append(branches, [2]string{condition, "$candidates = Get-R2D2Paths $wordToComplete 'file'"})
+			case valueDir:
+				branches = append(branches, [2]string{condition, "$candidates = Get-R2D2Paths $wordToComplete 'dir'"})
+			case valueChoices:
+				branches = append(branches, [2]string{condition, "$candidates = " + powershellList(strings.Split(flag.typ, "|"))})
+			case valueOther:
+				branches = append(branches, [2]string{condition, ""})
+			}
+		}
+
+		if !cmd.takesCode {
+			var flags []string
+			for _, flag := range completionFlags(cmd) {
+				flags = append(flags, flag.name)
+			}
+			branches = append(branches, [2]string{"$wordToComplete.StartsWith('-')", "$candidates = " + powershellList(flags)})
+		}
+
+		var args []string
+		if words := completionWords(cmd); len(words) > 0 {
+			args = append(args, "$candidates += "+powershellList(words))
+		}
+		switch cmd.args {
+		case argFiles:
+			args = append(args, "$candidates += Get-R2D2Paths $wordToComplete 'r2d2'")
+		case argSymbols:
+			args = append(args, "$candidates += @(r2d2 __complete symbols 2>$null)", "$candidates += Get-R2D2Paths $wordToComplete 'r2d2'")
+		case argPackages:
+			args = append(args, "$candidates += @(r2d2 __complete packages 2>$null)")
+		}
+
+		for i, branch := range branches {
+			keyword := "elseif"
+			if i == 0 {
+				keyword = "if"
+			}
+			if branch[1] == "" {
+				fmt.Fprintf(&sb, "                %s (%s) { }\n", keyword, branch[0])
+			} else {
+				fmt.Fprintf(&sb, "                %s (%s) { %s }\n", keyword, branch[0], branch[1])
+			}
+		}
+		switch {
+		case len(args) > 0 && len(branches) > 0:
+			fmt.Fprintf(&sb, "                else { %s }\n", strings.Join(args, "; "))
+		case len(args) > 0:
+			fmt.Fprintf(&sb, "                %s\n", strings.Join(args, "; "))
+		}
+		sb.WriteString("            }\n")
+	}
+
+	sb.WriteString(`        }
+    }
+
+    $candidates | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`)
+	return sb.String()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCompletionScript(t *testing.T) {
+	for _, shell := range completionShells {
+		t.Run(shell, func(t *testing.T) {
+			script, err := CompletionScript(shell)
+			if err != nil {
+				t.Fatalf("CompletionScript() error = %v", err)
+			}
+			for _, cmd := range commands {
+				if !strings.Contains(script, cmd.name) {
+					t.Errorf("script doesn't complete %s", cmd.name)
+				}
+				for _, flag := range cmd.flags {
+					if !strings.Contains(script, strings.TrimLeft(flag.name, "-")) {
+						t.Errorf("script doesn't complete %s %s", cmd.name, flag.name)
+					}
+				}
+			}
+			if !strings.Contains(script, "__complete symbols") || !strings.Contains(script, ".r2d2") {
+				t.Error("script doesn't complete symbols and .r2d2 files")
+			}
+			for _, topic := range langTopicNames() {
+				if !strings.Contains(script, topic) {
+					t.Errorf("script doesn't complete help lang %s", topic)
+				}
+			}
+		})
+	}
+
+	if _, err := CompletionScript("tcsh"); err == nil {
+		t.Error("CompletionScript(tcsh) didn't fail")
+	}
+}
+
+func TestBashCompletion(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "hello.r2d2"), []byte(docTestSource), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644)
+	os.Mkdir(filepath.Join(dir, "src"), 0755)
+	os.WriteFile(filepath.Join(dir, "completion.bash"), []byte(bashCompletion()), 0644)
+
+	tests := []struct {
+		words string
+		want  string
+	}{
+		{"r2d2 bu", "build"},
+		{"r2d2 build ''", "hello.r2d2 src"},
+		{"r2d2 js --out", "--outdir"},
+		{"r2d2 js --outdir ''", "src"},
+		{"r2d2 doc --format ''", "md html json"},
+		{"r2d2 self ''", "uninstall repair"},
+		{"r2d2 help ver", "version"},
+		{"r2d2 help lang ''", strings.Join(langTopicNames(), " ")},
+		{"r2d2 help lang arr", "arrow-if"},
+		{"r2d2 help lang loops ''", ""},
+		{"r2d2 completion f", "fish"},
+		{"r2d2 -b h", "hello.r2d2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.words, func(t *testing.T) {
+			script := "source completion.bash\nCOMP_WORDS=(" + tt.words + ")\nCOMP_CWORD=$((${#COMP_WORDS[@]} - 1))\n_r2d2\necho \"${COMPREPLY[*]}\""
+			cmd := exec.Command("bash", "-c", script)
+			cmd.Dir = dir
+			output, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("bash error = %v: %s", err, output)
+			}
+			if got := strings.TrimSpace(string(output)); got != tt.want {
+				t.Errorf("completions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompletionValues(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("R2D2_STD", "")
+	t.Setenv("HOME", dir)
+	os.WriteFile(filepath.Join(dir, "shapes.r2d2"), []byte(docTestSource), 0644)
+	os.WriteFile(filepath.Join(dir, manifestFile), []byte(`{"dependencies": {"strings": "../strings", "json": "https://example.com/json.tar.gz"}}`), 0644)
+
+	symbols := strings.Join(completionValues(argSymbols), " ")
+	for _, want := range []string{"Circle", "Circle.area", "Circle.radius", "Shape"} {
+		if !strings.Contains(symbols, want) {
+			t.Errorf("symbols %q are missing %s", symbols, want)
+		}
+	}
+
+	if got := strings.Join(completionValues(argPackages), " "); got != "json strings" {
+		t.Errorf("packages = %q, want json strings", got)
+	}
+}
+
+// Test that symbols come from the project root, skipping vendored, hidden and deep files
+func TestCompletionSources(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{
+		"main.r2d2",
+		"lib/util.r2d2",
+		"a/b/c/d/deep.r2d2",
+		"a/b/c/d/e/deeper.r2d2",
+		"vendor/json/json.r2d2",
+		"node_modules/x/x.r2d2",
+		".cache/cached.r2d2",
+		"notes.txt",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, nil, 0644)
+	}
+
+	var got []string
+	for _, file := range completionSources(root) {
+		rel, _ := filepath.Rel(root, file)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	sort.Strings(got)
+	want := []string{"a/b/c/d/deep.r2d2", "lib/util.r2d2", "main.r2d2"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("completionSources() = %v, want %v", got, want)
+	}
+}
+
+// Test that symbols are completed from the project root in one of its subdirectories
+func TestCompletionValuesFromSubdirectory(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("R2D2_STD", "")
+	t.Setenv("HOME", root)
+	os.WriteFile(filepath.Join(root, manifestFile), []byte(`{}`), 0644)
+	os.WriteFile(filepath.Join(root, "shapes.r2d2"), []byte(docTestSource), 0644)
+	os.MkdirAll(filepath.Join(root, "src"), 0755)
+	t.Chdir(filepath.Join(root, "src"))
+
+	if symbols := strings.Join(completionValues(argSymbols), " "); !strings.Contains(symbols, "Circle.area") {
+		t.Errorf("symbols %q are missing Circle.area", symbols)
+	}
+}
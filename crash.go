@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// Source of the program being compiled, kept so a crash can be reproduced
+var currentSource string
+
+// recoverInternalError turns a panic into an "internal compiler error" report.
+// It must be deferred directly by main.
+func recoverInternalError() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	fmt.Println(ErrorMessage(fmt.Sprintf("internal compiler error: %v", r)))
+
+	path, err := writeCrashReport(".", r, debug.Stack())
+	if err != nil {
+		// Fall back to the temp directory when the working directory isn't writable
+		path, err = writeCrashReport(os.TempDir(), r, debug.Stack())
+	}
+
+	if err != nil {
+		fmt.Println(InfoMessage(fmt.Sprintf("Could not write the crash report: %v", err)))
+	} else {
+		fmt.Println(InfoMessage("A reproducer was written to " + path))
+	}
+	fmt.Println(HelpMessage("Please attach it to a bug report at https://github.com/ArturC03/r2d2-cli/issues"))
+
+	os.Exit(2)
+}
+
+// writeCrashReport writes a reproducer file into dir and returns its path.
+// The report is itself a valid R2D2 file: the crash details are comments
+// followed by the source that was being compiled.
+func writeCrashReport(dir string, panicValue any, stack []byte) (string, error) {
+	var sb strings.Builder
+
+	sb.WriteString("// R2D2 internal compiler error\n")
+	sb.WriteString(fmt.Sprintf("// version: %s (%s/%s, %s)\n", Version, runtime.GOOS, runtime.GOARCH, runtime.Version()))
+	sb.WriteString(fmt.Sprintf("// command: r2d2 %s\n", commandLine))
+	sb.WriteString(fmt.Sprintf("// panic: %v\n", panicValue))
+	sb.WriteString("//\n")
+	for _, line := range strings.Split(strings.TrimRight(string(stack), "\n"), "\n") {
+		sb.WriteString("// " + line + "\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(currentSource)
+
+	filename := fmt.Sprintf("r2d2-crash-%s.r2d2", time.Now().Format("20060102-150405"))
+	path := filepath.Join(dir, filename)
+
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test that a crash report is a reproducer carrying the crash details
+func TestWriteCrashReport(t *testing.T) {
+	oldSource := currentSource
+	defer func() { currentSource = oldSource }()
+
+	currentSource = "module Test { export fn main() { } }"
+
+	path, err := writeCrashReport(t.TempDir(), fmt.Errorf("boom"), []byte("goroutine 1 [running]:\nmain.main()"))
+	if err != nil {
+		t.Fatalf("writeCrashReport() error = %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error reading crash report: %v", err)
+	}
+
+	expected := []string{
+		"// R2D2 internal compiler error",
+		"// panic: boom",
+		"// goroutine 1 [running]:",
+		"// version: " + Version,
+		currentSource,
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(content), e) {
+			t.Errorf("crash report should contain %q, got:\n%s", e, content)
+		}
+	}
+
+	if filepath.Ext(path) != ".r2d2" {
+		t.Errorf("crash report should be a .r2d2 file, got %s", path)
+	}
+}
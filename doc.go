@@ -0,0 +1,553 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// docModule is a documented top-level declaration: a module, an interface or a type
+type docModule struct {
+	Name       string        `json:"name"`
+	Kind       string        `json:"kind"`
+	Implements string        `json:"implements,omitempty"`
+	File       string        `json:"file"`
+	Line       int           `json:"line"`
+	Doc        string        `json:"doc,omitempty"`
+	Functions  []docFunction `json:"functions,omitempty"`
+	Fields     []docField    `json:"fields,omitempty"`
+}
+
+// docFunction is a function declared in a module or interface
+type docFunction struct {
+	Name     string     `json:"name"`
+	Params   []docParam `json:"params"`
+	Returns  string     `json:"returns,omitempty"`
+	Exported bool       `json:"exported"`
+	Pseudo   bool       `json:"pseudo,omitempty"`
+	Line     int        `json:"line"`
+	Doc      string     `json:"doc,omitempty"`
+}
+
+// docParam is a function parameter, the type is empty when it wasn't declared
+type docParam struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+// docField is a variable declared in a module, interface or type
+type docField struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Type     string `json:"type,omitempty"`
+	Exported bool   `json:"exported"`
+	Line     int    `json:"line"`
+	Doc      string `json:"doc,omitempty"`
+}
+
+// Signature returns the function declaration as written in R2D2
+func (f docFunction) Signature() string {
+	var sb strings.Builder
+	if f.Exported {
+		sb.WriteString("export ")
+	}
+	if f.Pseudo {
+		sb.WriteString("pseudo ")
+	}
+	sb.WriteString("fn " + f.Name + "(")
+	for i, p := range f.Params {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(p.Name)
+		if p.Type != "" {
+			sb.WriteString(" " + p.Type)
+		}
+	}
+	sb.WriteString(")")
+	if f.Returns != "" {
+		sb.WriteString(" " + f.Returns)
+	}
+	return sb.String()
+}
+
+// Signature returns the field declaration as written in R2D2
+func (f docField) Signature() string {
+	s := f.Kind + " " + f.Name
+	if f.Type != "" {
+		s += " " + f.Type
+	}
+	if f.Exported {
+		s = "export " + s
+	}
+	return s
+}
+
+// docToken is a lexical token with the comment written right before it
+type docToken struct {
+	text string
+	line int
+	doc  string
+}
+
+// tokenizeDoc splits R2D2 source into tokens, skipping strings and JS blocks
+// and attaching every comment block to the token that follows it
+func tokenizeDoc(src string) []docToken {
+	var tokens []docToken
+	runes := []rune(src)
+	line := 1
+
+	var pending []string
+	pendingEnd := 0
+	lastTokenLine := 0
+
+	// advance moves past n runes, counting lines
+	advance := func(i, n int) int {
+		for k := 0; k < n && i < len(runes); k++ {
+			if runes[i] == '\n' {
+				line++
+			}
+			i++
+		}
+		return i
+	}
+
+	emit := func(text string) {
+		doc := ""
+		if len(pending) > 0 && line-pendingEnd <= 1 {
+			doc = strings.Join(pending, "\n")
+		}
+		pending = nil
+		lastTokenLine = line
+		tokens = append(tokens, docToken{text: text, line: line, doc: doc})
+	}
+
+	// skipUntil moves past the next occurrence of end
+	skipUntil := func(i int, end string) int {
+		idx := strings.Index(string(runes[i:]), end)
+		if idx < 0 {
+			return advance(i, len(runes)-i)
+		}
+		return advance(i, len([]rune(string(runes[i:])[:idx]))+len([]rune(end)))
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		rest := string(runes[i:min(i+3, len(runes))])
+
+		switch {
+		case unicode.IsSpace(r):
+			i = advance(i, 1)
+
+		case strings.HasPrefix(rest, "//"):
+			start, end := i, i
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			i = end
+
+			// Trailing comments describe their own line, not the next declaration
+			if line == lastTokenLine {
+				continue
+			}
+			if len(pending) > 0 && line-pendingEnd > 1 {
+				pending = nil
+			}
+			pending = append(pending, strings.TrimSpace(strings.TrimPrefix(string(runes[start:end])[2:], "/")))
+			pendingEnd = line
+
+		case strings.HasPrefix(rest, "/*"):
+			start := i
+			startLine := line
+			i = skipUntil(i+2, "*/")
+			if startLine == lastTokenLine {
+				continue
+			}
+			text := strings.TrimSuffix(strings.TrimPrefix(string(runes[start:i]), "/*"), "*/")
+			if len(pending) > 0 && startLine-pendingEnd > 1 {
+				pending = nil
+			}
+			for _, l := range strings.Split(text, "\n") {
+				l = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(l), "*"))
+				if l != "" {
+					pending = append(pending, l)
+				}
+			}
+			pendingEnd = line
+
+		case strings.HasPrefix(rest, "<<"):
+			emit("<<>>")
+			i = skipUntil(i+2, ">>")
+
+		case rest == `"""`:
+			emit(`""`)
+			i = skipUntil(i+3, `"""`)
+
+		case r == '"':
+			emit(`""`)
+			i = advance(i, 1)
+			for i < len(runes) && runes[i] != '"' && runes[i] != '\n' {
+				if runes[i] == '\\' {
+					i = advance(i, 1)
+				}
+				i = advance(i, 1)
+			}
+			i = advance(i, 1)
+
+		case unicode.IsLetter(r) || r == '_' || unicode.IsDigit(r):
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			emit(string(runes[i:end]))
+			i = end
+
+		default:
+			emit(string(r))
+			i = advance(i, 1)
+		}
+	}
+
+	return tokens
+}
+
+// docParser walks the tokens of one file
+type docParser struct {
+	tokens []docToken
+	pos    int
+	file   string
+}
+
+func (p *docParser) peek(offset int) string {
+	if p.pos+offset < len(p.tokens) {
+		return p.tokens[p.pos+offset].text
+	}
+	return ""
+}
+
+func (p *docParser) next() docToken {
+	if p.pos < len(p.tokens) {
+		p.pos++
+		return p.tokens[p.pos-1]
+	}
+	return docToken{}
+}
+
+// skipBalanced skips a bracketed group starting at the current token
+func (p *docParser) skipBalanced() {
+	depth := 0
+	for p.pos < len(p.tokens) {
+		switch p.next().text {
+		case "{", "(", "[":
+			depth++
+		case "}", ")", "]":
+			depth--
+		}
+		if depth <= 0 {
+			return
+		}
+	}
+}
+
+// skipStatement skips to the end of the current statement
+func (p *docParser) skipStatement() {
+	for p.pos < len(p.tokens) {
+		switch p.peek(0) {
+		case ";":
+			p.next()
+			return
+		case "}":
+			return
+		case "{", "(", "[":
+			p.skipBalanced()
+		default:
+			p.next()
+		}
+	}
+}
+
+// typeUntil joins the tokens of a type expression up to one of the stop tokens
+func (p *docParser) typeUntil(stops ...string) string {
+	var sb strings.Builder
+	depth := 0
+	for p.pos < len(p.tokens) {
+		tok := p.peek(0)
+		if depth == 0 {
+			for _, stop := range stops {
+				if tok == stop {
+					return sb.String()
+				}
+			}
+		}
+		switch tok {
+		case "<", "[":
+			depth++
+		case ">", "]":
+			depth--
+		}
+		p.next()
+		if tok == "," {
+			sb.WriteString(", ")
+		} else {
+			sb.WriteString(tok)
+		}
+	}
+	return sb.String()
+}
+
+// parseDocFile extracts the documented declarations of an R2D2 source
+func parseDocFile(file string, src string) []docModule {
+	p := &docParser{tokens: tokenizeDoc(src), file: file}
+	var modules []docModule
+
+	for p.pos < len(p.tokens) {
+		start := p.tokens[p.pos]
+		tok := p.peek(0)
+
+		if tok == "export" && p.peek(1) == "type" {
+			p.next()
+			tok = "type"
+		}
+
+		switch tok {
+		case "module", "interface", "type":
+			p.next()
+			module := docModule{Kind: tok, Name: p.next().text, File: file, Line: start.line, Doc: start.doc}
+
+			// Both `Name::Parent` and `Name implements Parent` are used
+			if p.peek(0) == ":" && p.peek(1) == ":" {
+				p.pos += 2
+				module.Implements = p.next().text
+			} else if p.peek(0) == "implements" {
+				p.next()
+				module.Implements = p.next().text
+			}
+
+			if p.peek(0) != "{" {
+				continue
+			}
+			p.next()
+			p.parseMembers(&module)
+			modules = append(modules, module)
+
+		case "{", "(", "[":
+			p.skipBalanced()
+
+		default:
+			p.next()
+		}
+	}
+
+	return modules
+}
+
+// parseMembers reads functions and fields until the closing brace of a declaration
+func (p *docParser) parseMembers(module *docModule) {
+	for p.pos < len(p.tokens) {
+		start := p.tokens[p.pos]
+		if start.text == "}" {
+			p.next()
+			return
+		}
+
+		exported, pseudo := false, false
+		for p.peek(0) == "export" || p.peek(0) == "pseudo" {
+			if p.next().text == "export" {
+				exported = true
+			} else {
+				pseudo = true
+			}
+		}
+
+		switch p.peek(0) {
+		case "fn":
+			p.next()
+			fn := docFunction{Name: p.next().text, Exported: exported, Pseudo: pseudo, Line: start.line, Doc: start.doc}
+			if p.peek(0) == "(" {
+				p.next()
+				fn.Params = p.parseParams()
+			}
+			fn.Returns = p.typeUntil("{", ";", "}")
+			if p.peek(0) == "{" {
+				p.skipBalanced()
+			} else if p.peek(0) == ";" {
+				p.next()
+			}
+			module.Functions = append(module.Functions, fn)
+
+		case "var", "let", "const":
+			field := docField{Kind: p.next().text, Name: p.next().text, Exported: exported, Line: start.line, Doc: start.doc}
+			field.Type = p.typeUntil("=", ";", "}")
+			p.skipStatement()
+			module.Fields = append(module.Fields, field)
+
+		case "{", "(", "[":
+			p.skipBalanced()
+
+		default:
+			p.next()
+		}
+	}
+}
+
+// parseParams reads a parameter list up to and including the closing parenthesis
+func (p *docParser) parseParams() []docParam {
+	params := []docParam{}
+	for p.pos < len(p.tokens) && p.peek(0) != ")" {
+		if p.peek(0) == "," {
+			p.next()
+			continue
+		}
+		param := docParam{Name: p.next().text}
+		param.Type = p.typeUntil(",", ")")
+		params = append(params, param)
+	}
+	p.next()
+	return params
+}
+
+// collectDocModules parses every .r2d2 file of the paths, sorted by module name
+func collectDocModules(paths []string) ([]docModule, error) {
+	var modules []docModule
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("file not found: %v", path)
+		}
+
+		var files []string
+		if info.IsDir() {
+			err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() && p != path && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+					return filepath.SkipDir
+				}
+				if !d.IsDir() && filepath.Ext(p) == ".r2d2" {
+					files = append(files, p)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			files = append(files, path)
+		}
+
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			modules = append(modules, parseDocFile(filepath.ToSlash(file), string(content))...)
+		}
+	}
+
+	sort.SliceStable(modules, func(i, j int) bool {
+		return modules[i].Name < modules[j].Name
+	})
+	return modules, nil
+}
+
+// findDocSymbol looks up "module" or "module.member" in the collected modules
+func findDocSymbol(modules []docModule, symbol string) (*docModule, *docFunction, *docField) {
+	moduleName, member, _ := strings.Cut(symbol, ".")
+
+	for i := range modules {
+		if modules[i].Name != moduleName {
+			continue
+		}
+		module := &modules[i]
+		if member == "" {
+			return module, nil, nil
+		}
+		for j := range module.Functions {
+			if module.Functions[j].Name == member {
+				return module, &module.Functions[j], nil
+			}
+		}
+		for j := range module.Fields {
+			if module.Fields[j].Name == member {
+				return module, nil, &module.Fields[j]
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+// isDocSymbol reports whether arg looks like "module" or "module.member" rather than a path
+func isDocSymbol(arg string) bool {
+	if _, err := os.Stat(arg); err == nil {
+		return false
+	}
+	for _, part := range strings.Split(arg, ".") {
+		if part == "" {
+			return false
+		}
+		for _, r := range part {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// RunDoc generates documentation for the paths, or shows one symbol in the terminal
+// when the first argument is "module" or "module.member".
+func RunDoc(args []string, format string, outDir string) (err error) {
+	if len(args) > 0 && isDocSymbol(args[0]) {
+		paths := args[1:]
+		if len(paths) == 0 {
+			paths = []string{"."}
+		}
+
+		modules, err := collectDocModules(paths)
+		if err != nil {
+			fmt.Println(ErrorMessage(err.Error()))
+			return err
+		}
+
+		module, fn, field := findDocSymbol(modules, args[0])
+		if module == nil || (strings.Contains(args[0], ".") && fn == nil && field == nil) {
+			err = fmt.Errorf("no documentation found for %s", args[0])
+			fmt.Println(ErrorMessage(err.Error()))
+			return err
+		}
+
+		fmt.Print(renderDocSymbol(module, fn, field))
+		return nil
+	}
+
+	paths := args
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	modules, err := collectDocModules(paths)
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+	if len(modules) == 0 {
+		err = fmt.Errorf("no modules, interfaces or types found in %s", strings.Join(paths, ", "))
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+
+	written, err := WriteDocs(modules, format, outDir)
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+
+	fmt.Println(InfoMessage(fmt.Sprintf("Documented %d declarations in %d files under %s", len(modules), len(written), outDir)))
+	return nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const docTestSource = `use "std.r2d2";
+
+// Shapes that know their area
+interface Shape {
+    fn area() number;
+}
+
+/*
+ * A circle.
+ * Has a radius.
+ */
+module Circle implements Shape {
+    // Radius in meters
+    export var radius number = 1;
+    let hidden = "}"; // a brace inside a string
+
+    // Computes the area
+    export fn area() number {
+        if radius > 0 { return 3.14 * radius * radius; }
+        return 0;
+    }
+
+    fn scale(factor number, times) Circle {
+        @js << return { radius: radius * factor }; >>;
+    }
+}
+
+module Square::Shape {
+    export fn area() number { return 1; }
+}
+`
+
+// Test that declarations, signatures and doc comments are extracted
+func TestParseDocFile(t *testing.T) {
+	modules := parseDocFile("shapes.r2d2", docTestSource)
+	if len(modules) != 3 {
+		t.Fatalf("parseDocFile() found %d declarations, want 3: %+v", len(modules), modules)
+	}
+
+	shape, circle, square := modules[0], modules[1], modules[2]
+
+	if shape.Kind != "interface" || shape.Name != "Shape" || shape.Doc != "Shapes that know their area" {
+		t.Errorf("interface = %+v", shape)
+	}
+	if len(shape.Functions) != 1 || shape.Functions[0].Returns != "number" {
+		t.Errorf("interface functions = %+v", shape.Functions)
+	}
+
+	if circle.Implements != "Shape" || circle.Doc != "A circle.\nHas a radius." || circle.Line != 12 {
+		t.Errorf("module = %+v", circle)
+	}
+	if len(circle.Fields) != 2 || circle.Fields[0].Signature() != "export var radius number" || circle.Fields[0].Doc != "Radius in meters" {
+		t.Errorf("module fields = %+v", circle.Fields)
+	}
+	if circle.Fields[1].Doc != "" {
+		t.Errorf("trailing comments shouldn't become docs, got %q", circle.Fields[1].Doc)
+	}
+
+	if len(circle.Functions) != 2 {
+		t.Fatalf("module functions = %+v", circle.Functions)
+	}
+	if circle.Functions[0].Signature() != "export fn area() number" || circle.Functions[0].Doc != "Computes the area" {
+		t.Errorf("area = %+v", circle.Functions[0])
+	}
+	if circle.Functions[1].Signature() != "fn scale(factor number, times) Circle" {
+		t.Errorf("scale signature = %q", circle.Functions[1].Signature())
+	}
+
+	if square.Implements != "Shape" || len(square.Functions) != 1 {
+		t.Errorf("Name::Parent module = %+v", square)
+	}
+}
+
+// Test symbol lookup for the terminal viewer
+func TestFindDocSymbol(t *testing.T) {
+	modules := parseDocFile("shapes.r2d2", docTestSource)
+
+	if module, fn, field := findDocSymbol(modules, "Circle"); module == nil || fn != nil || field != nil {
+		t.Error("findDocSymbol(Circle) should find the module")
+	}
+	if _, fn, _ := findDocSymbol(modules, "Circle.area"); fn == nil || fn.Name != "area" {
+		t.Error("findDocSymbol(Circle.area) should find the function")
+	}
+	if _, _, field := findDocSymbol(modules, "Circle.radius"); field == nil {
+		t.Error("findDocSymbol(Circle.radius) should find the variable")
+	}
+	if module, _, _ := findDocSymbol(modules, "Triangle"); module != nil {
+		t.Error("findDocSymbol(Triangle) should find nothing")
+	}
+
+	output := renderDocSymbol(findDocSymbol(modules, "Circle.area"))
+	for _, expected := range []string{"Circle.area", "area", "Computes the area", "shapes.r2d2:18"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("renderDocSymbol() should contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+// Test that symbols and paths are told apart
+func TestIsDocSymbol(t *testing.T) {
+	tests := map[string]bool{
+		"std":           true,
+		"std.print":     true,
+		"std.r2d2":      false, // exists in the repository root
+		"src/":          false,
+		"std..print":    false,
+		"examples/cli":  false,
+		"Circle.area_2": true,
+	}
+
+	for arg, expected := range tests {
+		if got := isDocSymbol(arg); got != expected {
+			t.Errorf("isDocSymbol(%q) = %v, want %v", arg, got, expected)
+		}
+	}
+}
+
+// Test every output format
+func TestWriteDocs(t *testing.T) {
+	modules := parseDocFile("shapes.r2d2", docTestSource)
+
+	tests := []struct {
+		format   string
+		file     string
+		contains []string
+	}{
+		{DocFormatMarkdown, "index.md", []string{"[Circle](shapes.Circle.md)", "A circle."}},
+		{DocFormatMarkdown, "shapes.Circle.md", []string{"# module Circle", "Implements [Shape](shapes.Shape.md)", "export fn area() number", "- returns: [Circle](shapes.Circle.md)"}},
+		{DocFormatHTML, "index.html", []string{"<a href=\"shapes.Circle.html\">Circle</a>", "<!DOCTYPE html>"}},
+		{DocFormatHTML, "shapes.Circle.html", []string{"<a href=\"shapes.Shape.html\">Shape</a>", "export fn area() number"}},
+		{DocFormatJSON, "docs.json", []string{"\"name\": \"Circle\"", "\"implements\": \"Shape\""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format+"/"+tt.file, func(t *testing.T) {
+			outDir := t.TempDir()
+			if _, err := WriteDocs(modules, tt.format, outDir); err != nil {
+				t.Fatalf("WriteDocs() error = %v", err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(outDir, tt.file))
+			if err != nil {
+				t.Fatalf("WriteDocs() should write %s: %v", tt.file, err)
+			}
+			for _, expected := range tt.contains {
+				if !strings.Contains(string(content), expected) {
+					t.Errorf("%s should contain %q, got:\n%s", tt.file, expected, content)
+				}
+			}
+		})
+	}
+
+	var decoded []docModule
+	outDir := t.TempDir()
+	WriteDocs(modules, DocFormatJSON, outDir)
+	content, _ := os.ReadFile(filepath.Join(outDir, "docs.json"))
+	if err := json.Unmarshal(content, &decoded); err != nil || len(decoded) != len(modules) {
+		t.Errorf("docs.json should decode back to %d modules: %v", len(modules), err)
+	}
+
+	if _, err := WriteDocs(modules, "pdf", t.TempDir()); err == nil {
+		t.Error("WriteDocs() should reject unknown formats")
+	}
+}
+
+// Test that modules with the same name in different files get a page each,
+// and that links go to the module of the same file
+func TestWriteDocsSameName(t *testing.T) {
+	modules := append(
+		parseDocFile("examples/cli/demo.r2d2", "interface Console { }\nmodule Main::Console { }"),
+		parseDocFile("examples/web/demo.r2d2", "interface Console { }\nmodule Main::Console { }")...,
+	)
+	pages := newDocPages(modules)
+
+	if got := pages.page(1); got != "cli-demo.Main" {
+		t.Errorf("page of examples/cli/demo.r2d2 Main = %q, want cli-demo.Main", got)
+	}
+
+	outDir := t.TempDir()
+	written, err := WriteDocs(modules, DocFormatMarkdown, outDir)
+	if err != nil || len(written) != 5 {
+		t.Fatalf("WriteDocs() wrote %d pages, want 5: %v", len(written), err)
+	}
+
+	index, _ := os.ReadFile(filepath.Join(outDir, "index.md"))
+	for _, expected := range []string{"[Main](cli-demo.Main.md)", "[Main](web-demo.Main.md)"} {
+		if !strings.Contains(string(index), expected) {
+			t.Errorf("index.md should contain %q, got:\n%s", expected, index)
+		}
+	}
+	page, _ := os.ReadFile(filepath.Join(outDir, "web-demo.Main.md"))
+	if !strings.Contains(string(page), "Implements [Console](web-demo.Console.md)") {
+		t.Errorf("web-demo.Main.md should link the Console of its own file, got:\n%s", page)
+	}
+
+	// A single file keeps its own name
+	if got := newDocPages(parseDocFile("shapes.r2d2", docTestSource)).page(1); got != "shapes.Circle" {
+		t.Errorf("page of shapes.r2d2 Circle = %q", got)
+	}
+}
+
+// Test that commented-out code isn't documented
+func TestParseDocFileComments(t *testing.T) {
+	src := `// module Fake { fn a() { } }
+/* interface Fake { fn b(); } */
+module Real {
+  // export fn print(s string) {
+  //   @js "console.log(s);";
+  // }
+
+  export fn shown() { } // fn trailing() { }
+  /*
+   * export fn hidden() { }
+   */
+}
+`
+	modules := parseDocFile("real.r2d2", src)
+	if len(modules) != 1 || modules[0].Name != "Real" {
+		t.Fatalf("parseDocFile() = %+v, want only Real", modules)
+	}
+	if fns := modules[0].Functions; len(fns) != 1 || fns[0].Name != "shown" || fns[0].Line != 8 {
+		t.Errorf("functions = %+v, want only shown on line 8", fns)
+	}
+}
+
+// Test that the standard library can be documented
+func TestCollectDocModulesStd(t *testing.T) {
+	modules, err := collectDocModules([]string{"std.r2d2"})
+	if err != nil {
+		t.Fatalf("collectDocModules() error = %v", err)
+	}
+
+	if _, fn, _ := findDocSymbol(modules, "std.print"); fn == nil || fn.Signature() != "export fn print(s string)" {
+		t.Errorf("std.print should be documented, got %+v", fn)
+	}
+	if _, fn, _ := findDocSymbol(modules, "std.trim"); fn == nil || fn.Signature() != "export fn trim(s string) string" {
+		t.Errorf("std.trim, the example of 'r2d2 help doc', should be documented, got %+v", fn)
+	}
+}
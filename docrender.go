@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Output formats of r2d2 doc
+const (
+	DocFormatMarkdown = "md"
+	DocFormatHTML     = "html"
+	DocFormatJSON     = "json"
+)
+
+// Matches identifiers inside signatures, to link them to their module
+var docIdentPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// WriteDocs renders the modules in the given format into outDir.
+// Markdown and HTML get one page per module and an index, JSON a single docs.json.
+func WriteDocs(modules []docModule, format string, outDir string) ([]string, error) {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, err
+	}
+
+	names := newDocPages(modules)
+	pages := make(map[string]string)
+	switch format {
+	case DocFormatMarkdown:
+		pages["index.md"] = markdownDocIndex(names)
+		for i, module := range modules {
+			pages[names.page(i)+".md"] = markdownDocPage(module, names)
+		}
+
+	case DocFormatHTML:
+		pages["index.html"] = htmlDocPage("Index", htmlDocIndex(names))
+		for i, module := range modules {
+			pages[names.page(i)+".html"] = htmlDocPage(module.Name, htmlDocModule(module, names))
+		}
+
+	case DocFormatJSON:
+		content, err := json.MarshalIndent(modules, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		pages["docs.json"] = string(content) + "\n"
+
+	default:
+		return nil, fmt.Errorf("unknown doc format %q (use md, html or json)", format)
+	}
+
+	var written []string
+	for name, content := range pages {
+		path := filepath.Join(outDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// docPages names the page of every module after its file and its name, since
+// modules of different files can share a name, like the Main of each program
+type docPages struct {
+	modules []docModule
+	names   []string
+}
+
+// newDocPages names the pages of modules. The directory shared by every file is
+// left out, so documenting examples/ gives cli-demo.Main rather than examples-cli-demo.Main.
+func newDocPages(modules []docModule) docPages {
+	var common []string
+	for i, module := range modules {
+		var dir []string
+		if d := path.Dir(module.File); d != "." {
+			dir = strings.Split(d, "/")
+		}
+		if i == 0 {
+			common = dir
+			continue
+		}
+		n := 0
+		for n < len(common) && n < len(dir) && common[n] == dir[n] {
+			n++
+		}
+		common = common[:n]
+	}
+
+	names := make([]string, len(modules))
+	for i, module := range modules {
+		parts := strings.Split(strings.TrimSuffix(module.File, path.Ext(module.File)), "/")
+		names[i] = strings.Join(parts[len(common):], "-") + "." + module.Name
+	}
+	return docPages{modules: modules, names: names}
+}
+
+// page returns the page name of the i-th module, without extension
+func (p docPages) page(i int) string {
+	return p.names[i]
+}
+
+// lookup returns the page of the module called name, preferring the one declared in file
+func (p docPages) lookup(name string, file string) (string, bool) {
+	page, found := "", false
+	for i, module := range p.modules {
+		if module.Name != name {
+			continue
+		}
+		if module.File == file {
+			return p.names[i], true
+		}
+		if !found {
+			page, found = p.names[i], true
+		}
+	}
+	return page, found
+}
+
+// linkDocTypes replaces the names of documented modules in text, written in file,
+// with links to their page built by link
+func linkDocTypes(text string, pages docPages, file string, link func(name string, page string) string) string {
+	return docIdentPattern.ReplaceAllStringFunc(text, func(ident string) string {
+		if page, ok := pages.lookup(ident, file); ok {
+			return link(ident, page)
+		}
+		return ident
+	})
+}
+
+// markdownDocIndex renders the list of every module
+func markdownDocIndex(pages docPages) string {
+	var sb strings.Builder
+	sb.WriteString("# API Reference\n\n")
+	sb.WriteString("| Name | Kind | File | Summary |\n|------|------|------|---------|\n")
+	for i, module := range pages.modules {
+		sb.WriteString(fmt.Sprintf("| [%s](%s.md) | %s | `%s` | %s |\n",
+			module.Name, pages.page(i), module.Kind, module.File, docSummary(module.Doc)))
+	}
+	return sb.String()
+}
+
+// markdownDocPage renders one module with its members
+func markdownDocPage(module docModule, pages docPages) string {
+	link := func(name string, page string) string {
+		return fmt.Sprintf("[%s](%s.md)", name, page)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s %s\n\n", module.Kind, module.Name))
+	if module.Implements != "" {
+		sb.WriteString("Implements " + linkDocTypes(module.Implements, pages, module.File, link) + ".\n\n")
+	}
+	sb.WriteString(fmt.Sprintf("Defined in `%s:%d`. [Back to index](index.md)\n\n", module.File, module.Line))
+	if module.Doc != "" {
+		sb.WriteString(module.Doc + "\n\n")
+	}
+
+	if len(module.Fields) > 0 {
+		sb.WriteString("## Variables\n\n")
+		for _, field := range module.Fields {
+			sb.WriteString(fmt.Sprintf("### %s\n\n```r2d2\n%s\n```\n\n", field.Name, field.Signature()))
+			if field.Doc != "" {
+				sb.WriteString(field.Doc + "\n\n")
+			}
+		}
+	}
+
+	if len(module.Functions) > 0 {
+		sb.WriteString("## Functions\n\n")
+		for _, fn := range module.Functions {
+			sb.WriteString(fmt.Sprintf("### %s\n\n```r2d2\n%s\n```\n\n", fn.Name, fn.Signature()))
+			if fn.Doc != "" {
+				sb.WriteString(fn.Doc + "\n\n")
+			}
+			if params := markdownDocParams(fn, module.File, pages, link); params != "" {
+				sb.WriteString(params + "\n")
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// markdownDocParams lists the typed parameters of a function
+func markdownDocParams(fn docFunction, file string, pages docPages, link func(string, string) string) string {
+	var sb strings.Builder
+	for _, p := range fn.Params {
+		if p.Type != "" {
+			sb.WriteString(fmt.Sprintf("- `%s`: %s\n", p.Name, linkDocTypes(p.Type, pages, file, link)))
+		}
+	}
+	if fn.Returns != "" {
+		sb.WriteString("- returns: " + linkDocTypes(fn.Returns, pages, file, link) + "\n")
+	}
+	return sb.String()
+}
+
+// Stylesheet embedded in the HTML pages, using the colors from styles.go
+const htmlDocStyle = `body { font-family: system-ui, sans-serif; max-width: 860px; margin: 2rem auto; padding: 0 1rem; color: #1E1E1E; background: ` + backgroundHex + `; }
+h1, h2, h3 { color: ` + highlightHex + `; }
+a { color: ` + infoHex + `; }
+pre { background: #1E1E1E; color: ` + specialHexDark + `; padding: .75rem 1rem; border-radius: 6px; overflow-x: auto; }
+table { border-collapse: collapse; width: 100%; }
+td, th { border-bottom: 1px solid ` + subtleHex + `; padding: .4rem; text-align: left; }
+.kind { color: ` + subtleHexDark + `; font-style: italic; }`
+
+// htmlDocPage wraps a body into a standalone HTML document
+func htmlDocPage(title string, body string) string {
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>%s - R2D2 API Reference</title>
+<style>
+%s
+</style>
+</head>
+<body>
+%s
+</body>
+</html>
+`, html.EscapeString(title), htmlDocStyle, body)
+}
+
+// htmlDocIndex renders the list of every module
+func htmlDocIndex(pages docPages) string {
+	var sb strings.Builder
+	sb.WriteString("<h1>API Reference</h1>\n<table>\n<tr><th>Name</th><th>Kind</th><th>File</th><th>Summary</th></tr>\n")
+	for i, module := range pages.modules {
+		sb.WriteString(fmt.Sprintf("<tr><td><a href=\"%s.html\">%s</a></td><td class=\"kind\">%s</td><td><code>%s</code></td><td>%s</td></tr>\n",
+			html.EscapeString(pages.page(i)), html.EscapeString(module.Name), module.Kind,
+			html.EscapeString(module.File), html.EscapeString(docSummary(module.Doc))))
+	}
+	sb.WriteString("</table>\n")
+	return sb.String()
+}
+
+// htmlDocModule renders one module with its members
+func htmlDocModule(module docModule, pages docPages) string {
+	link := func(name string, page string) string {
+		return fmt.Sprintf("<a href=\"%s.html\">%s</a>", html.EscapeString(page), name)
+	}
+	code := func(signature string) string {
+		return "<pre><code>" + linkDocTypes(html.EscapeString(signature), pages, module.File, link) + "</code></pre>\n"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<p><a href=\"index.html\">Index</a></p>\n<h1><span class=\"kind\">%s</span> %s</h1>\n",
+		module.Kind, html.EscapeString(module.Name)))
+	if module.Implements != "" {
+		sb.WriteString("<p>Implements " + linkDocTypes(html.EscapeString(module.Implements), pages, module.File, link) + ".</p>\n")
+	}
+	sb.WriteString(fmt.Sprintf("<p>Defined in <code>%s:%d</code>.</p>\n", html.EscapeString(module.File), module.Line))
+	if module.Doc != "" {
+		sb.WriteString("<p>" + html.EscapeString(module.Doc) + "</p>\n")
+	}
+
+	if len(module.Fields) > 0 {
+		sb.WriteString("<h2>Variables</h2>\n")
+		for _, field := range module.Fields {
+			sb.WriteString(fmt.Sprintf("<h3 id=\"%s\">%s</h3>\n", field.Name, field.Name))
+			sb.WriteString(code(field.Signature()))
+			if field.Doc != "" {
+				sb.WriteString("<p>" + html.EscapeString(field.Doc) + "</p>\n")
+			}
+		}
+	}
+
+	if len(module.Functions) > 0 {
+		sb.WriteString("<h2>Functions</h2>\n")
+		for _, fn := range module.Functions {
+			sb.WriteString(fmt.Sprintf("<h3 id=\"%s\">%s</h3>\n", fn.Name, fn.Name))
+			sb.WriteString(code(fn.Signature()))
+			if fn.Doc != "" {
+				sb.WriteString("<p>" + html.EscapeString(fn.Doc) + "</p>\n")
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// docSummary returns the first line of a doc comment
+func docSummary(doc string) string {
+	summary, _, _ := strings.Cut(doc, "\n")
+	return summary
+}
+
+// renderDocSymbol renders a module or one of its members for the terminal
+func renderDocSymbol(module *docModule, fn *docFunction, field *docField) string {
+	var sb strings.Builder
+
+	switch {
+	case fn != nil:
+		sb.WriteString(headingStyle.Render(module.Name+"."+fn.Name) + "\n\n")
+		sb.WriteString(exampleStyle.Render(highlightR2D2(fn.Signature())) + "\n")
+		if fn.Doc != "" {
+			sb.WriteString("\n" + infoTextStyle.Render(fn.Doc) + "\n")
+		}
+		sb.WriteString("\n" + categoryStyle.Render(fmt.Sprintf("  %s:%d", module.File, fn.Line)) + "\n")
+
+	case field != nil:
+		sb.WriteString(headingStyle.Render(module.Name+"."+field.Name) + "\n\n")
+		sb.WriteString(exampleStyle.Render(highlightR2D2(field.Signature())) + "\n")
+		if field.Doc != "" {
+			sb.WriteString("\n" + infoTextStyle.Render(field.Doc) + "\n")
+		}
+		sb.WriteString("\n" + categoryStyle.Render(fmt.Sprintf("  %s:%d", module.File, field.Line)) + "\n")
+
+	default:
+		title := module.Kind + " " + module.Name
+		if module.Implements != "" {
+			title += " implements " + module.Implements
+		}
+		sb.WriteString(headingStyle.Render(title) + "\n")
+		sb.WriteString(categoryStyle.Render(fmt.Sprintf("  %s:%d", module.File, module.Line)) + "\n")
+		if module.Doc != "" {
+			sb.WriteString("\n" + infoTextStyle.Render(module.Doc) + "\n")
+		}
+		for _, field := range module.Fields {
+			sb.WriteString("\n" + exampleStyle.Render(highlightR2D2(field.Signature())))
+		}
+		if len(module.Fields) > 0 {
+			sb.WriteString("\n")
+		}
+		for _, fn := range module.Functions {
+			sb.WriteString("\n" + exampleStyle.Render(highlightR2D2(fn.Signature())))
+			if summary := docSummary(fn.Doc); summary != "" {
+				sb.WriteString("\n" + normalItemStyle.Render(summary))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
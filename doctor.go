@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/term"
+)
+
+// Results of a doctor check
+const (
+	CheckPass = "pass"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// Oldest Go release able to build the CLI, matching go.mod
+const minGoVersion = "1.24"
+
+// DoctorCheck is the outcome of one diagnostic
+type DoctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+// Styles for the doctor report (using colors from styles.go)
+var (
+	checkPassStyle = lipgloss.NewStyle().Foreground(specialColor).Bold(true)
+	checkWarnStyle = lipgloss.NewStyle().Foreground(warningColor).Bold(true)
+	checkFailStyle = lipgloss.NewStyle().Foreground(errorColor).Bold(true)
+)
+
+// runDoctorChecks runs every diagnostic in order
+func runDoctorChecks() []DoctorCheck {
+	exe, _ := os.Executable()
+	wd, _ := os.Getwd()
+
+	return []DoctorCheck{
+		checkGit(),
+		checkGo(),
+		checkJSRuntime(detectRuntimes()),
+		checkInstallDirInPath(filepath.Dir(exe), os.Getenv("PATH")),
+		checkStdLibrary(findStdLibrary(wd)),
+		checkWritable(wd),
+		checkTerminal(term.IsTerminal(int(os.Stdout.Fd())), os.Getenv("TERM"), os.Getenv("NO_COLOR")),
+	}
+}
+
+// checkGit looks for git, needed by r2d2 add and the installer
+func checkGit() DoctorCheck {
+	path, err := exec.LookPath("git")
+	if err != nil {
+		return DoctorCheck{"git", CheckWarn, "git not found", "Install git to use git dependencies and the installer"}
+	}
+	return DoctorCheck{"git", CheckPass, path, ""}
+}
+
+// checkGo looks for a Go toolchain recent enough to build the CLI
+func checkGo() DoctorCheck {
+	path, err := exec.LookPath("go")
+	if err != nil {
+		return DoctorCheck{"go", CheckWarn, "go not found", "Install Go " + minGoVersion + " or newer to build r2d2 from source"}
+	}
+
+	output, err := exec.Command(path, "env", "GOVERSION").Output()
+	if err != nil {
+		return DoctorCheck{"go", CheckWarn, "couldn't run " + path, "Check your Go installation with 'go version'"}
+	}
+	return checkGoVersion(strings.TrimSpace(string(output)))
+}
+
+// checkGoVersion compares a version like "go1.22.5" with minGoVersion
+func checkGoVersion(version string) DoctorCheck {
+	if compareVersions(strings.TrimPrefix(version, "go"), minGoVersion) < 0 {
+		return DoctorCheck{"go", CheckWarn, version + " is older than go" + minGoVersion, "Upgrade Go from https://go.dev/dl/"}
+	}
+	return DoctorCheck{"go", CheckPass, version, ""}
+}
+
+// checkJSRuntime requires deno, which runs the code of r2d2 run, eval and repl.
+// Node and bun can only run the output of r2d2 js, so they don't pass the check.
+func checkJSRuntime(runtimes []RuntimeInfo) DoctorCheck {
+	var others []string
+	for _, rt := range runtimes {
+		if rt.Name == "deno" {
+			return DoctorCheck{"js runtime", CheckPass, "deno " + rt.Version, ""}
+		}
+		others = append(others, rt.Name+" "+rt.Version)
+	}
+
+	fix := "Install Deno, which r2d2 run, eval and repl need: curl -fsSL https://deno.land/install.sh | sh"
+	if len(others) > 0 {
+		return DoctorCheck{"js runtime", CheckFail, "deno not found, only " + strings.Join(others, ", ") + " (enough for the output of r2d2 js)", fix}
+	}
+	return DoctorCheck{"js runtime", CheckFail, "deno not found", fix}
+}
+
+// checkInstallDirInPath checks that the directory holding r2d2 is on the PATH
+func checkInstallDirInPath(dir string, pathEnv string) DoctorCheck {
+	for _, entry := range filepath.SplitList(pathEnv) {
+		if entry != "" && filepath.Clean(entry) == filepath.Clean(dir) {
+			return DoctorCheck{"PATH", CheckPass, dir + " is on the PATH", ""}
+		}
+	}
+	return DoctorCheck{"PATH", CheckWarn, dir + " is not on the PATH", fmt.Sprintf("Add it to your shell profile: export PATH=\"%s:$PATH\"", dir)}
+}
+
+// checkStdLibrary reports where std.r2d2 resolves from
+func checkStdLibrary(path string) DoctorCheck {
+	if path == "" {
+		return DoctorCheck{"std library", CheckWarn, stdLibraryFile + " not found", "Set R2D2_STD or copy " + stdLibraryFile + " to ~/.r2d2/"}
+	}
+	return DoctorCheck{"std library", CheckPass, path, ""}
+}
+
+// checkWritable checks that compiled output can be written to dir
+func checkWritable(dir string) DoctorCheck {
+	file, err := os.CreateTemp(dir, ".r2d2-doctor-")
+	if err != nil {
+		return DoctorCheck{"output dir", CheckFail, "can't write to " + dir, "Run r2d2 from a writable directory or use -o to pick another output"}
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return DoctorCheck{"output dir", CheckPass, dir + " is writable", ""}
+}
+
+// checkTerminal checks whether the interactive commands (help, repl) can render
+func checkTerminal(isTTY bool, termEnv string, noColor string) DoctorCheck {
+	switch {
+	case !isTTY:
+		return DoctorCheck{"terminal", CheckWarn, "stdout is not a terminal", "Use 'r2d2 help static' and non-interactive commands in scripts"}
+	case termEnv == "dumb":
+		return DoctorCheck{"terminal", CheckWarn, "TERM=dumb has no cursor control", "Set TERM to xterm-256color or similar"}
+	case noColor != "":
+		return DoctorCheck{"terminal", CheckPass, "interactive, colors disabled by NO_COLOR", ""}
+	}
+	return DoctorCheck{"terminal", CheckPass, "interactive, " + termEnv, ""}
+}
+
+// Doctor runs the environment diagnostics and prints them, or a JSON document with asJSON.
+// It fails when any check fails.
+func Doctor(asJSON bool) error {
+	checks := runDoctorChecks()
+
+	failed := 0
+	for _, check := range checks {
+		if check.Status == CheckFail {
+			failed++
+		}
+	}
+
+	if asJSON {
+		content, err := json.MarshalIndent(checks, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(content))
+	} else {
+		for _, check := range checks {
+			fmt.Println(formatDoctorCheck(check))
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// formatDoctorCheck renders one check as a status line, with its fix below
+func formatDoctorCheck(check DoctorCheck) string {
+	var status string
+	switch check.Status {
+	case CheckPass:
+		status = checkPassStyle.Render("PASS")
+	case CheckWarn:
+		status = checkWarnStyle.Render("WARN")
+	default:
+		status = checkFailStyle.Render("FAIL")
+	}
+
+	line := fmt.Sprintf("%s %s %s", status, highlightedCmdStyle.Render(fmt.Sprintf("%-12s", check.Name)), check.Detail)
+	if check.Fix != "" {
+		line += "\n     " + exampleStyle.Render(check.Fix)
+	}
+	return line
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCheckGoVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"go1.24.1", CheckPass},
+		{"go1.25.0", CheckPass},
+		{"go1.22.5", CheckWarn},
+		{"go1.9", CheckWarn},
+	}
+
+	for _, tt := range tests {
+		if got := checkGoVersion(tt.version); got.Status != tt.want {
+			t.Errorf("checkGoVersion(%q) = %+v, want %s", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestCheckJSRuntime(t *testing.T) {
+	tests := []struct {
+		name     string
+		runtimes []RuntimeInfo
+		want     string
+	}{
+		{"deno", []RuntimeInfo{{Name: "node"}, {Name: "deno", Version: "2.1.4"}}, CheckPass},
+		{"node only", []RuntimeInfo{{Name: "node", Version: "22.3.0"}}, CheckFail},
+		{"none", nil, CheckFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkJSRuntime(tt.runtimes)
+			if got.Status != tt.want {
+				t.Errorf("checkJSRuntime() = %+v, want %s", got, tt.want)
+			}
+			if got.Status != CheckPass && got.Fix == "" {
+				t.Error("checkJSRuntime() should suggest a fix")
+			}
+		})
+	}
+}
+
+func TestCheckInstallDirInPath(t *testing.T) {
+	sep := string(os.PathListSeparator)
+	if got := checkInstallDirInPath("/usr/local/bin", "/usr/bin"+sep+"/usr/local/bin/"); got.Status != CheckPass {
+		t.Errorf("checkInstallDirInPath() = %+v, want pass", got)
+	}
+
+	got := checkInstallDirInPath("/opt/r2d2", "/usr/bin")
+	if got.Status != CheckWarn || !strings.Contains(got.Fix, "/opt/r2d2") {
+		t.Errorf("checkInstallDirInPath() = %+v, want a warning with an export line", got)
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	dir := t.TempDir()
+	if got := checkWritable(dir); got.Status != CheckPass {
+		t.Errorf("checkWritable() = %+v, want pass", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("checkWritable() left %d file(s) behind", len(entries))
+	}
+
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		return
+	}
+	readOnly := filepath.Join(dir, "ro")
+	if err := os.Mkdir(readOnly, 0555); err != nil {
+		t.Fatal(err)
+	}
+	if got := checkWritable(readOnly); got.Status != CheckFail {
+		t.Errorf("checkWritable() = %+v, want fail", got)
+	}
+}
+
+func TestCheckTerminal(t *testing.T) {
+	tests := []struct {
+		isTTY   bool
+		term    string
+		noColor string
+		want    string
+	}{
+		{true, "xterm-256color", "", CheckPass},
+		{true, "xterm-256color", "1", CheckPass},
+		{true, "dumb", "", CheckWarn},
+		{false, "xterm-256color", "", CheckWarn},
+	}
+
+	for _, tt := range tests {
+		if got := checkTerminal(tt.isTTY, tt.term, tt.noColor); got.Status != tt.want {
+			t.Errorf("checkTerminal(%v, %q, %q) = %+v, want %s", tt.isTTY, tt.term, tt.noColor, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDoctorCheck(t *testing.T) {
+	line := formatDoctorCheck(DoctorCheck{"git", CheckWarn, "git not found", "Install git"})
+	for _, want := range []string{"WARN", "git not found", "Install git"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("formatDoctorCheck() = %q, missing %q", line, want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Regenerate golden files with: go test -run TestExamplesGolden -update
+var update = flag.Bool("update", false, "regenerate the .golden.js and .golden.out files of the examples")
+
+// Examples that compile fine but can't be executed unattended
+var noRunExamples = map[string]string{
+	"examples/cli/donut.r2d2":     "renders an endless animation",
+	"examples/cli/menu.r2d2":      "waits for input on stdin",
+	"examples/web/demo.r2d2":      "needs a browser DOM",
+	"examples/web/web.r2d2":       "needs a browser DOM",
+	"examples/web/web_utils.r2d2": "needs a browser DOM",
+}
+
+// Fragments the generated JavaScript must always contain, whatever the golden file says.
+// They pin down compiler bugs that were already fixed once.
+var goldenMustContain = map[string][]string{
+	// Every branch of an arrow-if chain must survive the transpilation
+	"sad.r2d2": {`"positive"`, `"negative"`, `"zero"`},
+}
+
+// exampleSources returns every R2D2 program the golden harness covers
+func exampleSources(t *testing.T) []string {
+	var sources []string
+	for _, pattern := range []string{"examples/cli/*.r2d2", "examples/web/*.r2d2", "examples/lang/*.r2d2", "sad.r2d2"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatalf("invalid glob %q: %v", pattern, err)
+		}
+		sources = append(sources, matches...)
+	}
+
+	if len(sources) == 0 {
+		t.Fatal("no examples found, is the test running from the repository root?")
+	}
+	return sources
+}
+
+// goldenPath returns the golden file of a source with the given extension (".golden.js", ".golden.out")
+func goldenPath(source string, ext string) string {
+	return strings.TrimSuffix(source, ".r2d2") + ext
+}
+
+// compileExample transpiles an example into a temporary directory and returns the generated JavaScript
+func compileExample(t *testing.T, source string) (string, string) {
+	t.Helper()
+
+	code, err := os.ReadFile(source)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", source, err)
+	}
+
+	base := filepath.Base(source)
+	target := filepath.Join(t.TempDir(), base)
+	if err := BuildJs(string(code), target); err != nil {
+		t.Fatalf("BuildJs(%s) error = %v", source, err)
+	}
+
+	jsPath := strings.TrimSuffix(target, ".r2d2") + ".js"
+	js, err := os.ReadFile(jsPath)
+	if err != nil {
+		t.Fatalf("BuildJs(%s) did not produce %s: %v", source, jsPath, err)
+	}
+	return string(js), jsPath
+}
+
+// compareGolden checks got against the golden file, or rewrites it when -update is set
+func compareGolden(t *testing.T, path string, got string) {
+	t.Helper()
+
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("error writing golden file %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("golden file %s is missing, run 'go test -run TestExamplesGolden -update' to create it", path)
+	}
+	if err != nil {
+		t.Fatalf("error reading golden file %s: %v", path, err)
+	}
+
+	if normalizeNewlines(string(want)) != normalizeNewlines(got) {
+		t.Errorf("output differs from %s (run with -update if the change is intended)\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
+
+// normalizeNewlines makes the comparison independent of the platform line endings
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
+
+// Compiles every example and compares the JavaScript with the checked-in .golden.js files
+func TestExamplesGolden(t *testing.T) {
+	for _, source := range exampleSources(t) {
+		source := filepath.ToSlash(source)
+
+		t.Run(source, func(t *testing.T) {
+			js, _ := compileExample(t, source)
+
+			for _, fragment := range goldenMustContain[source] {
+				if !strings.Contains(js, fragment) {
+					t.Errorf("generated JavaScript for %s should contain %s, got:\n%s", source, fragment, js)
+				}
+			}
+
+			compareGolden(t, goldenPath(source, ".golden.js"), js)
+		})
+	}
+}
+
+// Runs the compiled examples with Deno and compares stdout with the .golden.out files
+func TestExamplesGoldenOutput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping example execution in short mode")
+	}
+
+	deno, err := exec.LookPath("deno")
+	if err != nil {
+		t.Skip("deno not found in PATH, skipping example execution")
+	}
+
+	for _, source := range exampleSources(t) {
+		source := filepath.ToSlash(source)
+
+		t.Run(source, func(t *testing.T) {
+			if reason, ok := noRunExamples[source]; ok {
+				t.Skipf("not executed: %s", reason)
+			}
+
+			_, jsPath := compileExample(t, source)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			var stdout, stderr bytes.Buffer
+			cmd := exec.CommandContext(ctx, deno, "run", "--allow-all", jsPath)
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("error running %s with Deno: %v\nstderr: %s", jsPath, err, stderr.String())
+			}
+
+			compareGolden(t, goldenPath(source, ".golden.out"), stdout.String())
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"testing"
+	"time"
+)
+
+// Maximum time a single compilation may take before it counts as a hang
+const fuzzDeadline = 10 * time.Second
+
+// Matches the positions compiler errors are expected to carry ("line 3", "3:14")
+var errorPositionPattern = regexp.MustCompile(`(?i)line\s*\d+|\d+:\d+`)
+
+// addFuzzSeeds seeds the corpus with the examples and the standard library
+func addFuzzSeeds(f *testing.F) {
+	var sources []string
+	for _, pattern := range []string{"examples/cli/*.r2d2", "examples/web/*.r2d2", "*.r2d2"} {
+		matches, _ := filepath.Glob(pattern)
+		sources = append(sources, matches...)
+	}
+
+	for _, source := range sources {
+		code, err := os.ReadFile(source)
+		if err != nil {
+			f.Fatalf("error reading seed %s: %v", source, err)
+		}
+		f.Add(string(code))
+	}
+
+	// Small malformed inputs that used to be interesting for the parser
+	f.Add("")
+	f.Add("module")
+	f.Add("module Test {")
+	f.Add("module Test { export fn main() { if => } }")
+	f.Add("use \"missing.r2d2\";")
+	f.Add("module Test { export fn main() { @js << unterminated } }")
+}
+
+// compileResult is what a guarded compilation reports back
+type compileResult struct {
+	err        error
+	panicValue any
+	stack      []byte
+}
+
+// runGuarded runs a compilation step, failing the test on panics and on hangs
+func runGuarded(t *testing.T, name string, code string, step func() error) error {
+	t.Helper()
+
+	done := make(chan compileResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- compileResult{panicValue: r, stack: debug.Stack()}
+			}
+		}()
+		done <- compileResult{err: step()}
+	}()
+
+	select {
+	case result := <-done:
+		if result.panicValue != nil {
+			t.Fatalf("%s panicked: %v\ninput: %q\n%s", name, result.panicValue, code, result.stack)
+		}
+		return result.err
+	case <-time.After(fuzzDeadline):
+		t.Fatalf("%s did not finish within %v\ninput: %q", name, fuzzDeadline, code)
+	}
+	return nil
+}
+
+// assertErrorPosition fails when a front-end error doesn't say where it happened
+func assertErrorPosition(t *testing.T, name string, code string, err error) {
+	t.Helper()
+
+	if err != nil && !errorPositionPattern.MatchString(err.Error()) {
+		t.Errorf("%s error has no position: %v\ninput: %q", name, err, code)
+	}
+}
+
+func FuzzCheck(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, code string) {
+		err := runGuarded(t, "Check", code, func() error {
+			return Check(code)
+		})
+		assertErrorPosition(t, "Check", code, err)
+	})
+}
+
+func FuzzBuildJs(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, code string) {
+		target := filepath.Join(t.TempDir(), "fuzz.r2d2")
+
+		err := runGuarded(t, "BuildJs", code, func() error {
+			return BuildJs(code, target)
+		})
+		assertErrorPosition(t, "BuildJs", code, err)
+	})
+}
+
+func FuzzBuild(f *testing.F) {
+	addFuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, code string) {
+		if testing.Short() {
+			t.Skip("skipping native builds in short mode")
+		}
+
+		target := filepath.Join(t.TempDir(), "fuzz.r2d2")
+
+		// Build also depends on the toolchain, so only panics and hangs are checked here
+		runGuarded(t, "Build", code, func() error {
+			return Build(code, target)
+		})
+	})
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/ArturC03/r2d2Styles"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"golang.org/x/term"
@@ -20,6 +21,29 @@ type Command struct {
 	usage       string
 	examples    []string
 	category    string
+	flags       []Flag
+	long        string // markdown shown on the command's page
+	seeAlso     []string
+	exitCodes   []ExitCode
+	aliases     []string            // other names main takes for it
+	takesCode   bool                // arguments are code, so none of them is a flag
+	args        string              // kind of its arguments, for the shell completions
+	choices     []string            // words its first argument can be
+	subChoices  map[string][]string // words the argument after one of the choices can be
+}
+
+// Flag documents an option of a command
+type Flag struct {
+	name        string // with its dashes
+	typ         string // kind of value it takes, empty for switches
+	def         string // default value, empty when there's none
+	description string
+}
+
+// ExitCode documents what a command's exit status means
+type ExitCode struct {
+	code    int
+	meaning string
 }
 
 // Implementing list.Item interface - return plain text for filtering
@@ -35,57 +59,408 @@ const (
 	CategoryBasic = "basic"
 	CategoryBuild = "build"
 	CategoryUtil  = "utility"
+	CategoryPkg   = "package"
+)
+
+// Kinds of arguments the shell completions complete
+const (
+	argFiles    = "files"    // .r2d2 files and directories
+	argSymbols  = "symbols"  // modules and their members, or paths
+	argCommands = "commands" // names of commands
+	argPackages = "packages" // dependencies of the project
 )
 
+// Shells 'r2d2 completion' writes scripts for
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// Exit codes every command shares
+var commonExitCodes = []ExitCode{
+	{0, "Success"},
+	{1, "The command failed, the error is printed above"},
+	{2, "Internal compiler error, a crash report is written to the working directory"},
+}
+
 // Available commands
 var commands = []Command{
 	{
-		"help",
-		"Shows help menu (interactive by default, use 'static' for simple output)",
-		"r2d2 help [static]",
-		[]string{"r2d2 help", "r2d2 help static"},
-		CategoryBasic,
+		name:        "help",
+		description: "Shows help menu (interactive by default, use 'static' for simple output)",
+		usage:       "r2d2 help [static | lang [<topic>] | <command>] | r2d2 help --man <dir> | r2d2 help --markdown <dir>",
+		examples: []string{
+			"r2d2 help",
+			"r2d2 help static",
+			"r2d2 help build",
+			"r2d2 help lang",
+			"r2d2 help lang arrow-if",
+			"r2d2 help --man ~/.local/share/man/man1",
+			"r2d2 help --markdown docs/reference",
+		},
+		category: CategoryBasic,
+		aliases:  []string{"-help", "-h", "--help", "--h"},
+		args:     argCommands,
+		choices:  []string{"static", "lang"},
+		subChoices: map[string][]string{
+			"lang": langTopicNames(),
+		},
+		flags: []Flag{
+			{name: "--man", typ: "dir", description: "Writes a man page for r2d2 and each command into the directory"},
+			{name: "--markdown", typ: "dir", description: "Writes a markdown reference page for each command into the directory"},
+		},
+		long: `Opens an interactive browser of every command. Type **/** to filter,
+**c** to pick a category and **enter** to read a command's page.
+**tab** switches to the language tab.
+
+- **static** prints every command at once, for pipes and scripts
+- **lang** opens the language tab: modules, interfaces,
+  pseudo-functions, @js blocks, arrow-if, loops, switch and types,
+  each with an example that **r** runs and **w** writes to a file.
+  ` + "`r2d2 help lang <topic>`" + ` prints one topic
+- **<command>** prints the page of one command, the same as ` + "`r2d2 <command> --help`" + `
+- **--man** and **--markdown** write these pages as files: r2d2(1),
+  r2d2-build(1) and so on, or one markdown page per command with an
+  index. The copies in docs/man and docs/reference are generated this way`,
+		seeAlso:   []string{"version", "doctor"},
+		exitCodes: commonExitCodes[:2],
 	},
 	{
-		"version",
-		"Displays the language version",
-		"r2d2 version",
-		[]string{
+		name:        "version",
+		description: "Displays the language version, with build and runtime details when verbose",
+		usage:       "r2d2 version [--verbose] [--json]",
+		examples: []string{
 			"r2d2 version",
-			// "r2d2 version -f"
+			"r2d2 version --verbose",
+			"r2d2 version --json",
 		},
-		CategoryBasic,
+		category: CategoryBasic,
+		aliases:  []string{"-version", "-v", "--version", "--v"},
+		flags: []Flag{
+			{name: "--verbose", description: "Adds the compiler, commit, build date, Go version and JavaScript runtimes"},
+			{name: "--json", description: "Prints the verbose details as JSON"},
+		},
+		long: `Prints the version of r2d2. Bug reports should include the output of
+` + "`r2d2 version --verbose`" + `, which also lists the JavaScript runtimes
+that were found.`,
+		seeAlso:   []string{"upgrade", "doctor"},
+		exitCodes: commonExitCodes[:2],
 	},
 	{
-		"build",
-		"Compiles a .r2d2 file",
-		"r2d2 build <file.r2d2>",
-		[]string{
+		name:        "build",
+		description: "Compiles a .r2d2 file (use - to read from stdin)",
+		usage:       "r2d2 build <file.r2d2> [-o <output>]",
+		examples: []string{
 			"r2d2 build hello.r2d2",
 			"r2d2 build hello.r2d2 -o hi",
+			"cat hello.r2d2 | r2d2 build - -o hello",
 			// "r2d2 build --optimize hello.r2d2",
 		},
-		CategoryBuild,
+		category: CategoryBuild,
+		aliases:  []string{"-b"},
+		args:     argFiles,
+		flags: []Flag{
+			{name: "-o", typ: "file", def: "the input name", description: "Name of the executable to write"},
+		},
+		long: `Compiles a program into a standalone executable. Imports of packages
+added with ` + "`r2d2 add`" + ` are resolved first.
+
+With **-** as the file the source is read from stdin, so another tool
+can generate it:
+
+` + "```" + `
+generate-code | r2d2 build - -o app
+` + "```",
+		seeAlso:   []string{"run", "js", "check"},
+		exitCodes: commonExitCodes,
 	},
 	{
-		"run",
-		"Executes a .r2d2 file",
-		"r2d2 run <file.r2d2>",
-		[]string{
+		name:        "run",
+		description: "Executes a .r2d2 file (use - to read from stdin)",
+		usage:       "r2d2 run <file.r2d2>",
+		examples: []string{
 			"r2d2 run hello.r2d2",
+			"generate-code | r2d2 run -",
 			// "r2d2 run debug hello.r2d2",
 		},
-		CategoryBuild,
+		category: CategoryBuild,
+		aliases:  []string{"-r"},
+		args:     argFiles,
+		long: `Compiles a program to JavaScript and runs it with Deno, which has to
+be on the PATH. Nothing is left behind on disk.
+
+Use **-** as the file to read the source from stdin.`,
+		seeAlso:   []string{"build", "eval", "repl", "doctor"},
+		exitCodes: commonExitCodes,
 	},
 	{
-		"js",
-		"Transpiles a .r2d2 file to JavaScript",
-		"r2d2 js <file.r2d2>",
-		[]string{
+		name:        "js",
+		description: "Transpiles .r2d2 files to JavaScript (- reads stdin and writes stdout)",
+		usage:       "r2d2 js <file.r2d2|dir...> [-o <file.js>] [--outdir <dir>] [--entry <file.r2d2>]",
+		examples: []string{
 			"r2d2 js hello.r2d2",
 			"r2d2 js hello.r2d2 -o bye.js",
+			"cat hello.r2d2 | r2d2 js - > hello.js",
+			"r2d2 js src/ --outdir dist/",
+			"r2d2 js src/ --outdir dist/ --entry src/main.r2d2",
+		},
+		category: CategoryBuild,
+		args:     argFiles,
+		flags: []Flag{
+			{name: "-o", typ: "file", def: "the input name", description: "JavaScript file to write for a single input"},
+			{name: "--outdir", typ: "dir", def: "dist", description: "Directory the output tree is written to"},
+			{name: "--entry", typ: "file", description: "Files whose output runs its main module, can be repeated"},
+		},
+		long: `Transpiles R2D2 to JavaScript.
+
+- One file gives one .js file next to it, or the file named by **-o**
+- Directories and several files give an output tree in **--outdir**,
+  with the layout of the sources
+- Each output bundles the files its source uses. With **--entry**,
+  files only used by others are skipped and only the entries are
+  written
+- **-** reads stdin and writes the JavaScript to stdout, compiler
+  messages go to stderr`,
+		seeAlso:   []string{"build", "check"},
+		exitCodes: commonExitCodes,
+	},
+	{
+		name:        "eval",
+		description: "Runs a snippet of code, printing the value of expressions",
+		usage:       "r2d2 eval '<code>'",
+		examples: []string{
+			"r2d2 eval '1 + 2'",
+			"r2d2 eval 'console.log(\"Hello\");'",
+			"echo 'std.upper(\"hi\")' | r2d2 eval -",
+		},
+		category:  CategoryBuild,
+		takesCode: true,
+		long: `Runs a snippet without writing a file. Statements and expressions are
+wrapped into a module with a main function, full programs run as they
+are. The value of an expression is printed.
+
+Use **-** to read the snippet from stdin.`,
+		seeAlso:   []string{"repl", "run"},
+		exitCodes: commonExitCodes,
+	},
+	{
+		name:        "check",
+		description: "Checks a .r2d2 file for errors without writing any output",
+		usage:       "r2d2 check <file.r2d2>",
+		examples: []string{
+			"r2d2 check hello.r2d2",
+			"cat hello.r2d2 | r2d2 check -",
+		},
+		category: CategoryBuild,
+		args:     argFiles,
+		long: `Compiles a program and reports its errors without keeping any output,
+which makes it quick enough for editors and pre-commit hooks.
+
+Use **-** to read the source from stdin.`,
+		seeAlso:   []string{"build", "js"},
+		exitCodes: commonExitCodes,
+	},
+	{
+		name:        "doc",
+		description: "Generates API docs from doc comments, or shows one symbol",
+		usage:       "r2d2 doc [paths...] [--format md|html|json] [--out <dir>] | r2d2 doc <module[.member]> [paths...]",
+		examples: []string{
+			"r2d2 doc src/",
+			"r2d2 doc std.r2d2 --format html --out docs/std",
+			"r2d2 doc std.trim",
+		},
+		category: CategoryUtil,
+		args:     argSymbols,
+		flags: []Flag{
+			{name: "--format", typ: "md|html|json", def: "md", description: "Format of the generated docs"},
+			{name: "--out", typ: "dir", def: "docs/api", description: "Directory the docs are written to"},
+		},
+		long: `Reads the doc comments of modules, interfaces and their members and
+writes one page per module, with an index. Pages are named after the
+file and the module, like cli-demo.Main.md, so modules of different
+files with the same name each get their own.
+
+When the first argument is a symbol such as ` + "`std.trim`" + ` its
+documentation is printed to the terminal instead. The paths default to
+the working directory.`,
+		seeAlso:   []string{"check"},
+		exitCodes: commonExitCodes[:2],
+	},
+	{
+		name:        "repl",
+		description: "Starts an interactive R2D2 shell",
+		usage:       "r2d2 repl",
+		examples: []string{
+			"r2d2 repl",
+		},
+		category: CategoryUtil,
+		long: `Starts a shell that runs declarations, statements and expressions as
+they're typed. Declarations are kept for the rest of the session and
+the history is saved between sessions. Ctrl+C stops an input that's
+still running; if it was already executing, the runtime is restarted
+and the declarations are cleared.`,
+		seeAlso:   []string{"eval", "run"},
+		exitCodes: commonExitCodes[:2],
+	},
+	{
+		name:        "doctor",
+		description: "Checks the environment (git, go, deno, PATH, std library, terminal)",
+		usage:       "r2d2 doctor [--json]",
+		examples: []string{
+			"r2d2 doctor",
+			"r2d2 doctor --json > doctor.json",
+		},
+		category: CategoryUtil,
+		flags: []Flag{
+			{name: "--json", description: "Prints the checks as JSON"},
+		},
+		long: `Checks everything r2d2 needs and prints a fix for each problem:
+
+- git and Go, with a supported Go version
+- Deno, which runs the code of **run**, **eval** and **repl**
+- the install directory being on the PATH
+- the std library and a writable working directory
+- a terminal with colors`,
+		seeAlso:   []string{"version", "self"},
+		exitCodes: []ExitCode{{0, "Every check passed or only warned"}, {1, "At least one check failed"}},
+	},
+	{
+		name:        "upgrade",
+		description: "Replaces r2d2 with the latest release, or the given version",
+		usage:       "r2d2 upgrade [--version <version>] [--check] [--source <url>]",
+		examples: []string{
+			"r2d2 upgrade",
+			"r2d2 upgrade --check",
+			"r2d2 upgrade --version 0.3.0",
+		},
+		category: CategoryUtil,
+		flags: []Flag{
+			{name: "--version", typ: "version", def: "latest", description: "Release to install, older ones included"},
+			{name: "--check", description: "Only tells whether a newer release exists"},
+			{name: "--source", typ: "url", description: "Releases page to download from instead of GitHub"},
+		},
+		long: `Downloads a release and replaces the running executable with it. The
+current one is kept when anything goes wrong.
+
+Without **--version** only a newer release is installed.`,
+		seeAlso:   []string{"version", "self"},
+		exitCodes: commonExitCodes[:2],
+	},
+	{
+		name:        "self",
+		description: "Uninstalls or repairs an installation made by r2d2-installer",
+		usage:       "r2d2 self uninstall [--dry-run] | r2d2 self repair [--source <url>]",
+		examples: []string{
+			"r2d2 self uninstall --dry-run",
+			"r2d2 self uninstall",
+			"r2d2 self repair",
+		},
+		category: CategoryUtil,
+		choices:  []string{"uninstall", "repair"},
+		flags: []Flag{
+			{name: "--dry-run", description: "uninstall: lists what would be removed"},
+			{name: "--source", typ: "url", description: "repair: releases page to download the CLI from instead of GitHub"},
+		},
+		long: `Works with the receipt the installer leaves in ~/.r2d2.
+
+- **uninstall** removes the binary, the PATH lines the installer
+  added, the shell completion, its temporary directories and the
+  downloaded toolchain
+- **repair** restores the PATH lines, and reinstalls the release the
+  receipt records when the binary is missing or doesn't run, or the
+  latest release when that one can't be downloaded`,
+		seeAlso:   []string{"upgrade", "doctor"},
+		exitCodes: commonExitCodes[:2],
+	},
+	{
+		name:        "completion",
+		description: "Prints the shell completion script for bash, zsh, fish or PowerShell",
+		usage:       "r2d2 completion bash|zsh|fish|powershell",
+		examples: []string{
+			"source <(r2d2 completion bash)",
+			"r2d2 completion zsh > ~/.zfunc/_r2d2",
+			"r2d2 completion fish > ~/.config/fish/completions/r2d2.fish",
+			"r2d2 completion powershell >> $PROFILE",
+		},
+		category: CategoryUtil,
+		choices:  completionShells,
+		long: `Prints a script completing commands, flags, .r2d2 files, the modules
+and functions of **doc** and the dependencies of **remove**. The
+installer sets it up for the login shell. To do it by hand:
+
+- **bash**: save it as ~/.local/share/bash-completion/completions/r2d2
+- **zsh**: save it as _r2d2 in a directory of ` + "`$fpath`" + `
+- **fish**: save it as ~/.config/fish/completions/r2d2.fish
+- **powershell**: add it to ` + "`$PROFILE`",
+		seeAlso:   []string{"help", "self"},
+		exitCodes: commonExitCodes[:2],
+	},
+	{
+		name:        "add",
+		description: "Adds a dependency from a git URL, tarball URL or local path",
+		usage:       "r2d2 add <source>[@version] [--name <name>]",
+		examples: []string{
+			"r2d2 add https://github.com/user/strings.git@v1.2.0",
+			"r2d2 add https://example.com/json.tar.gz",
+			"r2d2 add ../shared --name shared",
+		},
+		category: CategoryPkg,
+		flags: []Flag{
+			{name: "--name", typ: "name", def: "the source's base name", description: "Name the package is imported with"},
+		},
+		long: `Fetches a package, records it in r2d2.json and locks its checksum in
+r2d2.lock. Sources can be:
+
+- a git URL, with a tag, branch or commit after **@**
+- a tarball URL
+- a local directory`,
+		seeAlso:   []string{"remove", "install", "vendor"},
+		exitCodes: commonExitCodes[:2],
+	},
+	{
+		name:        "remove",
+		description: "Removes a dependency from r2d2.json and r2d2.lock",
+		usage:       "r2d2 remove <name>",
+		examples: []string{
+			"r2d2 remove strings",
 		},
-		CategoryBuild,
+		category:  CategoryPkg,
+		args:      argPackages,
+		long:      `Removes a package from r2d2.json and r2d2.lock.`,
+		seeAlso:   []string{"add", "install"},
+		exitCodes: commonExitCodes[:2],
+	},
+	{
+		name:        "install",
+		description: "Fetches every dependency at the versions locked in r2d2.lock",
+		usage:       "r2d2 install",
+		examples: []string{
+			"r2d2 install",
+		},
+		category: CategoryPkg,
+		long: `Fetches every package of r2d2.json at the version locked in r2d2.lock
+and checks it against the locked checksum. Run it after cloning a
+project.`,
+		seeAlso:   []string{"add", "vendor"},
+		exitCodes: commonExitCodes[:2],
+	},
+	{
+		name:        "vendor",
+		description: "Copies every dependency and std.r2d2 into vendor/ for offline builds",
+		usage:       "r2d2 vendor [--verify]",
+		examples: []string{
+			"r2d2 vendor",
+			"r2d2 vendor --verify",
+		},
+		category: CategoryPkg,
+		flags: []Flag{
+			{name: "--verify", description: "Checks vendor/ against r2d2.lock instead of copying"},
+		},
+		long: `Copies the locked packages and std.r2d2 into vendor/. Builds use
+vendor/ when it exists, so they don't need the network.
+
+Builds fail when vendor/modules.json no longer matches r2d2.lock, for
+example after **r2d2 add**. Run **r2d2 vendor** again to bring it up
+to date.`,
+		seeAlso:   []string{"install", "add"},
+		exitCodes: []ExitCode{{0, "Success"}, {1, "The command failed, or with --verify vendor/ doesn't match r2d2.lock"}},
 	},
 	// {
 	// 	"init",
@@ -208,9 +583,17 @@ func (d customDelegate) Render(w io.Writer, m list.Model, index int, item list.I
 	fmt.Fprint(w, str)
 }
 
+// Tabs of the interactive help
+const (
+	helpTabCommands = iota
+	helpTabLanguage
+)
+
 // HelpModel represents the application state
 type HelpModel struct {
+	tab             int // helpTabCommands or helpTabLanguage
 	list            list.Model
+	topics          list.Model // topics of the language tab
 	help            string
 	detailed        bool
 	selectedItem    Command
@@ -219,6 +602,127 @@ type HelpModel struct {
 	showingCategory bool
 	width           int
 	height          int
+	page            viewport.Model // scrolls the page of the selected command
+	seeAlso         int            // highlighted "see also" entry, -1 for none
+	history         []Command      // pages left by following "see also" entries
+	topic           LangTopic      // topic whose page is open in the language tab
+	status          string         // outcome of running or writing the topic's example
+}
+
+// Help line of the lists
+func listHelp(width int, tab int) string {
+	other := "language"
+	if tab == helpTabLanguage {
+		other = "commands"
+	}
+	if width < 40 {
+		return "/ filter • tab • q quit • ↑/↓ • enter"
+	} else if width < 60 {
+		return "/ filter • tab " + other + " • q quit • enter info"
+	}
+	if tab == helpTabLanguage {
+		return "/ filter • tab commands • q quit • ↑/↓ navigate • enter topic"
+	}
+	return "/ filter • c categories • tab language • q quit • ↑/↓ navigate • enter details"
+}
+
+// Help line of the topic pages
+func topicHelp(width int) string {
+	if width < 40 {
+		return "↑/↓ • r run • w write • esc"
+	} else if width < 60 {
+		return "↑/↓ scroll • r run • w write file • esc back"
+	}
+	return "↑/↓ scroll • r run example • w write it to a file • 1-9 see also • esc back"
+}
+
+// renderHelpTabs renders the tab bar above the lists
+func renderHelpTabs(active int) string {
+	tabs := []string{"Commands", "Language"}
+	for i, tab := range tabs {
+		if i == active {
+			tabs[i] = selectedSeeAlsoStyle.Render(tab)
+		} else {
+			tabs[i] = categoryStyle.Padding(0, 1).Render(tab)
+		}
+	}
+	return strings.Join(tabs, " ") + "\n"
+}
+
+// Help line of the command pages
+func detailHelp(width int) string {
+	if width < 40 {
+		return "↑/↓ • tab • enter • esc"
+	} else if width < 60 {
+		return "↑/↓ scroll • tab see also • esc back"
+	}
+	return "↑/↓ scroll • tab see also • enter open • esc back"
+}
+
+// detailLayout returns the width, height and padding of the page viewport
+func (m HelpModel) detailLayout() (width int, height int, padding int) {
+	containerWidth := m.width - 4
+	if containerWidth < 30 {
+		containerWidth = 30
+	}
+	if containerWidth > 80 {
+		containerWidth = 80
+	}
+
+	padding = 1
+	if m.width < 50 {
+		padding = 0
+	}
+
+	// The border, the padding and the help line take the rest of the screen
+	height = m.height - 4 - 2*padding
+	if height < 5 {
+		height = 5
+	}
+	return containerWidth - 2*padding, height, padding
+}
+
+// openCommand shows the page of cmd from the top
+func (m *HelpModel) openCommand(cmd Command) {
+	m.selectedItem = cmd
+	m.detailed = true
+	m.seeAlso = -1
+
+	width, height, _ := m.detailLayout()
+	m.page = viewport.New(width, height)
+	m.page.SetContent(renderCommandHelp(cmd, width, m.seeAlso))
+	m.help = detailHelp(m.width)
+}
+
+// openTopic shows the page of a language topic from the top
+func (m *HelpModel) openTopic(topic LangTopic) {
+	m.topic = topic
+	m.detailed = true
+	m.status = ""
+
+	width, height, _ := m.detailLayout()
+	m.page = viewport.New(width, height)
+	m.page.SetContent(renderLangTopic(topic, width))
+	m.help = topicHelp(m.width)
+}
+
+// refreshPage renders the page again after a resize or a new "see also" selection
+func (m *HelpModel) refreshPage() {
+	width, height, _ := m.detailLayout()
+	m.page.Width, m.page.Height = width, height
+	if m.tab == helpTabLanguage {
+		m.page.SetContent(renderLangTopic(m.topic, width))
+		return
+	}
+	m.page.SetContent(renderCommandHelp(m.selectedItem, width, m.seeAlso))
+}
+
+// activeList is the list of the open tab
+func (m *HelpModel) activeList() *list.Model {
+	if m.tab == helpTabLanguage {
+		return &m.topics
+	}
+	return &m.list
 }
 
 func (m HelpModel) Init() tea.Cmd {
@@ -250,33 +754,83 @@ func (m HelpModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		m.list.SetSize(listWidth, listHeight)
+		m.topics.SetSize(listWidth, listHeight)
 
 		// Note: Delegate width will be updated on next render
 
 		// Update help message based on screen width
-		if m.width < 40 {
-			m.help = "/ filter • c cat • q quit • ↑/↓ • enter"
-		} else if m.width < 60 {
-			m.help = "/ filter • c cat • q quit • ↑/↓ nav • enter info"
-		} else {
-			m.help = "/ filter • c categories • q quit • ↑/↓ navigate • enter details"
+		m.help = listHelp(m.width, m.tab)
+
+		if m.detailed {
+			m.refreshPage()
+			m.help = detailHelp(m.width)
+			if m.tab == helpTabLanguage {
+				m.help = topicHelp(m.width)
+			}
 		}
+		return m, nil
 
+	case exampleRunMsg:
+		if msg.err != nil {
+			m.status = msg.topic.examplePath() + " failed: " + msg.err.Error()
+		} else {
+			m.status = "Ran " + msg.topic.examplePath()
+		}
 		return m, nil
 	}
 
+	// Handle the page of a language topic
+	if m.detailed && m.tab == helpTabLanguage {
+		return m.updateTopic(msg)
+	}
+
 	// Handle detailed view mode
 	if m.detailed {
-		switch msg := msg.(type) {
-		case tea.KeyMsg:
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			seeAlso := m.selectedItem.seeAlso
+
 			switch msg.String() {
 			case "esc", "q", "backspace":
+				// Go back through the followed "see also" entries first
+				if len(m.history) > 0 {
+					previous := m.history[len(m.history)-1]
+					m.history = m.history[:len(m.history)-1]
+					m.openCommand(previous)
+					return m, nil
+				}
 				m.detailed = false
-				m.help = "/ filter • c categories • q quit • ↑/↓ navigate • <CR> details"
+				m.help = listHelp(m.width, m.tab)
+				return m, nil
+			case "tab", "shift+tab":
+				if len(seeAlso) == 0 {
+					return m, nil
+				}
+				if msg.String() == "tab" {
+					m.seeAlso = (m.seeAlso + 1) % len(seeAlso)
+				} else {
+					m.seeAlso = (m.seeAlso - 1 + len(seeAlso)) % len(seeAlso)
+				}
+				// "See also" is the last section, keep it in sight
+				m.refreshPage()
+				m.page.GotoBottom()
+				return m, nil
+			case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+				if idx := int(msg.Runes[0] - '1'); idx < len(seeAlso) {
+					m.seeAlso = idx
+					return m.followSeeAlso(), nil
+				}
+				return m, nil
+			case "enter":
+				if m.seeAlso >= 0 {
+					return m.followSeeAlso(), nil
+				}
 				return m, nil
 			}
 		}
-		return m, nil
+
+		var cmd tea.Cmd
+		m.page, cmd = m.page.Update(msg)
+		return m, cmd
 	}
 
 	// Handle category selection mode
@@ -306,15 +860,22 @@ func (m HelpModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	// Handle list view mode
+	active := m.activeList()
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "q", "esc":
-			if m.list.FilterState() == list.Filtering {
-				m.list.ResetFilter()
+			if active.FilterState() == list.Filtering {
+				active.ResetFilter()
 				return m, nil
 			}
 			return m, tea.Quit
+		case "tab", "shift+tab":
+			if active.FilterState() != list.Filtering {
+				m.tab = 1 - m.tab
+				m.help = listHelp(m.width, m.tab)
+				return m, nil
+			}
 		case "/":
 			if m.width < 40 {
 				m.help = "Filter • ESC • ENTER"
@@ -324,21 +885,80 @@ func (m HelpModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.help = "Type to filter • ESC to cancel • ENTER to select"
 			}
 		case "c":
-			m.showingCategory = true
-			return m, nil
+			if m.tab == helpTabCommands {
+				m.showingCategory = true
+				return m, nil
+			}
 		case "enter":
-			if i, ok := m.list.SelectedItem().(Command); ok {
-				m.selectedItem = i
-				m.detailed = true
+			if active.FilterState() == list.Filtering {
+				break
+			}
+			if i, ok := active.SelectedItem().(Command); ok {
+				m.history = nil
+				m.openCommand(i)
+				return m, nil
+			}
+			if topic, ok := active.SelectedItem().(LangTopic); ok {
+				m.openTopic(topic)
+				return m, nil
 			}
 		}
 	}
 
 	var cmd tea.Cmd
-	m.list, cmd = m.list.Update(msg)
+	*active, cmd = active.Update(msg)
 	return m, cmd
 }
 
+// updateTopic handles the keys of a language topic's page
+func (m HelpModel) updateTopic(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc", "q", "backspace":
+			m.detailed = false
+			m.help = listHelp(m.width, m.tab)
+			return m, nil
+		case "r":
+			// Run prints to the terminal, which the TUI gives up until it's done
+			topic := m.topic
+			m.status = "Running " + topic.examplePath() + "..."
+			return m, tea.Exec(&exampleRun{topic: topic}, func(err error) tea.Msg {
+				return exampleRunMsg{topic: topic, err: err}
+			})
+		case "w":
+			path, err := writeExample(m.topic, ".")
+			if err != nil {
+				m.status = "Couldn't write the example: " + err.Error()
+			} else {
+				m.status = "Wrote " + path + ", run it with: r2d2 run " + path
+			}
+			return m, nil
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			if idx := int(msg.Runes[0] - '1'); idx < len(m.topic.seeAlso) {
+				if topic, ok := findLangTopic(m.topic.seeAlso[idx]); ok {
+					m.openTopic(topic)
+				}
+			}
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.page, cmd = m.page.Update(msg)
+	return m, cmd
+}
+
+// followSeeAlso opens the highlighted "see also" entry, remembering the page it leaves
+func (m HelpModel) followSeeAlso() HelpModel {
+	target, ok := findCommand(m.selectedItem.seeAlso[m.seeAlso])
+	if !ok {
+		return m
+	}
+	m.history = append(m.history, m.selectedItem)
+	m.openCommand(target)
+	return m
+}
+
 func (m *HelpModel) filterByCategory(category string) {
 	m.filterCategory = category
 
@@ -404,114 +1024,19 @@ func (m HelpModel) View() string {
 		return containerStyle.Width(categoryWidth).Padding(padding).Render(sb.String())
 	}
 
-	// Detailed view: show complete command information
+	// Detailed view: the page of the command or topic, scrolled in the viewport
 	if m.detailed {
-		detailWidth := containerWidth
-		if detailWidth > 80 {
-			detailWidth = 80
-		}
-
-		// Use minimal padding for very small screens
-		padding := 1
-		if m.width < 50 {
-			padding = 0
-		}
-		detailBox := containerStyle.Width(detailWidth).Padding(padding)
-
-		title := lipgloss.NewStyle().
-			Foreground(highlightColor).
-			Bold(true).
-			Render(m.selectedItem.name)
-
-		category := lipgloss.NewStyle().
-			Foreground(subtleColor).
-			Italic(true).
-			Render(m.selectedItem.category)
-
-		description := lipgloss.NewStyle().
-			Foreground(infoColor).
-			Render(m.selectedItem.description)
-
-		usage := lipgloss.NewStyle().
-			Foreground(specialColor).
-			Render(m.selectedItem.usage)
-
-		// Examples section with word wrapping for small screens
-		var examplesText string
-		maxExampleWidth := detailWidth - 8
-		if padding == 0 {
-			maxExampleWidth = detailWidth - 2
-		}
-
-		for _, example := range m.selectedItem.examples {
-			if len(example) > maxExampleWidth && maxExampleWidth > 15 {
-				// Wrap long examples on small screens
-				wrapped := ""
-				words := strings.Fields(example)
-				currentLine := ""
-				indent := "      "
-				if m.width < 50 {
-					indent = "  "
-				}
-
-				for _, word := range words {
-					if len(currentLine)+len(word)+1 <= maxExampleWidth {
-						if currentLine == "" {
-							currentLine = word
-						} else {
-							currentLine += " " + word
-						}
-					} else {
-						if wrapped != "" {
-							wrapped += "\n" + indent
-						}
-						wrapped += currentLine
-						currentLine = word
-					}
-				}
-				if wrapped != "" {
-					wrapped += "\n" + indent
-				}
-				wrapped += currentLine
-				examplesText += exampleStyle.Render(wrapped) + "\n"
-			} else {
-				examplesText += exampleStyle.Render(example) + "\n"
-			}
-		}
-
-		// Compact layout for very small screens
-		if m.width < 50 {
-			return detailBox.Render(
-				lipgloss.JoinVertical(lipgloss.Left,
-					title,
-					category,
-					"",
-					description,
-					"",
-					"Usage:",
-					usage,
-					"",
-					"Examples:",
-					examplesText,
-					statusMessageStyle.Render("ESC = back"),
-				),
-			)
-		} else {
-			return detailBox.Render(
-				lipgloss.JoinVertical(lipgloss.Left,
-					title,
-					category,
-					"",
-					description,
-					"",
-					"Usage: "+usage,
-					"",
-					"Examples:",
-					examplesText,
-					statusMessageStyle.Render("ESC to return"),
-				),
-			)
+		width, _, padding := m.detailLayout()
+		help := statusMessageStyle.Render(m.help)
+		if m.status != "" && m.tab == helpTabLanguage {
+			help = statusMessageStyle.Foreground(infoColor).Render(wrapText(m.status, width-2))
 		}
+		return containerStyle.Width(width + 2*padding).Padding(padding).Render(
+			lipgloss.JoinVertical(lipgloss.Left,
+				m.page.View(),
+				help,
+			),
+		)
 	}
 
 	// List view: show command list and help message
@@ -529,7 +1054,8 @@ func (m HelpModel) View() string {
 	return containerStyle.Width(listContainerWidth).Padding(padding).Render(
 		lipgloss.JoinVertical(
 			lipgloss.Left,
-			m.list.View(),
+			renderHelpTabs(m.tab),
+			m.activeList().View(),
 			statusMessageStyle.Render(m.help),
 		),
 	)
@@ -548,7 +1074,7 @@ func ShowHelpStatic() {
 	}
 
 	// Display commands by category
-	categories := []string{CategoryBasic, CategoryBuild, CategoryUtil}
+	categories := []string{CategoryBasic, CategoryBuild, CategoryUtil, CategoryPkg}
 
 	for _, category := range categories {
 		if cmds, exists := categoryMap[category]; exists && len(cmds) > 0 {
@@ -583,7 +1109,40 @@ func ShowHelpStatic() {
 	fmt.Println(statusMessageStyle.Render("For interactive help, use: r2d2 help (without 'static')"))
 }
 
+// helpPageWidth is the width pages are printed at: the terminal's, within 30 to 80
+func helpPageWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width > 80 {
+		width = 80
+	}
+	if width < 30 {
+		width = 30
+	}
+	return width
+}
+
+// ShowCommandHelp prints the page of one command, what 'r2d2 <command> --help' shows
+func ShowCommandHelp(cmd Command) {
+	fmt.Print(renderCommandHelp(cmd, helpPageWidth(), -1))
+}
+
+// ShowHelp opens the interactive help on the list of commands
 func ShowHelp() {
+	showHelpTab(helpTabCommands)
+}
+
+// ShowLangHelp opens the interactive help on the language topics, or lists
+// them when the output isn't a terminal
+func ShowLangHelp() {
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		ShowLangTopics()
+		return
+	}
+	showHelpTab(helpTabLanguage)
+}
+
+// showHelpTab runs the interactive help, starting on the given tab
+func showHelpTab(tab int) {
 	// Get terminal dimensions
 	width, height, err := term.GetSize(int(os.Stdout.Fd()))
 	if err != nil {
@@ -591,6 +1150,17 @@ func ShowHelp() {
 		width, height = 80, 24
 	}
 
+	// Initialize and run the program
+	p := tea.NewProgram(newHelpModel(tab, width, height), tea.WithAltScreen())
+
+	if _, err := p.Run(); err != nil {
+		r2d2Styles.ErrorMessage(err.Error())
+		os.Exit(1)
+	}
+}
+
+// newHelpModel sets up the help TUI on tab for a terminal of width x height
+func newHelpModel(tab, width, height int) HelpModel {
 	// Get unique categories
 	categoryMap := make(map[string]bool)
 	for _, cmd := range commands {
@@ -640,30 +1210,28 @@ func ShowHelp() {
 		Foreground(specialColor)
 	listModel.FilterInput.Placeholder = "Filter..."
 
-	// Set initial help message based on width
-	helpMsg := "/ filter • c categories • q quit • ↑/↓ navigate • enter details"
-	if width < 40 {
-		helpMsg = "/ filter • c cat • q quit • ↑/↓ • enter"
-	} else if width < 60 {
-		helpMsg = "/ filter • c cat • q quit • ↑/↓ nav • enter info"
+	// The language tab lists the topics the same way
+	topicItems := make([]list.Item, len(langTopics))
+	for i, topic := range langTopics {
+		topicItems[i] = topic
 	}
-
-	// Initialize and run the program
-	p := tea.NewProgram(
-		HelpModel{
-			list:           listModel,
-			help:           helpMsg,
-			detailed:       false,
-			categories:     categories,
-			filterCategory: "",
-			width:          width,
-			height:         height,
-		},
-		tea.WithAltScreen(),
-	)
-
-	if _, err := p.Run(); err != nil {
-		r2d2Styles.ErrorMessage(err.Error())
-		os.Exit(1)
+	topicsModel := list.New(topicItems, delegate, listWidth, listHeight)
+	topicsModel.Title = "R2D2 Language"
+	topicsModel.SetShowPagination(false)
+	topicsModel.Styles.Title = headingStyle
+	topicsModel.FilterInput.PromptStyle = listModel.FilterInput.PromptStyle
+	topicsModel.FilterInput.TextStyle = listModel.FilterInput.TextStyle
+	topicsModel.FilterInput.Placeholder = "Filter..."
+
+	return HelpModel{
+		tab:            tab,
+		list:           listModel,
+		topics:         topicsModel,
+		help:           listHelp(width, tab),
+		detailed:       false,
+		categories:     categories,
+		filterCategory: "",
+		width:          width,
+		height:         height,
 	}
 }
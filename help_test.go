@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestCommandsMetadata(t *testing.T) {
+	for _, cmd := range commands {
+		t.Run(cmd.name, func(t *testing.T) {
+			if cmd.long == "" {
+				t.Error("command has no long help")
+			}
+			if len(cmd.exitCodes) == 0 {
+				t.Error("command documents no exit codes")
+			}
+			for _, name := range cmd.seeAlso {
+				if _, ok := findCommand(name); !ok || name == cmd.name {
+					t.Errorf("see also %q isn't another command", name)
+				}
+			}
+			for _, flag := range cmd.flags {
+				if !strings.HasPrefix(flag.name, "-") || flag.description == "" {
+					t.Errorf("flag %+v needs dashes and a description", flag)
+				}
+				if !strings.Contains(cmd.usage, flag.name) {
+					t.Errorf("usage %q doesn't show %s", cmd.usage, flag.name)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	md := "Compiles a program.\n\n- **one** item that is long enough to wrap\n  onto a second line\n- `two`\n\n```\nr2d2 build - -o app\n```"
+	got := renderMarkdown(md, 30)
+
+	for _, want := range []string{"Compiles a program.", "• one item", "• two", "r2d2 build - -o app"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("renderMarkdown() is missing %q:\n%s", want, got)
+		}
+	}
+	for _, line := range strings.Split(got, "\n") {
+		if lipgloss.Width(line) > 30 {
+			t.Errorf("line %q is wider than 30", line)
+		}
+	}
+}
+
+func TestRenderCommandHelp(t *testing.T) {
+	cmd, _ := findCommand("js")
+
+	for _, width := range []int{30, 80} {
+		got := renderCommandHelp(cmd, width, -1)
+		// Wrapping may split the phrases across lines
+		text := strings.Join(strings.Fields(got), " ")
+		for _, want := range []string{"Usage", "Description", "Flags", "--outdir <dir>", "(default: dist)", "Examples", "Exit codes", "See also"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("width %d: page is missing %q", width, want)
+			}
+		}
+		for _, line := range strings.Split(got, "\n") {
+			if lipgloss.Width(line) > width {
+				t.Errorf("width %d: line %q is too wide", width, line)
+			}
+		}
+	}
+}
+
+func TestHelpModelSeeAlso(t *testing.T) {
+	build, _ := findCommand("build")
+	m := HelpModel{width: 80, height: 24}
+	m.openCommand(build)
+
+	key := func(k string) {
+		var msg tea.KeyMsg
+		switch k {
+		case "tab":
+			msg = tea.KeyMsg{Type: tea.KeyTab}
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		model, _ := m.Update(msg)
+		m = model.(HelpModel)
+	}
+
+	// Tab highlights the first entry, enter follows it
+	key("tab")
+	key("enter")
+	if m.selectedItem.name != build.seeAlso[0] {
+		t.Fatalf("enter opened %q, want %q", m.selectedItem.name, build.seeAlso[0])
+	}
+
+	// A number follows an entry straight away
+	key("1")
+	if m.selectedItem.name != m.history[len(m.history)-1].seeAlso[0] {
+		t.Errorf("1 opened %q", m.selectedItem.name)
+	}
+
+	// Esc goes back through the followed pages, then to the list
+	key("esc")
+	key("esc")
+	if !m.detailed || m.selectedItem.name != "build" {
+		t.Errorf("esc went back to %q, want build", m.selectedItem.name)
+	}
+	key("esc")
+	if m.detailed {
+		t.Error("esc on the first page didn't go back to the list")
+	}
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Formats of r2d2 help --man and --markdown
+const (
+	HelpFormatMan      = "man"
+	HelpFormatMarkdown = "md"
+)
+
+// Where the generated command docs are checked in
+const (
+	manDocsDir       = "docs/man"
+	referenceDocsDir = "docs/reference"
+)
+
+// Categories in the order the docs list them
+var helpCategories = []string{CategoryBasic, CategoryBuild, CategoryUtil, CategoryPkg}
+
+// Line heading every generated markdown page
+const generatedNotice = "<!-- Generated by 'r2d2 help --markdown' from the command table in help.go. Do not edit. -->"
+
+// helpDocPages renders every command page and the overview in the given format,
+// keyed by file name
+func helpDocPages(format string) (map[string]string, error) {
+	pages := make(map[string]string)
+	switch format {
+	case HelpFormatMan:
+		pages["r2d2.1"] = manIndexPage()
+		for _, cmd := range commands {
+			pages["r2d2-"+cmd.name+".1"] = manCommandPage(cmd)
+		}
+	case HelpFormatMarkdown:
+		pages["index.md"] = markdownHelpIndex()
+		for _, cmd := range commands {
+			pages[cmd.name+".md"] = markdownHelpPage(cmd)
+		}
+	default:
+		return nil, fmt.Errorf("unknown help format %q (use man or md)", format)
+	}
+	return pages, nil
+}
+
+// WriteHelpDocs writes the pages of every command in the given format into outDir
+func WriteHelpDocs(format string, outDir string) ([]string, error) {
+	pages, err := helpDocPages(format)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for name, content := range pages {
+		path := filepath.Join(outDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	slices.Sort(written)
+	return written, nil
+}
+
+// RunHelpDocs implements 'r2d2 help --man <dir>' and 'r2d2 help --markdown <dir>'
+func RunHelpDocs(format string, outDir string) error {
+	written, err := WriteHelpDocs(format, outDir)
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+	fmt.Println(InfoMessage(fmt.Sprintf("Wrote %d pages to %s", len(written), outDir)))
+	return nil
+}
+
+// commandsIn lists the commands of a category, in table order
+func commandsIn(category string) []Command {
+	var cmds []Command
+	for _, cmd := range commands {
+		if cmd.category == category {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds
+}
+
+// usageLines splits a usage with alternatives ("r2d2 x a | r2d2 x b") into one line each
+func usageLines(usage string) []string {
+	return strings.Split(usage, " | r2d2 ")
+}
+
+// roffEscape escapes text for roff: backslashes, dashes and a leading dot or quote
+func roffEscape(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, "-", `\-`)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
+
+// roffInline escapes text and turns its code spans and bold text into bold
+func roffInline(text string) string {
+	text = roffEscape(text)
+	text = codeSpanPattern.ReplaceAllString(text, `\fB$1\fR`)
+	return boldPattern.ReplaceAllString(text, `\fB$1\fR`)
+}
+
+// roffMarkdown converts the markdown of a command page to roff requests
+func roffMarkdown(md string) string {
+	var sb strings.Builder
+	var paragraph []string
+	inCode := false
+
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		text := strings.Join(paragraph, " ")
+		paragraph = nil
+		if bullet, ok := strings.CutPrefix(text, "- "); ok {
+			sb.WriteString(".IP \\(bu 2\n" + roffInline(bullet) + "\n")
+			return
+		}
+		sb.WriteString(".PP\n" + roffInline(text) + "\n")
+	}
+
+	for _, line := range strings.Split(md, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			if inCode {
+				sb.WriteString(".fi\n.RE\n")
+			} else {
+				sb.WriteString(".PP\n.RS 4\n.nf\n")
+			}
+			inCode = !inCode
+		case inCode:
+			sb.WriteString(roffEscape(line) + "\n")
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			sb.WriteString(".SS " + roffEscape(strings.TrimSpace(strings.TrimLeft(trimmed, "#"))) + "\n")
+		case strings.HasPrefix(trimmed, "- "):
+			flush()
+			paragraph = append(paragraph, trimmed)
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	return sb.String()
+}
+
+// manHeader starts a man page: the title line and the NAME section
+func manHeader(sb *strings.Builder, name string, summary string) {
+	sb.WriteString(".\\\" Generated by 'r2d2 help --man' from the command table in help.go. Do not edit.\n")
+	sb.WriteString(fmt.Sprintf(".TH %s 1 \"\" \"r2d2\" \"R2D2 Manual\"\n", strings.ToUpper(roffEscape(name))))
+	sb.WriteString(".SH NAME\n" + roffEscape(name) + " \\- " + roffEscape(summary) + "\n")
+}
+
+// manIndexPage renders r2d2(1), the overview of every command
+func manIndexPage() string {
+	var sb strings.Builder
+	manHeader(&sb, "r2d2", "the R2D2 programming language")
+
+	sb.WriteString(".SH SYNOPSIS\n.B r2d2\n.I command\n[\\fIarguments\\fR]\n")
+	sb.WriteString(".SH DESCRIPTION\n")
+	sb.WriteString(".PP\nCompiles, runs and documents R2D2 programs, and manages their packages.\n")
+	sb.WriteString("Every command has its own page, and prints it with \\fB\\-\\-help\\fR.\n")
+
+	sb.WriteString(".SH COMMANDS\n")
+	for _, category := range helpCategories {
+		sb.WriteString(".SS " + roffEscape(strings.ToUpper(category[:1])+category[1:]) + "\n")
+		for _, cmd := range commandsIn(category) {
+			sb.WriteString(".TP\n\\fBr2d2 " + roffEscape(cmd.name) + "\\fR\n")
+			sb.WriteString(roffEscape(cmd.description) + ". See \\fBr2d2\\-" + roffEscape(cmd.name) + "\\fR(1).\n")
+		}
+	}
+
+	sb.WriteString(".SH EXIT STATUS\n")
+	for _, exit := range commonExitCodes {
+		sb.WriteString(fmt.Sprintf(".TP\n.B %d\n%s\n", exit.code, roffEscape(exit.meaning)))
+	}
+
+	sb.WriteString(".SH SEE ALSO\n")
+	refs := make([]string, len(commands))
+	for i, cmd := range commands {
+		refs[i] = "\\fBr2d2\\-" + roffEscape(cmd.name) + "\\fR(1)"
+	}
+	sb.WriteString(strings.Join(refs, ",\n") + "\n")
+	return sb.String()
+}
+
+// manCommandPage renders r2d2-<command>(1)
+func manCommandPage(cmd Command) string {
+	var sb strings.Builder
+	manHeader(&sb, "r2d2-"+cmd.name, cmd.description)
+
+	sb.WriteString(".SH SYNOPSIS\n.nf\n")
+	for i, line := range usageLines(cmd.usage) {
+		if i > 0 {
+			line = "r2d2 " + line
+		}
+		sb.WriteString(roffEscape(line) + "\n")
+	}
+	sb.WriteString(".fi\n")
+
+	if cmd.long != "" {
+		sb.WriteString(".SH DESCRIPTION\n" + roffMarkdown(cmd.long))
+	}
+
+	if len(cmd.flags) > 0 {
+		sb.WriteString(".SH OPTIONS\n")
+		for _, flag := range cmd.flags {
+			sb.WriteString(".TP\n\\fB" + roffEscape(flag.name) + "\\fR")
+			if flag.typ != "" {
+				sb.WriteString(" \\fI" + roffEscape(flag.typ) + "\\fR")
+			}
+			text := flag.description
+			if flag.def != "" {
+				text += " (default: " + flag.def + ")"
+			}
+			sb.WriteString("\n" + roffEscape(text) + "\n")
+		}
+	}
+
+	if len(cmd.examples) > 0 {
+		sb.WriteString(".SH EXAMPLES\n.PP\n.RS 4\n.nf\n")
+		for _, example := range cmd.examples {
+			sb.WriteString(roffEscape(example) + "\n")
+		}
+		sb.WriteString(".fi\n.RE\n")
+	}
+
+	if len(cmd.exitCodes) > 0 {
+		sb.WriteString(".SH EXIT STATUS\n")
+		for _, exit := range cmd.exitCodes {
+			sb.WriteString(fmt.Sprintf(".TP\n.B %d\n%s\n", exit.code, roffEscape(exit.meaning)))
+		}
+	}
+
+	sb.WriteString(".SH SEE ALSO\n")
+	refs := []string{"\\fBr2d2\\fR(1)"}
+	for _, name := range cmd.seeAlso {
+		refs = append(refs, "\\fBr2d2\\-"+roffEscape(name)+"\\fR(1)")
+	}
+	sb.WriteString(strings.Join(refs, ",\n") + "\n")
+	return sb.String()
+}
+
+// markdownEscape keeps the <placeholders> of prose from being read as HTML tags,
+// leaving code spans alone
+func markdownEscape(text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, span := range codeSpanPattern.FindAllStringIndex(text, -1) {
+		sb.WriteString(strings.NewReplacer("<", "&lt;", ">", "&gt;").Replace(text[last:span[0]]))
+		sb.WriteString(text[span[0]:span[1]])
+		last = span[1]
+	}
+	sb.WriteString(strings.NewReplacer("<", "&lt;", ">", "&gt;").Replace(text[last:]))
+	return sb.String()
+}
+
+// markdownCell makes text safe inside a table cell
+func markdownCell(text string) string {
+	return strings.ReplaceAll(markdownEscape(text), "|", `\|`)
+}
+
+// markdownLong escapes the prose of a command page, leaving its code blocks alone
+func markdownLong(md string) string {
+	lines := strings.Split(md, "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if !inCode {
+			lines[i] = markdownEscape(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markdownHelpIndex renders the list of every command, by category
+func markdownHelpIndex() string {
+	var sb strings.Builder
+	sb.WriteString(generatedNotice + "\n\n# Command Reference\n\n")
+	sb.WriteString("Every command also prints its page with `r2d2 <command> --help`.\n")
+	for _, category := range helpCategories {
+		sb.WriteString("\n## " + strings.ToUpper(category[:1]) + category[1:] + "\n\n")
+		sb.WriteString("| Command | Description |\n|---------|-------------|\n")
+		for _, cmd := range commandsIn(category) {
+			sb.WriteString(fmt.Sprintf("| [%s](%s.md) | %s |\n", cmd.name, cmd.name, markdownCell(cmd.description)))
+		}
+	}
+	return sb.String()
+}
+
+// markdownHelpPage renders the page of one command
+func markdownHelpPage(cmd Command) string {
+	var sb strings.Builder
+	sb.WriteString(generatedNotice + "\n\n")
+	sb.WriteString("# r2d2 " + cmd.name + "\n\n")
+	sb.WriteString(markdownEscape(cmd.description) + ". [Back to index](index.md)\n\n")
+
+	sb.WriteString("## Usage\n\n```\n")
+	for i, line := range usageLines(cmd.usage) {
+		if i > 0 {
+			line = "r2d2 " + line
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("```\n\n")
+
+	if cmd.long != "" {
+		sb.WriteString("## Description\n\n" + markdownLong(cmd.long) + "\n\n")
+	}
+
+	if len(cmd.flags) > 0 {
+		sb.WriteString("## Flags\n\n| Flag | Default | Description |\n|------|---------|-------------|\n")
+		for _, flag := range cmd.flags {
+			sb.WriteString(fmt.Sprintf("| `%s` | %s | %s |\n", strings.ReplaceAll(flagSignature(flag), "|", `\|`), markdownCell(flag.def), markdownCell(flag.description)))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(cmd.examples) > 0 {
+		sb.WriteString("## Examples\n\n```bash\n" + strings.Join(cmd.examples, "\n") + "\n```\n\n")
+	}
+
+	if len(cmd.exitCodes) > 0 {
+		sb.WriteString("## Exit codes\n\n| Code | Meaning |\n|------|---------|\n")
+		for _, exit := range cmd.exitCodes {
+			sb.WriteString(fmt.Sprintf("| %d | %s |\n", exit.code, markdownCell(exit.meaning)))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(cmd.seeAlso) > 0 {
+		links := make([]string, len(cmd.seeAlso))
+		for i, name := range cmd.seeAlso {
+			links[i] = fmt.Sprintf("[%s](%s.md)", name, name)
+		}
+		sb.WriteString("## See also\n\n" + strings.Join(links, ", ") + "\n")
+	}
+
+	return sb.String()
+}
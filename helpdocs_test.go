@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The checked-in pages must be what the command table generates
+func TestHelpDocsUpToDate(t *testing.T) {
+	tests := []struct {
+		format string
+		dir    string
+		flag   string
+	}{
+		{HelpFormatMan, manDocsDir, "--man"},
+		{HelpFormatMarkdown, referenceDocsDir, "--markdown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			fix := "run 'make docs-cli' or 'go run . help " + tt.flag + " " + tt.dir + "'"
+			pages, err := helpDocPages(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for name, want := range pages {
+				got, err := os.ReadFile(filepath.Join(tt.dir, name))
+				if err != nil {
+					t.Errorf("%s/%s is missing, %s", tt.dir, name, fix)
+					continue
+				}
+				if string(got) != want {
+					t.Errorf("%s/%s is out of date, %s", tt.dir, name, fix)
+				}
+			}
+
+			entries, err := os.ReadDir(tt.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range entries {
+				if _, ok := pages[entry.Name()]; !ok {
+					t.Errorf("%s/%s documents no command, delete it", tt.dir, entry.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestRoffInline(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain text", "plain text"},
+		{"use **-o** or `r2d2 add`", `use \fB\-o\fR or \fBr2d2 add\fR`},
+		{`a \ backslash`, `a \e backslash`},
+		{".starts with a dot", `\&.starts with a dot`},
+	}
+
+	for _, tt := range tests {
+		if got := roffInline(tt.in); got != tt.want {
+			t.Errorf("roffInline(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestManCommandPage(t *testing.T) {
+	cmd, _ := findCommand("doc")
+	page := manCommandPage(cmd)
+
+	for _, want := range []string{
+		`.TH R2D2\-DOC 1`,
+		".SH NAME\nr2d2\\-doc \\- ",
+		// Each alternative of the usage gets its own line
+		"\nr2d2 doc [paths...] [\\-\\-format md|html|json] [\\-\\-out <dir>]\nr2d2 doc <module[.member]> [paths...]\n",
+		".SH OPTIONS\n.TP\n\\fB\\-\\-format\\fR \\fImd|html|json\\fR\n",
+		".SH EXIT STATUS",
+		".SH SEE ALSO\n\\fBr2d2\\fR(1),\n\\fBr2d2\\-check\\fR(1)\n",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("man page is missing %q:\n%s", want, page)
+		}
+	}
+}
+
+func TestMarkdownHelpPage(t *testing.T) {
+	cmd, _ := findCommand("help")
+	page := markdownHelpPage(cmd)
+
+	for _, want := range []string{
+		"# r2d2 help\n",
+		// Placeholders are escaped in prose, not in code
+		"**&lt;command&gt;**",
+		"`r2d2 <command> --help`",
+		"| `--man <dir>` |  | Writes a man page",
+		"[version](version.md)",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("markdown page is missing %q:\n%s", want, page)
+		}
+	}
+
+	cmd, _ = findCommand("doc")
+	if page := markdownHelpPage(cmd); !strings.Contains(page, "`--format <md\\|html\\|json>`") {
+		t.Errorf("pipes in a flag aren't escaped for the table:\n%s", page)
+	}
+}
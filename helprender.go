@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Styles of the command pages
+var (
+	sectionStyle = lipgloss.NewStyle().
+			Foreground(highlightColor).
+			Bold(true)
+
+	codeSpanStyle = lipgloss.NewStyle().
+			Foreground(specialColor)
+
+	seeAlsoStyle = lipgloss.NewStyle().
+			Foreground(infoColor).
+			Underline(true)
+
+	selectedSeeAlsoStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color(whiteHex)).
+				Background(highlightColor).
+				Bold(true).
+				Padding(0, 1)
+)
+
+// Inline markdown the pages use: `code` and **bold**
+var (
+	codeSpanPattern = regexp.MustCompile("`([^`]+)`")
+	boldPattern     = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+)
+
+// findCommand returns the command called name, or one of its aliases
+func findCommand(name string) (Command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name || slices.Contains(cmd.aliases, name) {
+			return cmd, true
+		}
+	}
+	return Command{}, false
+}
+
+// renderInline styles the code spans and bold text of a line
+func renderInline(text string) string {
+	text = codeSpanPattern.ReplaceAllStringFunc(text, func(span string) string {
+		return codeSpanStyle.Render(strings.Trim(span, "`"))
+	})
+	return boldPattern.ReplaceAllStringFunc(text, func(bold string) string {
+		return lipgloss.NewStyle().Bold(true).Render(strings.Trim(bold, "*"))
+	})
+}
+
+// wrapText wraps text to width, without the padding lipgloss adds to short lines
+func wrapText(text string, width int) string {
+	lines := strings.Split(lipgloss.NewStyle().Width(width).Render(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderMarkdown renders the markdown of a command page for the terminal,
+// wrapped to width: paragraphs, headings, bullet lists and code blocks
+func renderMarkdown(md string, width int) string {
+	var out []string
+	var paragraph []string
+	inCode := false
+
+	wrap := func(text string, indent int) string {
+		return wrapText(text, width-indent)
+	}
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		text := renderInline(strings.Join(paragraph, " "))
+		paragraph = nil
+
+		// A bullet wraps under its own text
+		if bullet, ok := strings.CutPrefix(text, "- "); ok {
+			lines := strings.Split(wrap(bullet, 4), "\n")
+			for i, line := range lines {
+				if i == 0 {
+					lines[i] = "  • " + line
+				} else {
+					lines[i] = "    " + line
+				}
+			}
+			out = append(out, strings.Join(lines, "\n"))
+			return
+		}
+		out = append(out, wrap(text, 0))
+	}
+
+	for _, line := range strings.Split(md, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			inCode = !inCode
+		case inCode:
+			out = append(out, exampleStyle.Render(line))
+		case trimmed == "":
+			flush()
+			out = append(out, "")
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			out = append(out, sectionStyle.Render(strings.TrimSpace(strings.TrimLeft(trimmed, "#"))))
+		case strings.HasPrefix(trimmed, "- "):
+			// Every bullet starts its own item, continuation lines join it
+			flush()
+			paragraph = append(paragraph, trimmed)
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	return strings.Join(out, "\n")
+}
+
+// flagSignature is how a flag shows in the flag list: its name and value
+func flagSignature(flag Flag) string {
+	if flag.typ == "" {
+		return flag.name
+	}
+	return flag.name + " <" + flag.typ + ">"
+}
+
+// renderCommandHelp renders the page of a command, wrapped to width. It's the
+// detail view of the help TUI and the output of --help. selected is the
+// highlighted "see also" entry, -1 for none.
+func renderCommandHelp(cmd Command, width int, selected int) string {
+	var sb strings.Builder
+	section := func(title string) {
+		sb.WriteString("\n" + sectionStyle.Render(title) + "\n")
+	}
+
+	sb.WriteString(lipgloss.NewStyle().Foreground(highlightColor).Bold(true).Render(cmd.name))
+	sb.WriteString(" " + categoryStyle.Render(cmd.category) + "\n")
+	sb.WriteString(renderLines(lipgloss.NewStyle().Foreground(infoColor), wrapText(cmd.description, width)) + "\n")
+
+	section("Usage")
+	sb.WriteString(renderLines(exampleStyle, wrapText(cmd.usage, width-2)) + "\n")
+
+	if cmd.long != "" {
+		section("Description")
+		sb.WriteString(renderMarkdown(cmd.long, width) + "\n")
+	}
+
+	if len(cmd.flags) > 0 {
+		section("Flags")
+		column := 0
+		for _, flag := range cmd.flags {
+			column = max(column, len(flagSignature(flag)))
+		}
+		for _, flag := range cmd.flags {
+			text := flag.description
+			if flag.def != "" {
+				text += " (default: " + flag.def + ")"
+			}
+			// The description wraps in its own column when there's room for one,
+			// below the flag otherwise
+			indent := column + 4
+			first := "  " + highlightedCmdStyle.Render(fmt.Sprintf("%-*s", column, flagSignature(flag))) + "  "
+			if width-indent < 20 {
+				indent = 4
+				sb.WriteString("  " + highlightedCmdStyle.Render(flagSignature(flag)) + "\n")
+				first = strings.Repeat(" ", indent)
+			}
+			lines := strings.Split(wrapText(text, width-indent), "\n")
+			for i := range lines {
+				if i == 0 {
+					lines[i] = first + lines[i]
+				} else {
+					lines[i] = strings.Repeat(" ", indent) + lines[i]
+				}
+			}
+			sb.WriteString(strings.Join(lines, "\n") + "\n")
+		}
+	}
+
+	if len(cmd.examples) > 0 {
+		section("Examples")
+		for _, example := range cmd.examples {
+			sb.WriteString(renderLines(exampleStyle, wrapText(example, width-2)) + "\n")
+		}
+	}
+
+	if len(cmd.exitCodes) > 0 {
+		section("Exit codes")
+		for _, exit := range cmd.exitCodes {
+			lines := strings.Split(wrapText(exit.meaning, width-6), "\n")
+			sb.WriteString("  " + highlightedCmdStyle.Render(fmt.Sprintf("%-4d", exit.code)) + strings.Join(lines, "\n      ") + "\n")
+		}
+	}
+
+	if len(cmd.seeAlso) > 0 {
+		section("See also")
+		entries := make([]string, len(cmd.seeAlso))
+		for i, name := range cmd.seeAlso {
+			if i == selected {
+				entries[i] = selectedSeeAlsoStyle.Render(name)
+			} else {
+				entries[i] = seeAlsoStyle.Render(name)
+			}
+		}
+		sb.WriteString("  " + strings.Join(entries, "  ") + "\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Reserved words of the language (see R2D2.g4)
+var r2d2Keywords = map[string]bool{
+	"use": true, "import": true, "from": true, "interface": true, "module": true,
+	"implements": true, "export": true, "fn": true, "pseudo": true, "var": true,
+	"let": true, "const": true, "if": true, "else": true, "loop": true, "for": true,
+	"while": true, "break": true, "send": true, "continue": true, "return": true,
+	"switch": true, "case": true, "default": true, "type": true, "js": true,
+}
+
+// Built-in types of the language
+var r2d2Types = map[string]bool{
+	"number": true, "boolean": true, "string": true, "array": true, "object": true, "void": true,
+}
+
+// Literal words of the language
+var r2d2Literals = map[string]bool{
+	"true": true, "false": true, "null": true,
+}
+
+// Styles for syntax highlighting (using colors from styles.go)
+var (
+	keywordStyle   = lipgloss.NewStyle().Foreground(highlightColor).Bold(true)
+	operatorStyle  = lipgloss.NewStyle().Foreground(highlightColor)
+	typeNameStyle  = lipgloss.NewStyle().Foreground(infoColor)
+	stringLitStyle = lipgloss.NewStyle().Foreground(specialColor)
+	numberLitStyle = lipgloss.NewStyle().Foreground(accentColor)
+	commentStyle   = lipgloss.NewStyle().Foreground(subtleColor).Italic(true)
+	jsBlockStyle   = lipgloss.NewStyle().Foreground(warningColor)
+)
+
+// Characters highlighted as operators
+const operatorChars = "+-*/%=!<>&|"
+
+// highlightR2D2 returns the source with ANSI syntax highlighting applied.
+// It is a lexer-level approximation of R2D2.g4, good enough for display.
+func highlightR2D2(src string) string {
+	var sb strings.Builder
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		// Line comment
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			end := i
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			sb.WriteString(renderLines(commentStyle, string(runes[i:end])))
+			i = end
+
+		// Block comment
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			end := indexFrom(runes, i+2, "*/")
+			sb.WriteString(renderLines(commentStyle, string(runes[i:end])))
+			i = end
+
+		// Raw JavaScript block
+		case r == '<' && i+1 < len(runes) && runes[i+1] == '<':
+			end := indexFrom(runes, i+2, ">>")
+			sb.WriteString(renderLines(jsBlockStyle, string(runes[i:end])))
+			i = end
+
+		// Triple-quoted string
+		case r == '"' && i+2 < len(runes) && runes[i+1] == '"' && runes[i+2] == '"':
+			end := indexFrom(runes, i+3, `"""`)
+			sb.WriteString(renderLines(stringLitStyle, string(runes[i:end])))
+			i = end
+
+		// String literal
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' && runes[end] != '\n' {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(runes) && runes[end] == '"' {
+				end++
+			}
+			if end > len(runes) {
+				end = len(runes)
+			}
+			sb.WriteString(stringLitStyle.Render(string(runes[i:end])))
+			i = end
+
+		// Number literal
+		case unicode.IsDigit(r):
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.' || unicode.IsLetter(runes[end])) {
+				end++
+			}
+			sb.WriteString(numberLitStyle.Render(string(runes[i:end])))
+			i = end
+
+		// Identifier, keyword or type
+		case unicode.IsLetter(r) || r == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			word := string(runes[i:end])
+			switch {
+			case r2d2Keywords[word]:
+				sb.WriteString(keywordStyle.Render(word))
+			case r2d2Types[word]:
+				sb.WriteString(typeNameStyle.Render(word))
+			case r2d2Literals[word]:
+				sb.WriteString(numberLitStyle.Render(word))
+			default:
+				sb.WriteString(word)
+			}
+			i = end
+
+		default:
+			if strings.ContainsRune(operatorChars, r) {
+				sb.WriteString(operatorStyle.Render(string(r)))
+			} else {
+				sb.WriteRune(r)
+			}
+			i++
+		}
+	}
+
+	return sb.String()
+}
+
+// indexFrom returns the index just after the next occurrence of end, or the end of the input
+func indexFrom(runes []rune, from int, end string) int {
+	if from > len(runes) {
+		return len(runes)
+	}
+	idx := strings.Index(string(runes[from:]), end)
+	if idx < 0 {
+		return len(runes)
+	}
+	return from + len([]rune(string(runes[from:])[:idx])) + len([]rune(end))
+}
+
+// renderLines styles every line separately so multi-line tokens don't break the layout
+func renderLines(style lipgloss.Style, text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = style.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
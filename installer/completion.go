@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// completionShell returns the shell to install the completion for: PowerShell on
+// Windows, the login shell from $SHELL elsewhere, empty when 'r2d2 completion'
+// has no script for it
+func completionShell(goos string, loginShell string) string {
+	if goos == "windows" {
+		return "powershell"
+	}
+	switch shell := filepath.Base(loginShell); shell {
+	case "bash", "zsh", "fish":
+		return shell
+	}
+	return ""
+}
+
+// detectedCompletionShell is the completion shell of this system
+func detectedCompletionShell() string {
+	return completionShell(runtime.GOOS, os.Getenv("SHELL"))
+}
+
+// completionTarget returns where the completion script of a shell goes, and the
+// startup file line loading it for the shells that don't look for it on their own.
+// bash-completion and fish find the script in their completions directories.
+func completionTarget(shell string, home string) (string, *pathEdit) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	scriptDir := filepath.Join(home, ".r2d2", "completions")
+
+	switch shell {
+	case "bash":
+		return filepath.Join(dataHome, "bash-completion", "completions", "r2d2"), nil
+	case "fish":
+		return filepath.Join(configHome, "fish", "completions", "r2d2.fish"), nil
+	case "zsh":
+		// Sourced at the end of .zshrc, once compinit has defined compdef
+		file := filepath.Join(scriptDir, "_r2d2")
+		for _, rc := range shellRCFiles(home, "zsh") {
+			if rc.shell == "zsh" {
+				return file, &pathEdit{File: rc.path, Line: "(( $+functions[compdef] )) && source " + shellQuote(file) + " " + pathMarker}
+			}
+		}
+	case "powershell":
+		file := filepath.Join(scriptDir, "r2d2.ps1")
+		profile := filepath.Join(home, "Documents", "PowerShell", "Microsoft.PowerShell_profile.ps1")
+		return file, &pathEdit{File: profile, Line: ". '" + file + "' " + pathMarker}
+	}
+	return "", nil
+}
+
+// completionCommands is the shell equivalent of installCompletion writing file
+// and edit, shown in the plan
+func completionCommands(target string, shell string, file string, edit *pathEdit) []plannedCommand {
+	commands := []plannedCommand{{
+		step: "completion",
+		args: []string{"sh", "-c", "mkdir -p " + shellQuote(filepath.Dir(file)) + " && " + shellQuote(target) + " completion " + shell + " > " + shellQuote(file)},
+	}}
+	if edit != nil {
+		command := pathEditCommand(*edit)
+		command.step = "completion"
+		commands = append(commands, command)
+	}
+	return commands
+}
+
+// installCompletion writes the completion script the installed binary generates
+// for the shell, adds the line loading it when the shell needs one and records
+// both in the receipt. It returns the script's path.
+func installCompletion(ctx context.Context, binary string, shell string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	file, edit := completionTarget(shell, home)
+	if file == "" {
+		return "", fmt.Errorf("no completion for %s", shell)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	script, err := exec.CommandContext(ctx, binary, "completion", shell).Output()
+	if err != nil {
+		return "", fmt.Errorf("'r2d2 completion %s' failed: %v", shell, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(file, script, 0644); err != nil {
+		return "", err
+	}
+	if edit != nil && !hasPathEdit(*edit) {
+		if err := os.MkdirAll(filepath.Dir(edit.File), 0755); err != nil {
+			return "", err
+		}
+		if err := appendPathEdit(*edit); err != nil {
+			return "", err
+		}
+	}
+
+	receipt, err := loadReceipt()
+	if err != nil {
+		return file, err
+	}
+	receipt.Completion, receipt.CompletionEdit = file, edit
+	return file, saveReceipt(receipt)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompletionShell(t *testing.T) {
+	tests := []struct {
+		goos  string
+		shell string
+		want  string
+	}{
+		{"linux", "/bin/bash", "bash"},
+		{"linux", "/usr/bin/zsh", "zsh"},
+		{"darwin", "/opt/homebrew/bin/fish", "fish"},
+		{"linux", "/bin/tcsh", ""},
+		{"linux", "", ""},
+		{"windows", "", "powershell"},
+	}
+
+	for _, tt := range tests {
+		if got := completionShell(tt.goos, tt.shell); got != tt.want {
+			t.Errorf("completionShell(%q, %q) = %q, want %q", tt.goos, tt.shell, got, tt.want)
+		}
+	}
+}
+
+func TestCompletionTarget(t *testing.T) {
+	home := "/home/me"
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("ZDOTDIR", "")
+
+	tests := []struct {
+		shell    string
+		wantFile string
+		wantEdit string // startup file edited, empty for none
+	}{
+		{"bash", "/home/me/.local/share/bash-completion/completions/r2d2", ""},
+		{"fish", "/home/me/.config/fish/completions/r2d2.fish", ""},
+		{"zsh", "/home/me/.r2d2/completions/_r2d2", "/home/me/.zshrc"},
+		{"powershell", "/home/me/.r2d2/completions/r2d2.ps1", "/home/me/Documents/PowerShell/Microsoft.PowerShell_profile.ps1"},
+		{"tcsh", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			file, edit := completionTarget(tt.shell, home)
+			if file != filepath.FromSlash(tt.wantFile) {
+				t.Errorf("file = %s, want %s", file, tt.wantFile)
+			}
+			switch {
+			case tt.wantEdit == "" && edit != nil:
+				t.Errorf("edit = %+v, want none", edit)
+			case tt.wantEdit != "" && (edit == nil || edit.File != filepath.FromSlash(tt.wantEdit)):
+				t.Errorf("edit = %+v, want one in %s", edit, tt.wantEdit)
+			case edit != nil && (!strings.Contains(edit.Line, file) || !strings.HasSuffix(edit.Line, pathMarker)):
+				t.Errorf("edit line %q doesn't load %s", edit.Line, file)
+			}
+		})
+	}
+}
+
+func TestInstallCompletion(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("ZDOTDIR", "")
+	binary := filepath.Join(t.TempDir(), "r2d2")
+	os.WriteFile(binary, []byte("#!/bin/sh\necho \"#compdef r2d2 ($2)\"\n"), 0755)
+	saveReceipt(installReceipt{Binary: binary})
+
+	// Installing again leaves a single line in .zshrc
+	var file string
+	for i := 0; i < 2; i++ {
+		var err error
+		if file, err = installCompletion(context.Background(), binary, "zsh"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if script, _ := os.ReadFile(file); string(script) != "#compdef r2d2 (zsh)\n" {
+		t.Errorf("script = %q", script)
+	}
+	zshrc := filepath.Join(home, ".zshrc")
+	content, _ := os.ReadFile(zshrc)
+	if got := strings.Count(string(content), pathMarker); got != 1 {
+		t.Errorf("completion line added %d times:\n%s", got, content)
+	}
+	receipt, _ := loadReceipt()
+	if receipt.Completion != file || receipt.CompletionEdit == nil || receipt.CompletionEdit.File != zshrc {
+		t.Errorf("receipt = %+v", receipt)
+	}
+
+	// Uninstalling removes both
+	var out strings.Builder
+	runUninstall(options{}, &out, &out)
+	if fileExists(file) {
+		t.Error("completion script left after uninstall")
+	}
+	if content, _ := os.ReadFile(zshrc); strings.Contains(string(content), pathMarker) {
+		t.Errorf("completion line left after uninstall:\n%s", content)
+	}
+}
+
+func TestBuildPlanCompletion(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SHELL", "/bin/zsh")
+	t.Setenv("ZDOTDIR", "")
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "r2d2")
+	os.WriteFile(binary, []byte("bin"), 0755)
+
+	opts := options{source: binary, prefix: dir, noDeps: true, noModifyPath: true}
+	plan, err := buildPlan(OSInfo{OS: "Linux"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var completion []string
+	for _, c := range plan.commands {
+		if c.step == "completion" {
+			completion = append(completion, c.String())
+		}
+	}
+	if len(completion) != 2 || !strings.Contains(completion[0], "completion zsh") || !strings.Contains(completion[1], ".zshrc") {
+		t.Errorf("completion commands = %q", completion)
+	}
+	if !contains(plan.writes, filepath.Join(home, ".zshrc")) {
+		t.Errorf("writes = %q, want .zshrc", plan.writes)
+	}
+
+	opts.noCompletion = true
+	if plan, _ := buildPlan(OSInfo{OS: "Linux"}, opts); len(plan.commands) != 3 {
+		t.Errorf("plan with --no-completion has %d commands, want 3", len(plan.commands))
+	}
+}
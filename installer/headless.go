@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Exit codes of the installer
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+	exitDetect  = 3
+	exitDeps    = 4
+	exitFetch   = 5
+	exitBuild   = 6
+	exitInstall = 7
+	exitVerify  = 8
+
+	exitCancelled = 130 // interrupted, like a shell reports Ctrl+C
+)
+
+// errCancelled is reported for the step running when the installation is interrupted
+var errCancelled = errors.New("cancelled, run the installer again to resume")
+
+// installRun carries the state of an installation from one step to the next
+type installRun struct {
+	opts        options
+	osInfo      OSInfo
+	repoDir     string
+	binaryPath  string
+	prebuilt    bool   // the source was a binary, nothing to build
+	toolchain   string // toolchain directory the deps step created, empty when it was there already
+	installPath string
+	pathEdits   []pathEdit   // startup files putting the install directory on the PATH
+	version     string       // reported by the installed binary
+	completion  string       // completion script installed, empty when skipped
+	skipped     string       // why the completion wasn't installed
+	rep         reporter     // output and progress of the running step
+	state       *resumeState // nil when the run can't be resumed
+}
+
+// newInstallRun starts an installation, picking up the state an interrupted run
+// with the same options left behind unless --no-resume was given
+func newInstallRun(opts options) *installRun {
+	r := &installRun{opts: opts, rep: nopReporter{}, state: loadResumeState(opts)}
+	r.repoDir, r.binaryPath, r.prebuilt, r.toolchain = r.state.RepoDir, r.state.BinaryPath, r.state.Prebuilt, r.state.Toolchain
+	return r
+}
+
+// installStep is one stage of the installation pipeline
+type installStep struct {
+	name     string // identifier used in JSON output
+	message  string // what the step is doing, for humans
+	exitCode int    // exit code when the step fails
+	run      func(ctx context.Context, r *installRun) error
+	// finished tells whether what the step made in an interrupted run is still
+	// there, nil for steps that always run again
+	finished func(r *installRun) bool
+}
+
+// installSteps returns the pipeline for the given options
+func installSteps(opts options) []installStep {
+	steps := []installStep{
+		{"detect", "Detecting OS", exitDetect, func(ctx context.Context, r *installRun) error {
+			osInfo, err := detectOSInfo()
+			osInfo.BinDir = r.opts.binDir(osInfo.BinDir)
+			r.osInfo = osInfo
+			return err
+		}, nil},
+		{"deps", "Installing dependencies", exitDeps, func(ctx context.Context, r *installRun) error {
+			existed := fileExists(toolchainDir())
+			err := installDeps(ctx, r.osInfo, r.opts.tools(), r.rep)
+			if !existed && fileExists(toolchainDir()) {
+				r.toolchain = toolchainDir()
+			}
+			return err
+		}, func(r *installRun) bool {
+			return true
+		}},
+		{"clone", fetchMessage(opts), exitFetch, func(ctx context.Context, r *installRun) error {
+			prepared, err := prepareSource(ctx, r.opts.installSource(), r.opts.cloneRef(), r.rep)
+			if errors.Is(err, errNoCLI) && r.opts.source == "" {
+				prepared, err = cloneRelease(ctx, r.opts.targetVersion(), r.rep)
+			}
+			r.repoDir, r.binaryPath = prepared.repoDir, prepared.binaryPath
+			r.prebuilt = prepared.binaryPath != ""
+			return err
+		}, func(r *installRun) bool {
+			if r.prebuilt {
+				return fileExists(r.binaryPath)
+			}
+			return fileExists(r.repoDir)
+		}},
+		{"build", "Building CLI", exitBuild, func(ctx context.Context, r *installRun) (err error) {
+			if r.prebuilt {
+				return nil
+			}
+			r.binaryPath, err = buildBinary(ctx, r.repoDir, r.rep)
+			return err
+		}, func(r *installRun) bool {
+			return fileExists(r.binaryPath)
+		}},
+		{"install", "Installing binary", exitInstall, func(ctx context.Context, r *installRun) (err error) {
+			r.installPath, err = installAndRecord(r.binaryPath, r.opts, r.osInfo.BinDir, r.toolchain)
+			return err
+		}, nil},
+		{"path", "Setting up PATH", exitInstall, func(ctx context.Context, r *installRun) error {
+			dirs := []string{r.osInfo.BinDir}
+			if bin := filepath.Join(toolchainDir(), "bin"); fileExists(bin) {
+				dirs = append(dirs, bin)
+			}
+			for _, dir := range dirs {
+				edits, err := setupPathFor(r.opts, dir)
+				if err != nil {
+					return err
+				}
+				r.pathEdits = append(r.pathEdits, edits...)
+			}
+			return nil
+		}, nil},
+		{"verify", "Checking the installed version", exitInstall, func(ctx context.Context, r *installRun) error {
+			r.version = binaryVersion(r.installPath)
+			return checkVersion(r.version, r.opts.targetVersion())
+		}, nil},
+		// Completion is a convenience, the installation doesn't fail without it
+		{"completion", "Installing shell completion", exitInstall, func(ctx context.Context, r *installRun) error {
+			shell := detectedCompletionShell()
+			if shell == "" {
+				r.skipped = "no completion for this shell, see 'r2d2 completion --help'"
+				return nil
+			}
+			file, err := installCompletion(ctx, r.installPath, shell)
+			if err != nil {
+				r.skipped = err.Error()
+				return nil
+			}
+			r.completion = file
+			return nil
+		}, nil},
+	}
+
+	if opts.noCompletion {
+		steps = steps[:len(steps)-1]
+	}
+	if opts.noDeps {
+		steps = append(steps[:1], steps[2:]...)
+	}
+	return steps
+}
+
+// fetchMessage describes how the source is obtained
+func fetchMessage(opts options) string {
+	switch {
+	case opts.source != "":
+		return "Preparing source from " + opts.source
+	case opts.installSource() != "":
+		return "Downloading release " + releaseTag(opts.targetVersion())
+	case opts.cloneRef() != "":
+		return "Cloning repository at " + opts.cloneRef()
+	}
+	return "Cloning repository"
+}
+
+// cloneRelease builds a release from its tag, for releases whose archive holds no CLI
+func cloneRelease(ctx context.Context, version string, rep reporter) (preparedSource, error) {
+	tag := releaseTag(version)
+	if missing := missingTools([]string{"git", "go"}); len(missing) > 0 {
+		return preparedSource{}, fmt.Errorf("release %s has no %s binary, building it from the tag needs %s", tag, binaryName(), strings.Join(missing, " and "))
+	}
+	rep.logLine("release " + tag + " has no " + binaryName() + " binary, cloning the tag instead")
+	return prepareSource(ctx, "", tag, rep)
+}
+
+// checkVersion compares the version the installed binary reports with the expected
+// one, want being empty when any version will do
+func checkVersion(got string, want string) error {
+	if got == "" {
+		return fmt.Errorf("the installed binary doesn't run, 'r2d2 version' failed")
+	}
+	if want != "" && strings.TrimPrefix(got, "v") != strings.TrimPrefix(want, "v") {
+		return fmt.Errorf("the installed r2d2 reports version %s, expected %s", got, want)
+	}
+	return nil
+}
+
+// installLogger reports the progress of an installation
+type installLogger interface {
+	start(step installStep)
+	progress(step installStep, fraction float64, detail string)
+	logLine(step installStep, line string)
+	done(step installStep, detail string, elapsed time.Duration)
+	fail(step installStep, err error)
+	finish(r *installRun)
+}
+
+// stepReporter passes the output of the running step on to the logger
+type stepReporter struct {
+	logger installLogger
+	step   installStep
+}
+
+func (s stepReporter) logLine(line string) {
+	s.logger.logLine(s.step, line)
+}
+
+func (s stepReporter) progress(fraction float64, detail string) {
+	s.logger.progress(s.step, fraction, detail)
+}
+
+// progressThrottle lets at most one progress update through per interval,
+// plus the one finishing the step
+type progressThrottle struct {
+	interval time.Duration
+	last     time.Time
+}
+
+func (t *progressThrottle) allow(fraction float64) bool {
+	now := time.Now()
+	if fraction < 1 && now.Sub(t.last) < t.interval {
+		return false
+	}
+	t.last = now
+	return true
+}
+
+// newInstallLogger returns the logger for an output mode
+func newInstallLogger(output string, out io.Writer, errOut io.Writer) installLogger {
+	switch output {
+	case outputJSON:
+		return &jsonLogger{enc: json.NewEncoder(out), throttle: progressThrottle{interval: 500 * time.Millisecond}}
+	case outputQuiet:
+		return &plainLogger{out: io.Discard, errOut: errOut}
+	default:
+		return &plainLogger{out: out, errOut: errOut}
+	}
+}
+
+// plainLogger prints one line per event, errors going to errOut
+type plainLogger struct {
+	out    io.Writer
+	errOut io.Writer
+}
+
+func (l *plainLogger) start(step installStep) {
+	fmt.Fprintf(l.out, "==> %s...\n", step.message)
+}
+
+// The plain output keeps to one line per step, progress and raw output are left out
+func (l *plainLogger) progress(step installStep, fraction float64, detail string) {}
+
+func (l *plainLogger) logLine(step installStep, line string) {}
+
+func (l *plainLogger) done(step installStep, detail string, elapsed time.Duration) {
+	if detail != "" {
+		fmt.Fprintf(l.out, "    %s (%s)\n", detail, elapsed.Round(time.Millisecond))
+	}
+}
+
+func (l *plainLogger) fail(step installStep, err error) {
+	fmt.Fprintf(l.errOut, "error: %s failed: %v\n", step.name, err)
+}
+
+func (l *plainLogger) finish(r *installRun) {
+	fmt.Fprintf(l.out, "R2D2 CLI installed to %s\n", r.installPath)
+	if len(r.pathEdits) > 0 {
+		fmt.Fprintln(l.out, pathHint(r.osInfo.BinDir))
+	}
+}
+
+// installEvent is one line of the JSON output
+type installEvent struct {
+	Step       string  `json:"step"`
+	Status     string  `json:"status"` // start, progress, done, error or complete
+	Message    string  `json:"message,omitempty"`
+	Fraction   float64 `json:"fraction,omitempty"` // share of the step done, with progress
+	Error      string  `json:"error,omitempty"`
+	Path       string  `json:"path,omitempty"`
+	ExitCode   int     `json:"exitCode,omitempty"`
+	DurationMs int64   `json:"durationMs,omitempty"`
+}
+
+// jsonLogger prints one JSON object per event
+type jsonLogger struct {
+	enc      *json.Encoder
+	throttle progressThrottle
+}
+
+func (l *jsonLogger) start(step installStep) {
+	l.enc.Encode(installEvent{Step: step.name, Status: "start", Message: step.message})
+}
+
+func (l *jsonLogger) progress(step installStep, fraction float64, detail string) {
+	if l.throttle.allow(fraction) {
+		l.enc.Encode(installEvent{Step: step.name, Status: "progress", Message: detail, Fraction: max(fraction, 0)})
+	}
+}
+
+func (l *jsonLogger) logLine(step installStep, line string) {}
+
+func (l *jsonLogger) done(step installStep, detail string, elapsed time.Duration) {
+	l.enc.Encode(installEvent{Step: step.name, Status: "done", Message: detail, DurationMs: elapsed.Milliseconds()})
+}
+
+func (l *jsonLogger) fail(step installStep, err error) {
+	l.enc.Encode(installEvent{Step: step.name, Status: "error", Error: err.Error(), ExitCode: step.exitCode})
+}
+
+func (l *jsonLogger) finish(r *installRun) {
+	l.enc.Encode(installEvent{Step: "install", Status: "complete", Path: r.installPath})
+}
+
+// stepDetail describes the result of a step for the log
+func stepDetail(step installStep, r *installRun) string {
+	switch step.name {
+	case "detect":
+		return fmt.Sprintf("%s (%s), installing to %s", r.osInfo.OS, r.osInfo.PkgManager, r.osInfo.BinDir)
+	case "clone":
+		if r.prebuilt {
+			return "prebuilt binary " + r.binaryPath
+		}
+		return r.repoDir
+	case "build":
+		if r.prebuilt {
+			return "skipped, using the prebuilt binary"
+		}
+		return r.binaryPath
+	case "install":
+		return r.installPath
+	case "verify":
+		return "r2d2 version " + r.version
+	case "completion":
+		if r.completion == "" {
+			return "skipped, " + r.skipped
+		}
+		return r.completion
+	case "path":
+		if len(r.pathEdits) == 0 {
+			return r.osInfo.BinDir + " left as is"
+		}
+		var files []string
+		for _, edit := range r.pathEdits {
+			files = append(files, edit.File)
+		}
+		return "added " + r.osInfo.BinDir + " to " + strings.Join(files, ", ")
+	}
+	return ""
+}
+
+// runSteps runs the pipeline, stopping at the first failure, and returns the exit code.
+// Steps an interrupted run finished are skipped while their output is still there.
+func runSteps(ctx context.Context, steps []installStep, r *installRun, logger installLogger) int {
+	resuming := r.state != nil
+	for _, step := range steps {
+		logger.start(step)
+		started := time.Now()
+
+		if step.finished != nil {
+			if resuming && contains(r.state.Completed, step.name) && step.finished(r) {
+				logger.done(step, stepDetail(step, r)+", done in the previous run", time.Since(started))
+				continue
+			}
+			resuming = false
+		}
+
+		r.rep = stepReporter{logger: logger, step: step}
+		err := step.run(ctx, r)
+		if ctx.Err() != nil {
+			logger.fail(step, errCancelled)
+			return exitCancelled
+		}
+		if err != nil {
+			logger.fail(step, err)
+			var verr *verificationError
+			if errors.As(err, &verr) {
+				return exitVerify
+			}
+			return step.exitCode
+		}
+		r.stepDone(step.name)
+		logger.done(step, stepDetail(step, r), time.Since(started))
+	}
+
+	r.clearState()
+	logger.finish(r)
+	return exitOK
+}
+
+// runHeadless installs without the TUI, for scripts, containers and CI
+func runHeadless(ctx context.Context, opts options, out io.Writer, errOut io.Writer) int {
+	return runSteps(ctx, installSteps(opts), newInstallRun(opts), newInstallLogger(opts.output, out, errOut))
+}
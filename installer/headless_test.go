@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeSteps returns a pipeline that records the steps run, failing at failAt.
+// Like the real ones, deps, clone and build can be resumed.
+func fakeSteps(ran *[]string, failAt string) []installStep {
+	var steps []installStep
+	for i, name := range []string{"detect", "deps", "clone", "build", "install"} {
+		var finished func(r *installRun) bool
+		if name == "deps" || name == "clone" || name == "build" {
+			finished = func(r *installRun) bool { return true }
+		}
+		steps = append(steps, installStep{name, "Running " + name, exitDetect + i, func(ctx context.Context, r *installRun) error {
+			*ran = append(*ran, name)
+			if name == failAt {
+				return errors.New(name + " broke")
+			}
+			if name == "install" {
+				r.installPath = "/opt/r2d2/bin/r2d2"
+			}
+			return nil
+		}, finished})
+	}
+	return steps
+}
+
+func TestRunSteps(t *testing.T) {
+	tests := []struct {
+		name     string
+		failAt   string
+		wantCode int
+		wantRan  int
+	}{
+		{"success", "", exitOK, 5},
+		{"deps failure", "deps", exitDeps, 2},
+		{"build failure", "build", exitBuild, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ran []string
+			var out, errOut bytes.Buffer
+
+			code := runSteps(context.Background(), fakeSteps(&ran, tt.failAt), &installRun{}, newInstallLogger(outputPlain, &out, &errOut))
+			if code != tt.wantCode {
+				t.Errorf("runSteps() = %d, want %d", code, tt.wantCode)
+			}
+			if len(ran) != tt.wantRan {
+				t.Errorf("ran %v, want %d steps", ran, tt.wantRan)
+			}
+			if tt.failAt != "" && !strings.Contains(errOut.String(), tt.failAt+" broke") {
+				t.Errorf("stderr = %q, want the error", errOut.String())
+			}
+		})
+	}
+}
+
+func TestQuietLogger(t *testing.T) {
+	var ran []string
+	var out, errOut bytes.Buffer
+
+	runSteps(context.Background(), fakeSteps(&ran, "clone"), &installRun{}, newInstallLogger(outputQuiet, &out, &errOut))
+	if out.Len() != 0 {
+		t.Errorf("quiet output = %q, want nothing on stdout", out.String())
+	}
+	if !strings.Contains(errOut.String(), "clone broke") {
+		t.Errorf("quiet stderr = %q, want the error", errOut.String())
+	}
+}
+
+func TestJSONLogger(t *testing.T) {
+	var ran []string
+	var out bytes.Buffer
+
+	code := runSteps(context.Background(), fakeSteps(&ran, ""), &installRun{}, newInstallLogger(outputJSON, &out, &out))
+	if code != exitOK {
+		t.Fatalf("runSteps() = %d", code)
+	}
+
+	var events []installEvent
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event installEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+
+	// start and done for each step, then complete
+	if len(events) != 11 {
+		t.Fatalf("got %d events, want 11", len(events))
+	}
+	last := events[len(events)-1]
+	if last.Status != "complete" || last.Path != "/opt/r2d2/bin/r2d2" {
+		t.Errorf("last event = %+v", last)
+	}
+}
+
+func TestInstallStepsNoDeps(t *testing.T) {
+	for _, step := range installSteps(options{noDeps: true}) {
+		if step.name == "deps" {
+			t.Error("installSteps() includes deps with --no-deps")
+		}
+	}
+	if got := len(installSteps(options{})); got != 8 {
+		t.Errorf("installSteps() has %d steps, want 8", got)
+	}
+}
+
+func TestInstallStepsNoCompletion(t *testing.T) {
+	for _, step := range installSteps(options{noCompletion: true}) {
+		if step.name == "completion" {
+			t.Error("installSteps() includes completion with --no-completion")
+		}
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	tests := []struct {
+		got     string
+		want    string
+		wantErr bool
+	}{
+		{"0.2.4", "0.2.4", false},
+		{"v0.2.4", "0.2.4", false},
+		{"0.2.5-dev", "", false},
+		{"0.2.3", "0.2.4", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		if err := checkVersion(tt.got, tt.want); (err != nil) != tt.wantErr {
+			t.Errorf("checkVersion(%q, %q) = %v, wantErr %v", tt.got, tt.want, err, tt.wantErr)
+		}
+	}
+}
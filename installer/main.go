@@ -2,12 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
@@ -25,36 +28,52 @@ type installState int
 const (
 	stateWelcome installState = iota
 	stateDetectingOS
+	statePlan
 	stateInstallingDeps
 	stateCloning
 	stateBuilding
 	stateInstalling
+	stateSettingPath
 	stateCompleted
 	stateError
 )
 
 type model struct {
+	opts         options
 	state        installState
 	spinner      spinner.Model
 	progress     progress.Model
 	message      string
 	error        error
 	osInfo       OSInfo
+	plan         installPlan
 	installPath  string
+	pathEdits    []pathEdit
+	completion   string // completion script installed, empty when skipped
+	version      string // reported by the installed binary
 	currentStep  int
 	totalSteps   int
 	stepMessages []string
+	stepFraction float64 // share of the current step done
+	stepDetail   string  // what the current step reports, like bytes downloaded
+	logLines     []string
+	events       chan tea.Msg // from the installation running in the background
+	cancel       context.CancelFunc
+	cancelled    bool
 	width        int
 	height       int
 }
 
+// Lines of raw command output shown under the progress bar
+const logPaneLines = 8
+
 type OSInfo struct {
 	OS         string
 	PkgManager string
 	BinDir     string
 }
 
-func initialModel() model {
+func initialModel(opts options) model {
 	// Initialize styles first
 	initStyles()
 
@@ -65,19 +84,30 @@ func initialModel() model {
 	p := progress.New(progress.WithDefaultGradient())
 	p.Width = 30
 
+	fetchMessage := "Cloning repo..."
+	switch {
+	case opts.source != "":
+		fetchMessage = "Preparing source..."
+	case opts.installSource() != "":
+		fetchMessage = "Downloading release..."
+	}
+
 	return model{
+		opts:        opts,
 		state:       stateWelcome,
 		spinner:     s,
 		progress:    p,
-		totalSteps:  6,
+		totalSteps:  len(installSteps(options{})),
 		currentStep: 0,
 		stepMessages: []string{
 			"Detecting OS...",
 			"Installing deps...",
-			"Cloning repo...",
+			fetchMessage,
 			"Building CLI...",
 			"Installing...",
-			"Finalizing...",
+			"Setting up PATH...",
+			"Checking version...",
+			"Installing shell completion...",
 		},
 	}
 }
@@ -94,6 +124,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "q", "ctrl+c":
+			// A running installation is cancelled first, its commands killed
+			// through the context, and the installer quits once it stopped
+			if m.events != nil && !m.cancelled {
+				m.cancelled = true
+				m.cancel()
+				return m, nil
+			}
 			return m, tea.Quit
 		case "enter":
 			if m.state == stateWelcome {
@@ -104,9 +141,32 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					detectOS(),
 				)
 			}
+			if m.state == statePlan {
+				return m.startInstall()
+			}
 			if m.state == stateCompleted || m.state == stateError {
 				return m, tea.Quit
 			}
+		case "d":
+			// Deno is only needed by 'r2d2 run', so it can be left out
+			if m.state == statePlan {
+				m.opts.noDeno = !m.opts.noDeno
+				return m.replan()
+			}
+		case "p":
+			if m.state == statePlan {
+				m.opts.noModifyPath = !m.opts.noModifyPath
+				return m.replan()
+			}
+		case "c":
+			if m.state == statePlan {
+				m.opts.noCompletion = !m.opts.noCompletion
+				return m.replan()
+			}
+		case "s":
+			if m.state == statePlan {
+				m.message = savePlanScript(m.plan)
+			}
 		}
 
 	case tea.WindowSizeMsg:
@@ -127,43 +187,205 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case osDetectedMsg:
 		m.osInfo = OSInfo(msg)
-		m.state = stateInstallingDeps
-		m.currentStep = 2
-		return m, installDependencies(m.osInfo)
-
-	case depsInstalledMsg:
-		m.state = stateCloning
-		m.currentStep = 3
-		return m, cloneRepository()
-
-	case repositoryClonedMsg:
-		m.state = stateBuilding
-		m.currentStep = 4
-		return m, buildCLI(string(msg))
-
-	case cliBuiltMsg:
-		m.state = stateInstalling
-		m.currentStep = 5
-		return m, installBinary(string(msg), m.osInfo)
-
-	case binaryInstalledMsg:
-		m.installPath = string(msg)
-		m.state = stateCompleted
-		m.currentStep = 6
+		m.osInfo.BinDir = m.opts.binDir(m.osInfo.BinDir)
+		m.state = statePlan
+		return m.replan()
+
+	case stepStartedMsg:
+		m.currentStep = stepNumber(msg.name)
+		m.state = stepState(msg.name)
+		m.stepFraction = 0
+		m.stepDetail = ""
+		return m, waitForEvent(m.events)
+
+	case stepProgressMsg:
+		if msg.fraction >= 0 {
+			m.stepFraction = msg.fraction
+		}
+		m.stepDetail = msg.detail
+		return m, waitForEvent(m.events)
+
+	case logLineMsg:
+		m.logLines = append(m.logLines, string(msg))
+		if len(m.logLines) > logPaneLines {
+			m.logLines = m.logLines[len(m.logLines)-logPaneLines:]
+		}
+		return m, waitForEvent(m.events)
+
+	case stepDoneMsg:
+		m.stepFraction = 1
+		m.logLines = append(m.logLines, "✓ "+string(msg))
+		return m, waitForEvent(m.events)
+
+	case installDoneMsg:
+		m.events = nil
+		if m.cancelled {
+			return m, tea.Quit
+		}
+		if msg.code == exitOK {
+			m.installPath = msg.installPath
+			m.pathEdits = msg.pathEdits
+			m.completion = msg.completion
+			m.version = msg.version
+			m.state = stateCompleted
+		}
 		return m, nil
 
 	case errorMsg:
 		m.error = error(msg)
 		m.state = stateError
+		// The installation still reports that it stopped
+		if m.events != nil {
+			return m, waitForEvent(m.events)
+		}
 		return m, nil
-
-	case progressMsg:
-		return m, m.progress.SetPercent(float64(msg))
 	}
 
 	return m, nil
 }
 
+// replan rebuilds the plan shown before installing, after the options changed
+func (m model) replan() (tea.Model, tea.Cmd) {
+	plan, err := buildPlan(m.osInfo, m.opts)
+	if err != nil {
+		m.error = err
+		m.state = stateError
+		return m, nil
+	}
+	m.plan = plan
+	return m, nil
+}
+
+// startInstall leaves the plan screen and runs the installation steps in the
+// background, the detected OS standing in for the detect step
+func (m model) startInstall() (tea.Model, tea.Cmd) {
+	m.message = ""
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.events = make(chan tea.Msg)
+
+	r := newInstallRun(m.opts)
+	r.osInfo = m.osInfo
+	steps := installSteps(m.opts)[1:]
+	go func(events chan<- tea.Msg) {
+		defer cancel()
+		code := runSteps(ctx, steps, r, &teaLogger{events: events, throttle: progressThrottle{interval: 50 * time.Millisecond}})
+		events <- installDoneMsg{code: code, installPath: r.installPath, pathEdits: r.pathEdits, completion: r.completion, version: r.version}
+	}(m.events)
+
+	return m, tea.Batch(m.spinner.Tick, waitForEvent(m.events))
+}
+
+// versionLine tells which version the installation picks, for the welcome screen
+func versionLine(opts options) string {
+	switch {
+	case opts.source != "":
+		return "Version: from " + opts.source
+	case opts.channel == channelNightly:
+		return "Version: nightly, the latest commit of the default branch"
+	case opts.versionPin != "":
+		return "Version: " + opts.versionPin + " (pinned)"
+	}
+	return "Version: " + releaseTag(opts.targetVersion()) + " (stable)"
+}
+
+// stepNumber is the position of a step in the full pipeline, counting from 1
+func stepNumber(name string) int {
+	for i, step := range installSteps(options{}) {
+		if step.name == name {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// stepState is the TUI state while a step runs
+func stepState(name string) installState {
+	switch name {
+	case "deps":
+		return stateInstallingDeps
+	case "clone":
+		return stateCloning
+	case "build":
+		return stateBuilding
+	case "path":
+		return stateSettingPath
+	}
+	return stateInstalling
+}
+
+// savePlanScript writes the plan as a shell script in the current directory
+func savePlanScript(plan installPlan) string {
+	file, err := os.Create("r2d2-install-plan.sh")
+	if err != nil {
+		return fmt.Sprintf("Couldn't save the plan: %v", err)
+	}
+	defer file.Close()
+
+	plan.writeScript(file)
+	return "Plan saved to r2d2-install-plan.sh"
+}
+
+// planView lists what the installation will run and write
+func (m model) planView() string {
+	var s strings.Builder
+	s.WriteString(infoStyle.Render("The installer will run:"))
+	s.WriteString("\n")
+
+	for _, c := range m.plan.commands {
+		line := "• " + c.summary()
+		switch {
+		case c.sudo() || (c.step == "install" && !m.plan.binDirOK):
+			s.WriteString(warningStyle.Render(line + "  [sudo]"))
+		case c.optional != "":
+			s.WriteString(listItemStyle.Render(line + "  [optional]"))
+		default:
+			s.WriteString(listItemStyle.Render(line))
+		}
+		s.WriteString("\n")
+	}
+
+	s.WriteString(infoStyle.Render("Files written:"))
+	s.WriteString("\n")
+	for _, path := range m.plan.writes {
+		s.WriteString(listItemStyle.Render("• " + path))
+		s.WriteString("\n")
+	}
+
+	deno := "install"
+	if m.opts.noDeno {
+		deno = "skip"
+	}
+	s.WriteString(subtleStyle.Render(fmt.Sprintf("Deno: %s (d to toggle)", deno)))
+	s.WriteString("\n")
+	if !pathContains(os.Getenv("PATH"), m.osInfo.BinDir) {
+		setup := "add to shell startup files"
+		if m.opts.noModifyPath {
+			setup = "leave as is"
+		}
+		s.WriteString(subtleStyle.Render(fmt.Sprintf("%s is not on the PATH: %s (p to toggle)", m.osInfo.BinDir, setup)))
+		s.WriteString("\n")
+	}
+	if shell := detectedCompletionShell(); shell != "" {
+		completion := "install"
+		if m.opts.noCompletion {
+			completion = "skip"
+		}
+		s.WriteString(subtleStyle.Render(fmt.Sprintf("Shell completion (%s): %s (c to toggle)", shell, completion)))
+		s.WriteString("\n")
+	}
+	if done := resumableSteps(m.opts); len(done) > 0 {
+		s.WriteString(subtleStyle.Render(fmt.Sprintf("Resuming: %s finished in the previous run", strings.Join(done, ", "))))
+		s.WriteString("\n")
+	}
+	if m.message != "" {
+		s.WriteString(successStyle.Render(m.message))
+		s.WriteString("\n")
+	}
+	s.WriteString(promptStyle.Render("Enter to install • s save as script • q quit"))
+	return s.String()
+}
+
 func (m model) View() string {
 	var s strings.Builder
 
@@ -242,14 +464,26 @@ func (m model) View() string {
 		s.WriteString("\n")
 		s.WriteString(listItemStyle.Render("• Detect your OS and package manager"))
 		s.WriteString("\n")
-		s.WriteString(listItemStyle.Render("• Install dependencies (Git, Go, Deno)"))
-		s.WriteString("\n")
+		if !m.opts.noDeps {
+			s.WriteString(listItemStyle.Render("• Install dependencies (Git, Go, Deno)"))
+			s.WriteString("\n")
+		}
 		s.WriteString(listItemStyle.Render("• Build and install R2D2 CLI"))
 		s.WriteString("\n")
+		s.WriteString(infoStyle.Render(versionLine(m.opts)))
+		s.WriteString("\n")
 		s.WriteString(promptStyle.Render("Press Enter to begin or 'q' to quit"))
 
+	case statePlan:
+		s.WriteString(m.planView())
+
 	case stateError:
-		s.WriteString(errorStyle.Render("❌ Installation failed!"))
+		var verr *verificationError
+		if errors.As(m.error, &verr) {
+			s.WriteString(errorStyle.Render("❌ Verification failed!"))
+		} else {
+			s.WriteString(errorStyle.Render("❌ Installation failed!"))
+		}
 		s.WriteString("\n")
 		s.WriteString(errorTextStyle.Render(fmt.Sprintf("Error: %v", m.error)))
 		s.WriteString("\n")
@@ -260,6 +494,20 @@ func (m model) View() string {
 		s.WriteString("\n")
 		s.WriteString(infoStyle.Render(fmt.Sprintf("Installed to: %s", m.installPath)))
 		s.WriteString("\n")
+		s.WriteString(infoStyle.Render(fmt.Sprintf("Version: %s", m.version)))
+		s.WriteString("\n")
+		if len(m.pathEdits) > 0 || !pathContains(os.Getenv("PATH"), m.osInfo.BinDir) {
+			for _, edit := range m.pathEdits {
+				s.WriteString(listItemStyle.Render("• PATH set in " + edit.File))
+				s.WriteString("\n")
+			}
+			s.WriteString(warningStyle.Render(pathHint(m.osInfo.BinDir)))
+			s.WriteString("\n")
+		}
+		if m.completion != "" {
+			s.WriteString(listItemStyle.Render("• Shell completion in " + m.completion))
+			s.WriteString("\n")
+		}
 		s.WriteString(subtleStyle.Render("Try: "))
 		s.WriteString(codeStyle.Render("r2d2 --help"))
 		s.WriteString("\n")
@@ -267,7 +515,10 @@ func (m model) View() string {
 
 	default:
 		// Progress view for installation steps
-		progressPercent := float64(m.currentStep) / float64(m.totalSteps)
+		progressPercent := 0.0
+		if m.currentStep > 0 {
+			progressPercent = (float64(m.currentStep-1) + m.stepFraction) / float64(m.totalSteps)
+		}
 		s.WriteString(m.progress.ViewAs(progressPercent))
 		s.WriteString("\n")
 
@@ -277,6 +528,10 @@ func (m model) View() string {
 		}
 
 		s.WriteString("\n")
+		if m.stepDetail != "" {
+			s.WriteString(subtleStyle.Render(truncate(m.stepDetail, 44)))
+			s.WriteString("\n")
+		}
 
 		// OS info if detected
 		if m.osInfo.OS != "" {
@@ -284,7 +539,20 @@ func (m model) View() string {
 			s.WriteString("\n")
 		}
 
-		s.WriteString(subtleStyle.Render("Press 'q' to quit"))
+		// Raw output of the running commands
+		for _, line := range m.logLines {
+			s.WriteString(subtleStyle.Render(truncate(line, 44)))
+			s.WriteString("\n")
+		}
+
+		switch {
+		case m.cancelled:
+			s.WriteString(warningStyle.Render("Cancelling..."))
+		case m.events != nil:
+			s.WriteString(subtleStyle.Render("Press 'q' to cancel"))
+		default:
+			s.WriteString(subtleStyle.Render("Press 'q' to quit"))
+		}
 	}
 
 	// Make container responsive
@@ -305,54 +573,67 @@ func (m model) View() string {
 
 // Messages
 type osDetectedMsg OSInfo
-type depsInstalledMsg struct{}
-type repositoryClonedMsg string
-type cliBuiltMsg string
-type binaryInstalledMsg string
 type errorMsg error
-type progressMsg float64
 
-// Commands
-func detectOS() tea.Cmd {
-	return func() tea.Msg {
-		osInfo := OSInfo{}
-
-		switch runtime.GOOS {
-		case "linux":
-			osInfo.OS = "Linux"
-			osInfo.BinDir = "/usr/local/bin"
-
-			// Detect package manager
-			if _, err := exec.LookPath("apt-get"); err == nil {
-				osInfo.PkgManager = "apt-get"
-			} else if _, err := exec.LookPath("yum"); err == nil {
-				osInfo.PkgManager = "yum"
-			} else if _, err := exec.LookPath("pacman"); err == nil {
-				osInfo.PkgManager = "pacman"
-			} else if _, err := exec.LookPath("zypper"); err == nil {
-				osInfo.PkgManager = "zypper"
-			} else {
-				osInfo.PkgManager = "unknown"
-			}
+// Messages from the installation running in the background
+type stepStartedMsg struct{ name string }
+type stepProgressMsg struct {
+	fraction float64 // negative when the total isn't known
+	detail   string
+}
+type logLineMsg string
+type stepDoneMsg string
+type installDoneMsg struct {
+	code        int
+	installPath string
+	pathEdits   []pathEdit
+	completion  string
+	version     string
+}
 
-		case "darwin":
-			osInfo.OS = "macOS"
-			osInfo.PkgManager = "brew"
-			osInfo.BinDir = "/usr/local/bin"
+// teaLogger turns the events of the installation into TUI messages
+type teaLogger struct {
+	events   chan<- tea.Msg
+	throttle progressThrottle
+}
 
-		case "windows":
-			osInfo.OS = "Windows"
-			osInfo.PkgManager = "choco"
-			osInfo.BinDir = filepath.Join(os.Getenv("USERPROFILE"), "bin")
+func (l *teaLogger) start(step installStep) {
+	l.events <- stepStartedMsg{name: step.name}
+}
 
-		default:
-			return errorMsg(fmt.Errorf("unsupported operating system: %s", runtime.GOOS))
-		}
+func (l *teaLogger) progress(step installStep, fraction float64, detail string) {
+	if l.throttle.allow(fraction) {
+		l.events <- stepProgressMsg{fraction: fraction, detail: detail}
+	}
+}
 
-		// Check if we have write permissions to the bin directory
-		if _, err := os.Stat(osInfo.BinDir); os.IsNotExist(err) {
-			homeDir, _ := os.UserHomeDir()
-			osInfo.BinDir = filepath.Join(homeDir, "bin")
+func (l *teaLogger) logLine(step installStep, line string) {
+	l.events <- logLineMsg(line)
+}
+
+func (l *teaLogger) done(step installStep, detail string, elapsed time.Duration) {
+	l.events <- stepDoneMsg(step.message)
+}
+
+func (l *teaLogger) fail(step installStep, err error) {
+	l.events <- errorMsg(err)
+}
+
+func (l *teaLogger) finish(r *installRun) {}
+
+// waitForEvent delivers the next message from the background installation
+func waitForEvent(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-events
+	}
+}
+
+// Commands
+func detectOS() tea.Cmd {
+	return func() tea.Msg {
+		osInfo, err := detectOSInfo()
+		if err != nil {
+			return errorMsg(err)
 		}
 
 		time.Sleep(time.Second) // Simulate detection time
@@ -360,160 +641,155 @@ func detectOS() tea.Cmd {
 	}
 }
 
-func installDependencies(osInfo OSInfo) tea.Cmd {
-	return func() tea.Msg {
-		// Check if dependencies are already installed
-		if commandExists("git") && commandExists("go") && commandExists("deno") {
-			time.Sleep(500 * time.Millisecond)
-			return depsInstalledMsg{}
-		}
+// truncate shortens s to width runes, for lines that would wrap in the TUI
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
 
-		var cmd *exec.Cmd
+// Installation steps, shared by the TUI and the headless mode
+func detectOSInfo() (OSInfo, error) {
+	osInfo := OSInfo{}
 
-		switch osInfo.PkgManager {
-		case "apt-get":
-			cmd = exec.Command("sudo", "apt-get", "update")
-			if err := cmd.Run(); err != nil {
-				return errorMsg(fmt.Errorf("failed to update package list: %v", err))
-			}
+	switch runtime.GOOS {
+	case "linux":
+		osInfo.OS = "Linux"
+		osInfo.BinDir = "/usr/local/bin"
 
-			cmd = exec.Command("sudo", "apt-get", "install", "-y", "git", "golang-go")
-			if err := cmd.Run(); err != nil {
-				return errorMsg(fmt.Errorf("failed to install dependencies: %v", err))
-			}
+		osInfo.PkgManager = detectPackageManager(runtime.GOOS)
 
-		case "brew":
-			if !commandExists("brew") {
-				return errorMsg(fmt.Errorf("homebrew is not installed. Please install it first from https://brew.sh/"))
-			}
+	case "darwin":
+		osInfo.OS = "macOS"
+		osInfo.PkgManager = detectPackageManager(runtime.GOOS)
+		osInfo.BinDir = "/usr/local/bin"
 
-			cmd = exec.Command("brew", "install", "git", "go", "deno")
-			if err := cmd.Run(); err != nil {
-				return errorMsg(fmt.Errorf("failed to install dependencies: %v", err))
-			}
+	case "windows":
+		osInfo.OS = "Windows"
+		osInfo.PkgManager = detectPackageManager(runtime.GOOS)
+		osInfo.BinDir = filepath.Join(os.Getenv("USERPROFILE"), "bin")
 
-		case "yum":
-			cmd = exec.Command("sudo", "yum", "install", "-y", "git", "golang")
-			if err := cmd.Run(); err != nil {
-				return errorMsg(fmt.Errorf("failed to install dependencies: %v", err))
-			}
+	default:
+		return osInfo, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
 
-		case "pacman":
-			cmd = exec.Command("sudo", "pacman", "-S", "--noconfirm", "git", "go")
-			if err := cmd.Run(); err != nil {
-				return errorMsg(fmt.Errorf("failed to install dependencies: %v", err))
-			}
-		}
+	// Without write access to the system directory the binary goes into the user's
+	if osInfo.OS != "Windows" {
+		osInfo.BinDir = chooseBinDir(osInfo.BinDir)
+	}
 
-		// Install Deno if not already installed and not on macOS with brew
-		if strings.ToLower(osInfo.OS) != "windows" {
-			// The command string for the shell.
-			// The -y flag is crucial for making the Deno installer non-interactive.
-			// Note: DENO_INSTALL is NOT in this string; it's set via cmd.Env.
-			denoInstallCmdStr := "curl -fsSL https://deno.land/install.sh | sh -s -- -y"
-
-			// Create the command: run "sh" with the "-c" flag, passing the command string.
-			cmd = exec.Command("sh", "-c", "\""+denoInstallCmdStr+"\"")
-
-			// Set the DENO_INSTALL environment variable for this specific command.
-			cmd.Env = append(os.Environ(), "DENO_INSTALL="+os.ExpandEnv("$HOME/.deno"))
-
-			// Execute the command and capture its combined output (stdout + stderr).
-			err := cmd.Run()
-			if err != nil {
-				// Return a detailed error, including the command's output for debugging.
-				// fmt.Println("\n\n\n\n\n\n\n\n\n\n\n"+err.Error()+"\n"+string(output)+"\n")
-				// return errorMsg(fmt.Errorf("falha ao instalar Deno: %v\nSaída do comando:\n%s", err, output))
-			}
+	return osInfo, nil
+}
+
+// installDeps installs the tools that are missing, leaving the ones already present alone
+func installDeps(ctx context.Context, osInfo OSInfo, tools []string, rep reporter) error {
+	commands := depsCommands(osInfo, missingTools(tools))
+	for i, planned := range commands {
+		rep.progress(float64(i)/float64(len(commands)), planned.summary())
+		if err := runPlanned(ctx, planned, rep, nil); err != nil {
+			return fmt.Errorf("failed to install dependencies (%s): %w", planned.summary(), err)
 		}
+	}
 
-		return depsInstalledMsg{}
+	// Deno is optional, the build can't go on without the others
+	useToolchain()
+	for _, tool := range missingTools(tools) {
+		if tool != "deno" {
+			return fmt.Errorf("%s is missing and %s can't install it, install it yourself and run the installer again", tool, osInfo.PkgManager)
+		}
 	}
+	return nil
 }
 
-func cloneRepository() tea.Cmd {
-	return func() tea.Msg {
-		tmpDir := os.TempDir()
-		repoDir := filepath.Join(tmpDir, "r2d2-cli-install")
+// cloneRepo clones the repository at ref. Unlike release archives nothing is
+// verified here beyond git's HTTPS transport, tags aren't signed.
+func cloneRepo(ctx context.Context, ref string, rep reporter) (string, error) {
+	repoDir := installWorkDir()
 
-		// Remove existing directory if it exists
-		os.RemoveAll(repoDir)
+	// Remove existing directory if it exists
+	os.RemoveAll(repoDir)
 
-		cmd := exec.Command("git", "clone", "https://github.com/ArturC03/r2d2-cli.git", repoDir)
-		if err := cmd.Run(); err != nil {
-			return errorMsg(fmt.Errorf("failed to clone repository: %v", err))
-		}
-
-		return repositoryClonedMsg(repoDir)
+	if err := runPlanned(ctx, cloneCommand(repoDir, ref), rep, gitCloneProgress); err != nil {
+		return "", fmt.Errorf("failed to clone repository: %w", err)
 	}
-}
 
-func buildCLI(repoDir string) tea.Cmd {
-	return func() tea.Msg {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		defer cancel()
+	return repoDir, nil
+}
 
-		cmd := exec.CommandContext(ctx, "go", "mod", "tidy")
-		cmd.Dir = repoDir
+func buildBinary(ctx context.Context, repoDir string, rep reporter) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
 
-		if err := cmd.Run(); err != nil {
-			// Get the full command string for the error message
-			executedCommand := cmd.String()
-			// return errorMsg(fmt.Errorf("failed to tidy Go modules. Command executed: '%s', Error: %v", executedCommand, err))
-			for {
-				fmt.Printf("failed to tidy Go modules. Command executed: '%s', Error: %v\n", executedCommand, err)
-				fmt.Print(cmd.Dir)
-			}
+	binaryPath := filepath.Join(repoDir, binaryName())
+	commands := buildCommands(repoDir, binaryPath)
+	for i, planned := range commands {
+		var parse progressParser
+		if i == len(commands)-1 {
+			parse = goBuildProgress(countPackages(ctx, repoDir))
 		}
-
-		binaryPath := filepath.Join(repoDir, "r2d2")
-		if runtime.GOOS == "windows" {
-			binaryPath += ".exe"
+		if err := runPlanned(ctx, planned, rep, parse); err != nil {
+			return "", fmt.Errorf("failed to build R2D2 CLI (%s): %w", planned, err)
 		}
+	}
 
-		cmd = exec.CommandContext(ctx, "go", "build", "-o", binaryPath, ".")
-		cmd.Dir = repoDir
-		if err := cmd.Run(); err != nil {
-			return errorMsg(fmt.Errorf("failed to build R2D2 CLI: %v", err))
-		}
+	return binaryPath, nil
+}
 
-		return cliBuiltMsg(binaryPath)
+// countPackages counts the non-standard packages go build -v will list
+func countPackages(ctx context.Context, repoDir string) int {
+	cmd := exec.CommandContext(ctx, "go", "list", "-deps", "-f", "{{if not .Standard}}{{.ImportPath}}{{end}}", ".")
+	cmd.Dir = repoDir
+	output, err := cmd.Output()
+	if err != nil {
+		return 0
 	}
+	return len(strings.Fields(string(output)))
 }
 
-func installBinary(binaryPath string, osInfo OSInfo) tea.Cmd {
-	return func() tea.Msg {
-		// Ensure bin directory exists
-		if err := os.MkdirAll(osInfo.BinDir, 0755); err != nil {
-			return errorMsg(fmt.Errorf("failed to create bin directory: %v", err))
-		}
-
-		binaryName := "r2d2"
-		if runtime.GOOS == "windows" {
-			binaryName += ".exe"
-		}
+// installBinaryTo copies the binary into binDir, through a temporary file renamed
+// over the target so a running r2d2 is never left half written
+func installBinaryTo(binaryPath string, binDir string) (string, error) {
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return "", installPermissionError(binDir, fmt.Errorf("failed to create bin directory: %w", err))
+	}
 
-		targetPath := filepath.Join(osInfo.BinDir, binaryName)
+	content, err := os.ReadFile(binaryPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read binary: %v", err)
+	}
 
-		// Copy binary to target location
-		cmd := exec.Command("cp", binaryPath, targetPath)
-		if runtime.GOOS == "windows" {
-			cmd = exec.Command("copy", binaryPath, targetPath)
-		}
+	targetPath := filepath.Join(binDir, binaryName())
+	tmp, err := os.CreateTemp(binDir, ".r2d2-install-")
+	if err != nil {
+		return "", installPermissionError(binDir, fmt.Errorf("failed to install binary: %w", err))
+	}
+	defer os.Remove(tmp.Name())
 
-		if err := cmd.Run(); err != nil {
-			return errorMsg(fmt.Errorf("failed to install binary: %v", err))
-		}
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0755)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), targetPath)
+	}
+	if err != nil {
+		return "", installPermissionError(binDir, fmt.Errorf("failed to install binary: %w", err))
+	}
 
-		// Make binary executable on Unix systems
-		if runtime.GOOS != "windows" {
-			if err := os.Chmod(targetPath, 0755); err != nil {
-				return errorMsg(fmt.Errorf("failed to make binary executable: %v", err))
-			}
-		}
+	return targetPath, nil
+}
 
-		return binaryInstalledMsg(targetPath)
+// installPermissionError points to --user when binDir isn't writable
+func installPermissionError(binDir string, err error) error {
+	if errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("%v\n%s needs root, run the installer with sudo or use --user", err, binDir)
 	}
+	return err
 }
 
 // Helper functions
@@ -522,38 +798,75 @@ func commandExists(cmd string) bool {
 	return err == nil
 }
 
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func main() {
-	// Check for version flag
-	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-v") {
+	opts, err := parseOptions(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		printUsage(os.Stderr)
+		os.Exit(exitUsage)
+	}
+
+	if opts.version {
 		fmt.Printf("R2D2 CLI Installer v%s\n", version)
 		return
 	}
 
-	// Check for help flag
-	if len(os.Args) > 1 && (os.Args[1] == "--help" || os.Args[1] == "-h") {
-		fmt.Printf("R2D2 CLI Installer v%s\n", version)
-		fmt.Println("A beautiful TUI installer for the R2D2 CLI tool")
-		fmt.Println("")
-		fmt.Println("Usage:")
-		fmt.Println("  r2d2-installer [options]")
-		fmt.Println("")
-		fmt.Println("Options:")
-		fmt.Println("  -v, --version    Show version")
-		fmt.Println("  -h, --help       Show help")
-		fmt.Println("")
-		fmt.Println("Controls:")
-		fmt.Println("  Enter            Begin installation")
-		fmt.Println("  q, Ctrl+C        Quit installer")
+	// Go and Deno downloaded by an earlier run count as installed
+	useToolchain()
+
+	if opts.help {
+		printUsage(os.Stdout)
 		return
 	}
 
+	// Ctrl+C stops the running command and leaves the state to resume from
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch opts.mode {
+	case modeUninstall:
+		os.Exit(runUninstall(opts, os.Stdout, os.Stderr))
+	case modeRepair:
+		os.Exit(runRepair(ctx, opts, os.Stdout, os.Stderr))
+	}
+
+	if opts.dryRun {
+		os.Exit(runDryRun(opts, os.Stdout, os.Stderr))
+	}
+
+	// Scripts, containers and CI get plain output instead of the TUI
+	if opts.headless() {
+		os.Exit(runHeadless(ctx, opts, os.Stdout, os.Stderr))
+	}
+	// Bubble Tea reads Ctrl+C as a key, the model cancels the installation itself
+	stop()
+
 	p := tea.NewProgram(
-		initialModel(),
+		initialModel(opts),
 		tea.WithAltScreen(),
 	)
 
-	if _, err := p.Run(); err != nil {
+	final, err := p.Run()
+	if err != nil {
 		fmt.Printf("Error running installer: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitFailure)
+	}
+	if m, ok := final.(model); ok {
+		switch {
+		case m.cancelled:
+			fmt.Println(errCancelled)
+			os.Exit(exitCancelled)
+		case m.state == stateError:
+			os.Exit(exitFailure)
+		}
 	}
 }
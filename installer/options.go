@@ -0,0 +1,295 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// Output modes of the headless installer
+const (
+	outputPlain = "plain"
+	outputQuiet = "quiet"
+	outputJSON  = "json"
+)
+
+// Modes of the installer, given as the first argument
+const (
+	modeInstall   = "install"
+	modeUninstall = "uninstall" // remove what the install receipt lists
+	modeRepair    = "repair"    // check the installation and fix it
+)
+
+// Release channels
+const (
+	channelStable  = "stable"  // a tagged release, the installer's own version unless pinned
+	channelNightly = "nightly" // the tip of the default branch
+)
+
+// Release tags, with or without the leading v
+var versionPattern = regexp.MustCompile(`^v?\d+(\.\d+)*(-[0-9A-Za-z.]+)?$`)
+
+// options holds the command line flags of the installer
+type options struct {
+	mode         string // install, uninstall or repair
+	yes          bool   // run without asking, implies headless mode
+	prefix       string // install into <prefix>/bin instead of the detected directory
+	noDeps       bool   // skip installing git, go and deno
+	user         bool   // install into $XDG_BIN_HOME or ~/.local/bin, without sudo
+	noModifyPath bool   // leave the shell startup files alone
+	noCompletion bool   // skip installing the shell completion script
+	source       string // local checkout, .tar.gz or binary to install from instead of GitHub
+	noDeno       bool   // leave out the optional Deno installation
+	versionPin   string // release tag to install instead of the installer's version
+	channel      string // stable or nightly
+	dryRun       bool   // print the plan as a shell script without installing
+	noResume     bool   // start over instead of skipping the steps an interrupted run finished
+	output       string // plain, quiet or json, only used headless
+	help         bool
+	version      bool
+}
+
+// parseOptions reads the installer flags from args (without the program name)
+func parseOptions(args []string) (options, error) {
+	opts := options{mode: modeInstall, output: outputPlain}
+	var quiet, jsonOutput bool
+
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case modeInstall, modeUninstall, modeRepair:
+			opts.mode = args[0]
+			args = args[1:]
+		default:
+			return opts, fmt.Errorf("unknown command: %s", args[0])
+		}
+	}
+
+	fs := flag.NewFlagSet("r2d2-installer", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&opts.yes, "yes", false, "")
+	fs.BoolVar(&opts.yes, "y", false, "")
+	fs.StringVar(&opts.prefix, "prefix", "", "")
+	fs.BoolVar(&opts.noDeps, "no-deps", false, "")
+	fs.BoolVar(&opts.user, "user", false, "")
+	fs.BoolVar(&opts.noModifyPath, "no-modify-path", false, "")
+	fs.BoolVar(&opts.noCompletion, "no-completion", false, "")
+	fs.StringVar(&opts.source, "source", "", "")
+	fs.BoolVar(&opts.noDeno, "no-deno", false, "")
+	fs.StringVar(&opts.versionPin, "version-pin", "", "")
+	fs.StringVar(&opts.channel, "channel", channelStable, "")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "")
+	fs.BoolVar(&opts.noResume, "no-resume", false, "")
+	fs.BoolVar(&quiet, "quiet", false, "")
+	fs.BoolVar(&quiet, "q", false, "")
+	fs.BoolVar(&jsonOutput, "json", false, "")
+	fs.BoolVar(&opts.help, "help", false, "")
+	fs.BoolVar(&opts.help, "h", false, "")
+	fs.BoolVar(&opts.version, "version", false, "")
+	fs.BoolVar(&opts.version, "v", false, "")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+
+	switch {
+	case quiet && jsonOutput:
+		return opts, fmt.Errorf("--quiet and --json can't be used together")
+	case quiet:
+		opts.output = outputQuiet
+	case jsonOutput:
+		opts.output = outputJSON
+	}
+
+	if opts.user && opts.prefix != "" {
+		return opts, fmt.Errorf("--user and --prefix can't be used together")
+	}
+
+	if opts.source != "" {
+		if _, err := sourceKind(opts.source); err != nil {
+			return opts, err
+		}
+	}
+
+	if opts.channel != channelStable && opts.channel != channelNightly {
+		return opts, fmt.Errorf("unknown channel: %s, use stable or nightly", opts.channel)
+	}
+	if opts.versionPin != "" {
+		switch {
+		case !versionPattern.MatchString(opts.versionPin):
+			return opts, fmt.Errorf("invalid version: %s, use a release tag like v0.2.4", opts.versionPin)
+		case opts.channel == channelNightly:
+			return opts, fmt.Errorf("--version-pin and --channel nightly can't be used together")
+		case opts.source != "":
+			return opts, fmt.Errorf("--version-pin and --source can't be used together")
+		}
+		opts.versionPin = releaseTag(opts.versionPin)
+	}
+
+	if opts.prefix != "" {
+		prefix, err := filepath.Abs(opts.prefix)
+		if err != nil {
+			return opts, err
+		}
+		opts.prefix = prefix
+	}
+
+	return opts, nil
+}
+
+// binDir returns where the binary goes: <prefix>/bin when a prefix was given,
+// the per-user directory with --user, or the detected directory
+func (o options) binDir(detected string) string {
+	switch {
+	case o.prefix != "":
+		return filepath.Join(o.prefix, "bin")
+	case o.user:
+		return userBinDir()
+	}
+	return detected
+}
+
+// targetVersion is the version the installation should end up with: the pinned one,
+// or the installer's own on the stable channel. It's empty for nightly and --source.
+func (o options) targetVersion() string {
+	switch {
+	case o.source != "" || o.channel != channelStable:
+		return ""
+	case o.versionPin != "":
+		return strings.TrimPrefix(o.versionPin, "v")
+	}
+	return version
+}
+
+// installSource is where the CLI comes from: --source, the release archive of a
+// pinned version, or the repository otherwise. Releases before 0.2.4 only archived
+// the installer, so the stable channel clones its tag rather than downloading.
+func (o options) installSource() string {
+	if o.source != "" {
+		return o.source
+	}
+	archive := releaseArchiveName(runtime.GOOS, runtime.GOARCH)
+	if o.versionPin != "" && o.targetVersion() != "" && archive != "" {
+		return releaseURL + "/download/" + releaseTag(o.targetVersion()) + "/" + archive
+	}
+	return ""
+}
+
+// cloneRef is the tag to check out when cloning, empty for the default branch
+func (o options) cloneRef() string {
+	if v := o.targetVersion(); v != "" && o.installSource() == "" {
+		return releaseTag(v)
+	}
+	return ""
+}
+
+// releaseTag turns a version into its release tag
+func releaseTag(version string) string {
+	return "v" + strings.TrimPrefix(version, "v")
+}
+
+// skipped returns the optional components turned off
+func (o options) skipped() []string {
+	if o.noDeno {
+		return []string{"deno"}
+	}
+	return nil
+}
+
+// tools returns the tools the installation needs, without the skipped ones
+func (o options) tools() []string {
+	var tools []string
+	for _, tool := range requiredTools(o.installSource()) {
+		if !contains(o.skipped(), tool) {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// headless tells whether to run without the TUI: with --yes, or when stdout isn't a terminal
+func (o options) headless() bool {
+	return o.yes || !isTerminal(os.Stdout)
+}
+
+// isTerminal checks if f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// printUsage writes the installer help
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "R2D2 CLI Installer v%s\n", version)
+	fmt.Fprintln(w, "A beautiful TUI installer for the R2D2 CLI tool")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  r2d2-installer [options]")
+	fmt.Fprintln(w, "  r2d2-installer --version-pin v0.2.4")
+	fmt.Fprintln(w, "  r2d2-installer uninstall [--dry-run] [--quiet]")
+	fmt.Fprintln(w, "  r2d2-installer repair [--source <path>] [--quiet] [--json]")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Commands:")
+	fmt.Fprintln(w, "  install             Install the R2D2 CLI (default)")
+	fmt.Fprintln(w, "  uninstall           Remove what the install receipt lists: the binary,")
+	fmt.Fprintln(w, "                      PATH setup, shell completion and temporary directories")
+	fmt.Fprintln(w, "  repair              Check the binary and PATH setup, rebuilding when needed")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "The receipt is written to ~/.r2d2/install-receipt.json after installing.")
+	fmt.Fprintln(w, "'r2d2 self uninstall' and 'r2d2 self repair' do the same from the CLI.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Options:")
+	fmt.Fprintln(w, "  -y, --yes           Install without asking (no TUI)")
+	fmt.Fprintln(w, "  --prefix <dir>      Install the binary into <dir>/bin")
+	fmt.Fprintln(w, "  --user              Install into $XDG_BIN_HOME or ~/.local/bin, without sudo")
+	fmt.Fprintln(w, "  --no-modify-path    Don't add the install directory to the PATH in the")
+	fmt.Fprintln(w, "                      bash, zsh and fish startup files")
+	fmt.Fprintln(w, "  --no-completion     Don't install the completion script for your shell")
+	fmt.Fprintln(w, "  --no-deps           Don't install git, go and deno")
+	fmt.Fprintln(w, "  --source <path>     Install from a local checkout, a .tar.gz (source or")
+	fmt.Fprintln(w, "                      prebuilt) or a binary instead of cloning from GitHub.")
+	fmt.Fprintln(w, "                      A .tar.gz URL is checked against the checksums.txt")
+	fmt.Fprintln(w, "                      next to it, and its signature in release builds")
+	fmt.Fprintln(w, "  --channel <name>    stable clones the tag of a release, the installer's own")
+	fmt.Fprintln(w, "                      version unless pinned (default). nightly builds the")
+	fmt.Fprintln(w, "                      latest commit of the default branch")
+	fmt.Fprintln(w, "  --version-pin <tag> Install the given release from its release archive,")
+	fmt.Fprintln(w, "                      like v0.2.4")
+	fmt.Fprintln(w, "  --no-deno           Don't install Deno (needed by 'r2d2 run' only)")
+	fmt.Fprintln(w, "  --dry-run           Print every command the installation would run, as a")
+	fmt.Fprintln(w, "                      shell script, without changing anything")
+	fmt.Fprintln(w, "  --no-resume         Start over instead of skipping the steps an interrupted")
+	fmt.Fprintln(w, "                      installation finished (kept in ~/.r2d2/install-state.json)")
+	fmt.Fprintln(w, "  -q, --quiet         Only print errors (headless mode)")
+	fmt.Fprintln(w, "  --json              Print progress as JSON lines (headless mode)")
+	fmt.Fprintln(w, "  -v, --version       Show version")
+	fmt.Fprintln(w, "  -h, --help          Show help")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "The TUI is only used when stdout is a terminal and --yes isn't given.")
+	fmt.Fprintln(w, "/usr/local/bin is used when it's writable, ~/.local/bin otherwise.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Controls:")
+	fmt.Fprintln(w, "  Enter            Begin installation")
+	fmt.Fprintln(w, "  q, Ctrl+C        Quit installer, or cancel the running step")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Exit codes:")
+	fmt.Fprintf(w, "  %d  success\n", exitOK)
+	fmt.Fprintf(w, "  %d  invalid arguments\n", exitUsage)
+	fmt.Fprintf(w, "  %d  unsupported system\n", exitDetect)
+	fmt.Fprintf(w, "  %d  dependencies couldn't be installed\n", exitDeps)
+	fmt.Fprintf(w, "  %d  source couldn't be fetched\n", exitFetch)
+	fmt.Fprintf(w, "  %d  build failed\n", exitBuild)
+	fmt.Fprintf(w, "  %d  binary couldn't be installed, or doesn't report the expected version\n", exitInstall)
+	fmt.Fprintf(w, "  %d  a download failed its checksum or signature check\n", exitVerify)
+	fmt.Fprintf(w, "  %d  cancelled with Ctrl+C, run again to resume\n", exitCancelled)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    options
+		wantErr bool
+	}{
+		{"defaults", nil, options{mode: modeInstall, output: outputPlain, channel: channelStable}, false},
+		{"yes", []string{"--yes"}, options{mode: modeInstall, yes: true, output: outputPlain, channel: channelStable}, false},
+		{"short yes", []string{"-y", "--no-deps"}, options{mode: modeInstall, yes: true, noDeps: true, output: outputPlain, channel: channelStable}, false},
+		{"prefix", []string{"--prefix", "/opt/r2d2"}, options{mode: modeInstall, prefix: "/opt/r2d2", output: outputPlain, channel: channelStable}, false},
+		{"prefix with equals", []string{"--prefix=/opt/r2d2"}, options{mode: modeInstall, prefix: "/opt/r2d2", output: outputPlain, channel: channelStable}, false},
+		{"quiet", []string{"--yes", "--quiet"}, options{mode: modeInstall, yes: true, output: outputQuiet, channel: channelStable}, false},
+		{"json", []string{"--json"}, options{mode: modeInstall, output: outputJSON, channel: channelStable}, false},
+		{"version", []string{"-v"}, options{mode: modeInstall, version: true, output: outputPlain, channel: channelStable}, false},
+		{"help", []string{"--help"}, options{mode: modeInstall, help: true, output: outputPlain, channel: channelStable}, false},
+		{"user", []string{"--user", "--no-modify-path"}, options{mode: modeInstall, user: true, noModifyPath: true, output: outputPlain, channel: channelStable}, false},
+		{"no completion", []string{"--yes", "--no-completion"}, options{mode: modeInstall, yes: true, noCompletion: true, output: outputPlain, channel: channelStable}, false},
+		{"user and prefix", []string{"--user", "--prefix", "/opt"}, options{}, true},
+		{"quiet and json", []string{"--quiet", "--json"}, options{}, true},
+		{"unknown flag", []string{"--nope"}, options{}, true},
+		{"install", []string{"install", "--yes"}, options{mode: modeInstall, yes: true, output: outputPlain, channel: channelStable}, false},
+		{"uninstall", []string{"uninstall", "--dry-run"}, options{mode: modeUninstall, dryRun: true, output: outputPlain, channel: channelStable}, false},
+		{"repair", []string{"repair", "--quiet"}, options{mode: modeRepair, output: outputQuiet, channel: channelStable}, false},
+		{"unknown command", []string{"upgrade"}, options{}, true},
+		{"extra argument", []string{"repair", "now"}, options{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOptions(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOptionsRelativePrefix(t *testing.T) {
+	opts, err := parseOptions([]string{"--prefix", "local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filepath.IsAbs(opts.prefix) {
+		t.Errorf("prefix = %q, want an absolute path", opts.prefix)
+	}
+}
+
+func TestOptionsBinDir(t *testing.T) {
+	if got := (options{}).binDir("/usr/local/bin"); got != "/usr/local/bin" {
+		t.Errorf("binDir() = %q, want the detected directory", got)
+	}
+	if got := (options{mode: modeInstall, prefix: "/opt/r2d2"}).binDir("/usr/local/bin"); got != filepath.Join("/opt/r2d2", "bin") {
+		t.Errorf("binDir() = %q, want <prefix>/bin", got)
+	}
+	t.Setenv("XDG_BIN_HOME", "/home/r2d2/bin")
+	if got := (options{user: true}).binDir("/usr/local/bin"); got != "/home/r2d2/bin" {
+		t.Errorf("binDir() = %q, want the user directory", got)
+	}
+}
+
+func TestOptionsHeadless(t *testing.T) {
+	// go test doesn't attach stdout to a terminal
+	if !(options{}).headless() {
+		t.Error("headless() = false without a terminal")
+	}
+	if !(options{mode: modeInstall, yes: true}).headless() {
+		t.Error("headless() = false with --yes")
+	}
+}
+
+func TestParseOptionsVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string // versionPin
+		wantErr bool
+	}{
+		{"pin", []string{"--version-pin", "v0.2.4"}, "v0.2.4", false},
+		{"pin without v", []string{"--version-pin", "0.2.4"}, "v0.2.4", false},
+		{"prerelease", []string{"--version-pin", "v0.3.0-rc.1"}, "v0.3.0-rc.1", false},
+		{"invalid pin", []string{"--version-pin", "latest"}, "", true},
+		{"pin on nightly", []string{"--version-pin", "v0.2.4", "--channel", "nightly"}, "", true},
+		{"pin with source", []string{"--version-pin", "v0.2.4", "--source", "."}, "", true},
+		{"unknown channel", []string{"--channel", "beta"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOptions(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.versionPin != tt.want {
+				t.Errorf("versionPin = %q, want %q", got.versionPin, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionsInstallSource(t *testing.T) {
+	archive := releaseArchiveName(runtime.GOOS, runtime.GOARCH)
+	if archive == "" {
+		t.Skip("no release archive for this platform")
+	}
+
+	tests := []struct {
+		name       string
+		opts       options
+		wantSource string
+		wantRef    string
+	}{
+		{"stable", options{channel: channelStable}, "", "v" + version},
+		{"pinned", options{channel: channelStable, versionPin: "v0.2.4"}, releaseURL + "/download/v0.2.4/" + archive, ""},
+		{"nightly", options{channel: channelNightly}, "", ""},
+		{"source", options{channel: channelStable, source: "/tmp/r2d2"}, "/tmp/r2d2", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.installSource(); got != tt.wantSource {
+				t.Errorf("installSource() = %q, want %q", got, tt.wantSource)
+			}
+			if got := tt.opts.cloneRef(); got != tt.wantRef {
+				t.Errorf("cloneRef() = %q, want %q", got, tt.wantRef)
+			}
+		})
+	}
+}
+
+func TestReleaseArchiveName(t *testing.T) {
+	if got := releaseArchiveName("linux", "arm64"); got != "r2d2-cli_linux_arm64.tar.gz" {
+		t.Errorf("releaseArchiveName(linux, arm64) = %q", got)
+	}
+	// Platforms goreleaser doesn't build for are cloned at the tag instead
+	if got := releaseArchiveName("windows", "amd64"); got != "" {
+		t.Errorf("releaseArchiveName(windows, amd64) = %q, want none", got)
+	}
+	if got := cloneCommand("/tmp/r2d2", "v0.2.4").String(); !strings.Contains(got, "--branch v0.2.4") {
+		t.Errorf("cloneCommand() at a tag = %s", got)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// packageManager describes how a package manager installs the tools
+type packageManager struct {
+	name     string            // executable, also shown as the detected package manager
+	goos     string            // system it's looked for on
+	update   []string          // refreshes the package index first, nil when not needed
+	install  []string          // installs the packages given after these arguments
+	root     bool              // whether it has to run as root
+	packages map[string]string // package names of the tools
+}
+
+// Package managers in the order they're looked for
+var packageManagers = []packageManager{
+	{"apt-get", "linux", []string{"apt-get", "update"}, []string{"apt-get", "install", "-y"}, true,
+		map[string]string{"git": "git", "go": "golang-go"}},
+	{"dnf", "linux", nil, []string{"dnf", "install", "-y"}, true,
+		map[string]string{"git": "git", "go": "golang"}},
+	{"yum", "linux", nil, []string{"yum", "install", "-y"}, true,
+		map[string]string{"git": "git", "go": "golang"}},
+	{"pacman", "linux", nil, []string{"pacman", "-S", "--noconfirm"}, true,
+		map[string]string{"git": "git", "go": "go"}},
+	{"zypper", "linux", nil, []string{"zypper", "--non-interactive", "install"}, true,
+		map[string]string{"git": "git", "go": "go"}},
+	// Alpine is built on musl, so Deno has to come from its packages
+	{"apk", "linux", nil, []string{"apk", "add", "--no-cache"}, true,
+		map[string]string{"git": "git", "go": "go", "deno": "deno"}},
+	{"nix-env", "linux", nil, []string{"nix-env", "-iA"}, false,
+		map[string]string{"git": "nixpkgs.git", "go": "nixpkgs.go", "deno": "nixpkgs.deno"}},
+	{"brew", "darwin", nil, []string{"brew", "install"}, false,
+		map[string]string{"git": "git", "go": "go", "deno": "deno"}},
+	{"choco", "windows", nil, []string{"choco", "install", "-y"}, false,
+		map[string]string{"git": "git", "go": "golang", "deno": "deno"}},
+}
+
+// detectPackageManager returns the first package manager of the system on the PATH,
+// or unknown
+func detectPackageManager(goos string) string {
+	for _, pm := range packageManagers {
+		if pm.goos == goos && commandExists(pm.name) {
+			return pm.name
+		}
+	}
+	return "unknown"
+}
+
+// findPackageManager returns the package manager called name
+func findPackageManager(name string) (packageManager, bool) {
+	for _, pm := range packageManagers {
+		if pm.name == name {
+			return pm, true
+		}
+	}
+	return packageManager{}, false
+}
+
+// currentUID is the effective user id, replaced in tests
+var currentUID = os.Geteuid
+
+// rootArgs returns the prefix running a command as root: nothing for root itself,
+// sudo for everyone else. ok is false when sudo isn't there.
+func rootArgs() (args []string, ok bool) {
+	if runtime.GOOS == "windows" || currentUID() == 0 {
+		return nil, true
+	}
+	if _, err := exec.LookPath("sudo"); err == nil {
+		return []string{"sudo"}, true
+	}
+	return nil, false
+}
+
+// usable tells whether the package manager can run, and with which prefix
+func (pm packageManager) usable() ([]string, bool) {
+	if !pm.root {
+		return nil, true
+	}
+	return rootArgs()
+}
+
+// commands returns the commands installing the packages of the missing tools,
+// and the tools it has no package for
+func (pm packageManager) commands(prefix []string, missing []string) ([]plannedCommand, []string) {
+	var packages, left []string
+	for _, tool := range missing {
+		if pkg, ok := pm.packages[tool]; ok {
+			packages = append(packages, pkg)
+		} else {
+			left = append(left, tool)
+		}
+	}
+	if len(packages) == 0 {
+		return nil, left
+	}
+
+	var commands []plannedCommand
+	if pm.update != nil {
+		commands = append(commands, plannedCommand{step: "deps", args: append(append([]string{}, prefix...), pm.update...)})
+	}
+	args := append(append(append([]string{}, prefix...), pm.install...), packages...)
+	return append(commands, plannedCommand{step: "deps", args: args}), left
+}
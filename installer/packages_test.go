@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeExecutables makes a PATH holding only empty scripts with the given names
+func fakeExecutables(t *testing.T, names ...string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake executables are shell scripts")
+	}
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+}
+
+// runAsUser pretends the installer runs with the given user id
+func runAsUser(t *testing.T, uid int) {
+	t.Helper()
+	saved := currentUID
+	currentUID = func() int { return uid }
+	t.Cleanup(func() { currentUID = saved })
+}
+
+func TestDetectPackageManager(t *testing.T) {
+	tests := []struct {
+		name        string
+		goos        string
+		executables []string
+		want        string
+	}{
+		{"apt-get", "linux", []string{"apt-get"}, "apt-get"},
+		{"dnf before yum", "linux", []string{"yum", "dnf"}, "dnf"},
+		{"zypper", "linux", []string{"zypper"}, "zypper"},
+		{"apk", "linux", []string{"apk"}, "apk"},
+		{"nix-env", "linux", []string{"nix-env"}, "nix-env"},
+		{"brew", "darwin", []string{"brew"}, "brew"},
+		{"brew on linux", "linux", []string{"brew"}, "unknown"},
+		{"choco", "windows", []string{"choco"}, "choco"},
+		{"none", "linux", nil, "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeExecutables(t, tt.executables...)
+			if got := detectPackageManager(tt.goos); got != tt.want {
+				t.Errorf("detectPackageManager(%s) = %s, want %s", tt.goos, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRootArgs(t *testing.T) {
+	fakeExecutables(t)
+	runAsUser(t, 0)
+	if args, ok := rootArgs(); !ok || args != nil {
+		t.Errorf("rootArgs() as root = %v, %v, want no prefix", args, ok)
+	}
+
+	runAsUser(t, 1000)
+	if _, ok := rootArgs(); ok {
+		t.Error("rootArgs() without sudo should fail")
+	}
+
+	fakeExecutables(t, "sudo")
+	if args, ok := rootArgs(); !ok || strings.Join(args, " ") != "sudo" {
+		t.Errorf("rootArgs() with sudo = %v, %v", args, ok)
+	}
+}
+
+func TestDepsCommandsToolchainFallback(t *testing.T) {
+	if goTarget(runtime.GOOS, runtime.GOARCH) == "" || denoTarget(runtime.GOOS, runtime.GOARCH) == "" {
+		t.Skip("no Go or Deno release for this platform")
+	}
+	t.Setenv("HOME", t.TempDir())
+
+	tests := []struct {
+		name   string
+		osInfo OSInfo
+	}{
+		{"no package manager", OSInfo{OS: "Linux", PkgManager: "unknown"}},
+		{"no sudo", OSInfo{OS: "Linux", PkgManager: "apt-get"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeExecutables(t, "apt-get")
+			runAsUser(t, 1000)
+
+			commands := depsCommands(tt.osInfo, []string{"git", "go", "deno"})
+			if len(commands) != 2 {
+				t.Fatalf("depsCommands() = %v, want the Go and Deno downloads", commands)
+			}
+			for _, c := range commands {
+				if c.sudo() || !usesToolchain([]plannedCommand{c}) {
+					t.Errorf("%s doesn't download into the toolchain", c.summary())
+				}
+			}
+			if !strings.Contains(commands[0].summary(), "Go "+goToolchainVersion) {
+				t.Errorf("first download = %s, want Go", commands[0].summary())
+			}
+		})
+	}
+}
+
+func TestUseToolchain(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PATH", "/usr/bin")
+	goBin := filepath.Join(toolchainDir(), "go", "bin")
+	os.MkdirAll(goBin, 0755)
+
+	useToolchain()
+	useToolchain()
+	if got, want := os.Getenv("PATH"), goBin+string(os.PathListSeparator)+"/usr/bin"; got != want {
+		t.Errorf("PATH = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Comment ending every line the installer adds to a shell startup file,
+// so the line is found again instead of being added twice
+const pathMarker = "# added by r2d2-installer"
+
+// userBinDir is where --user installs: $XDG_BIN_HOME, or ~/.local/bin
+func userBinDir() string {
+	if dir := os.Getenv("XDG_BIN_HOME"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "bin")
+}
+
+// chooseBinDir returns the system directory when it can be written without sudo,
+// and the per-user directory otherwise
+func chooseBinDir(system string) string {
+	if !dirWritable(system) {
+		return userBinDir()
+	}
+	return system
+}
+
+// rcFile is the startup file of a shell
+type rcFile struct {
+	shell string
+	path  string
+}
+
+// shellRCFiles lists the startup files of the shells in use: the login shell from
+// $SHELL and any other shell whose startup file already exists
+func shellRCFiles(home string, loginShell string) []rcFile {
+	zdotdir := os.Getenv("ZDOTDIR")
+	if zdotdir == "" {
+		zdotdir = home
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+
+	candidates := []rcFile{
+		{"bash", filepath.Join(home, ".bashrc")},
+		{"zsh", filepath.Join(zdotdir, ".zshrc")},
+		{"fish", filepath.Join(configHome, "fish", "config.fish")},
+	}
+	// macOS Terminal opens login shells, which read .bash_profile rather than .bashrc
+	if runtime.GOOS == "darwin" {
+		candidates[0].path = filepath.Join(home, ".bash_profile")
+	}
+
+	var files []rcFile
+	for _, rc := range candidates {
+		_, err := os.Stat(rc.path)
+		if err == nil || filepath.Base(loginShell) == rc.shell {
+			files = append(files, rc)
+		}
+	}
+	return files
+}
+
+// pathLine is the line adding dir to the PATH in a shell's startup file
+func pathLine(shell string, dir string) string {
+	if shell == "fish" {
+		return "fish_add_path " + shellQuote(dir) + " " + pathMarker
+	}
+	return "export PATH=\"" + dir + ":$PATH\" " + pathMarker
+}
+
+// pathEdits returns the edits putting dir on the PATH for the shells in use
+func pathEdits(dir string) []pathEdit {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	var edits []pathEdit
+	for _, rc := range shellRCFiles(home, os.Getenv("SHELL")) {
+		edits = append(edits, pathEdit{File: rc.path, Line: pathLine(rc.shell, dir)})
+	}
+	return edits
+}
+
+// needsPathSetup tells whether the startup files should be edited for dir
+func needsPathSetup(opts options, dir string) bool {
+	return !opts.noModifyPath && runtime.GOOS != "windows" && !pathContains(os.Getenv("PATH"), dir)
+}
+
+// setupPath adds the PATH line to every startup file that doesn't have it yet.
+// It returns all the edits, including the ones already in place.
+func setupPath(dir string) ([]pathEdit, error) {
+	edits := pathEdits(dir)
+	for _, edit := range edits {
+		if hasPathEdit(edit) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(edit.File), 0755); err != nil {
+			return nil, err
+		}
+		if err := appendPathEdit(edit); err != nil {
+			return nil, err
+		}
+	}
+	return edits, nil
+}
+
+// setupPathFor puts dir on the PATH unless it's there already or --no-modify-path
+// was given, and records the edits in the install receipt
+func setupPathFor(opts options, dir string) ([]pathEdit, error) {
+	if !needsPathSetup(opts, dir) {
+		return nil, nil
+	}
+	edits, err := setupPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	return edits, recordPathEdits(edits)
+}
+
+// pathEditCommand is the shell equivalent of setupPath for one file, shown in the plan
+func pathEditCommand(edit pathEdit) plannedCommand {
+	file := shellQuote(edit.File)
+	return plannedCommand{
+		step: "path",
+		args: []string{"sh", "-c", "grep -qxF " + shellQuote(edit.Line) + " " + file + " 2>/dev/null || printf '\\n%s\\n' " + shellQuote(edit.Line) + " >> " + file},
+	}
+}
+
+// recordPathEdits adds the edits to the install receipt, without duplicates
+func recordPathEdits(edits []pathEdit) error {
+	receipt, err := loadReceipt()
+	if err != nil {
+		return err
+	}
+	for _, edit := range edits {
+		if !containsPathEdit(receipt.PathEdits, edit) {
+			receipt.PathEdits = append(receipt.PathEdits, edit)
+		}
+	}
+	return saveReceipt(receipt)
+}
+
+// containsPathEdit checks if edits holds edit
+func containsPathEdit(edits []pathEdit, edit pathEdit) bool {
+	for _, e := range edits {
+		if e == edit {
+			return true
+		}
+	}
+	return false
+}
+
+// pathHint tells how to use r2d2 from the current terminal, whose PATH is unchanged
+func pathHint(dir string) string {
+	return "Open a new terminal to use r2d2, or run: export PATH=\"" + dir + ":$PATH\""
+}
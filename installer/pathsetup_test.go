@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Directory nobody can create files in, root included
+const unwritableDir = "/sys"
+
+func TestUserBinDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	t.Setenv("XDG_BIN_HOME", "")
+	if got, want := userBinDir(), filepath.Join(home, ".local", "bin"); got != want {
+		t.Errorf("userBinDir() = %s, want %s", got, want)
+	}
+
+	t.Setenv("XDG_BIN_HOME", "/opt/bin")
+	if got := userBinDir(); got != "/opt/bin" {
+		t.Errorf("userBinDir() with XDG_BIN_HOME = %s", got)
+	}
+}
+
+func TestChooseBinDir(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("uses /sys")
+	}
+	t.Setenv("XDG_BIN_HOME", "/home/r2d2/bin")
+
+	writable := t.TempDir()
+	if got := chooseBinDir(writable); got != writable {
+		t.Errorf("chooseBinDir() of a writable dir = %s", got)
+	}
+	if got := chooseBinDir(filepath.Join(unwritableDir, "r2d2", "bin")); got != "/home/r2d2/bin" {
+		t.Errorf("chooseBinDir() of an unwritable dir = %s, want the user dir", got)
+	}
+}
+
+func TestInstallBinaryToUnwritable(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("uses /sys")
+	}
+	binary := filepath.Join(t.TempDir(), "r2d2")
+	os.WriteFile(binary, []byte("bin"), 0755)
+
+	_, err := installBinaryTo(binary, unwritableDir)
+	if err == nil || !strings.Contains(err.Error(), "--user") {
+		t.Errorf("installBinaryTo() of an unwritable dir = %v, want a hint about --user", err)
+	}
+}
+
+func TestShellRCFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("ZDOTDIR", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	names := func(files []rcFile) string {
+		var shells []string
+		for _, rc := range files {
+			shells = append(shells, rc.shell)
+		}
+		return strings.Join(shells, ",")
+	}
+
+	if got := names(shellRCFiles(home, "/usr/bin/zsh")); got != "zsh" {
+		t.Errorf("shellRCFiles() for zsh = %s, want zsh", got)
+	}
+
+	for _, name := range []string{".bashrc", ".config/fish/config.fish"} {
+		os.MkdirAll(filepath.Dir(filepath.Join(home, name)), 0755)
+		os.WriteFile(filepath.Join(home, name), nil, 0644)
+	}
+	files := shellRCFiles(home, "/bin/zsh")
+	if runtime.GOOS != "darwin" && names(files) != "bash,zsh,fish" {
+		t.Errorf("shellRCFiles() = %s, want bash,zsh,fish", names(files))
+	}
+}
+
+func TestPathLine(t *testing.T) {
+	if got := pathLine("bash", "/home/me/.local/bin"); got != `export PATH="/home/me/.local/bin:$PATH" `+pathMarker {
+		t.Errorf("pathLine(bash) = %s", got)
+	}
+	if got := pathLine("fish", "/home/me/my bin"); got != `fish_add_path '/home/me/my bin' `+pathMarker {
+		t.Errorf("pathLine(fish) = %s", got)
+	}
+}
+
+func TestSetupPathIdempotent(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SHELL", "/bin/bash")
+	t.Setenv("ZDOTDIR", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	bashrc := filepath.Join(home, ".bashrc")
+	if runtime.GOOS == "darwin" {
+		bashrc = filepath.Join(home, ".bash_profile")
+	}
+	os.WriteFile(bashrc, []byte("set -o vi\n"), 0644)
+	saveReceipt(installReceipt{Binary: "/home/me/.local/bin/r2d2"})
+
+	dir := "/home/me/.local/bin"
+	for i := 0; i < 2; i++ {
+		edits, err := setupPathFor(options{}, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(edits) != 1 || edits[0].File != bashrc {
+			t.Fatalf("setupPathFor() = %+v", edits)
+		}
+	}
+
+	content, _ := os.ReadFile(bashrc)
+	if got := strings.Count(string(content), pathMarker); got != 1 {
+		t.Errorf("PATH line added %d times:\n%s", got, content)
+	}
+	receipt, _ := loadReceipt()
+	if len(receipt.PathEdits) != 1 {
+		t.Errorf("receipt has %d PATH edits, want 1", len(receipt.PathEdits))
+	}
+
+	// Uninstalling takes the line out again
+	var out strings.Builder
+	runUninstall(options{}, &out, &out)
+	if content, _ := os.ReadFile(bashrc); strings.Contains(string(content), pathMarker) {
+		t.Errorf("PATH line left after uninstall:\n%s", content)
+	}
+}
+
+func TestSetupPathSkipped(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SHELL", "/bin/bash")
+
+	if edits, _ := setupPathFor(options{noModifyPath: true}, "/home/me/.local/bin"); edits != nil {
+		t.Errorf("setupPathFor() with --no-modify-path = %+v", edits)
+	}
+
+	t.Setenv("PATH", "/usr/bin:/home/me/.local/bin")
+	if edits, _ := setupPathFor(options{}, "/home/me/.local/bin/"); edits != nil {
+		t.Errorf("setupPathFor() of a dir on the PATH = %+v", edits)
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Repository cloned when no --source is given
+const repositoryURL = "https://github.com/ArturC03/r2d2-cli.git"
+
+// Where the CLI releases are published, assets under <releaseURL>/download/<tag>/
+var releaseURL = "https://github.com/ArturC03/r2d2-cli/releases"
+
+// Where the Deno release zips and their .sha256sum files are downloaded from
+var denoReleaseURL = "https://github.com/denoland/deno/releases/latest/download"
+
+// plannedCommand is a command the installer will run
+type plannedCommand struct {
+	step     string   // pipeline step running it
+	args     []string // program and arguments, sudo included
+	env      []string // extra environment variables
+	dir      string   // working directory, empty for the current one
+	optional string   // optional component it belongs to, like deno
+	label    string   // short description shown instead of long scripts
+}
+
+// sudo tells whether the command runs with elevated privileges
+func (c plannedCommand) sudo() bool {
+	return len(c.args) > 0 && c.args[0] == "sudo"
+}
+
+// command builds the exec.Cmd for the planned command
+func (c plannedCommand) command(ctx context.Context) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, c.args[0], c.args[1:]...)
+	cmd.Dir = c.dir
+	if len(c.env) > 0 {
+		cmd.Env = append(os.Environ(), c.env...)
+	}
+	return cmd
+}
+
+// String renders the command as a shell line
+func (c plannedCommand) String() string {
+	var parts []string
+	if c.dir != "" {
+		parts = append(parts, "cd "+shellQuote(c.dir)+" &&")
+	}
+	for _, env := range c.env {
+		name, value, _ := strings.Cut(env, "=")
+		parts = append(parts, name+"="+shellQuote(value))
+	}
+	for _, arg := range c.args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// summary is the label of the command, or the command itself
+func (c plannedCommand) summary() string {
+	if c.label != "" {
+		return c.label
+	}
+	return c.String()
+}
+
+// shellQuote quotes a word for sh when it holds special characters
+func shellQuote(word string) string {
+	if word != "" && !strings.ContainsAny(word, " \t\n'\"\\$`|&;<>()*?[]{}!#~") {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'"'"'`) + "'"
+}
+
+// installWorkDir is where the source is cloned, copied or extracted
+func installWorkDir() string {
+	return filepath.Join(os.TempDir(), "r2d2-cli-install")
+}
+
+// depsCommands returns the commands installing the missing tools: packages when the
+// package manager can run, and release downloads into the toolchain for the rest
+func depsCommands(osInfo OSInfo, missing []string) []plannedCommand {
+	var commands []plannedCommand
+	left := missing
+	if pm, ok := findPackageManager(osInfo.PkgManager); ok {
+		if prefix, ok := pm.usable(); ok {
+			commands, left = pm.commands(prefix, missing)
+		}
+	}
+
+	return append(commands, toolchainCommands(left)...)
+}
+
+// denoTarget is the name Deno releases use for a platform, empty when there's none
+func denoTarget(goos string, goarch string) string {
+	arch := map[string]string{"amd64": "x86_64", "arm64": "aarch64"}[goarch]
+	if arch == "" {
+		return ""
+	}
+	switch goos {
+	case "linux":
+		return arch + "-unknown-linux-gnu"
+	case "darwin":
+		return arch + "-apple-darwin"
+	}
+	return ""
+}
+
+// denoInstallScript downloads a Deno release zip, compares it with the .sha256sum
+// published next to it and unpacks it into $DENO_INSTALL/bin
+func denoInstallScript(zip string) string {
+	lines := []string{
+		`set -e`,
+		`command -v unzip >/dev/null || { echo "unzip is needed to install Deno" >&2; exit 1; }`,
+		`tmp=$(mktemp -d)`,
+		`trap 'rm -rf "$tmp"' EXIT`,
+	}
+	lines = append(lines, verifiedDownloadScript(zip, zip+".sha256sum", "$tmp/deno.zip")...)
+	return strings.Join(append(lines,
+		`mkdir -p "$DENO_INSTALL/bin"`,
+		`unzip -o -q "$tmp/deno.zip" -d "$DENO_INSTALL/bin"`,
+		`chmod +x "$DENO_INSTALL/bin/deno"`,
+	), "\n")
+}
+
+// releaseArchiveName is the release archive goreleaser builds for a platform,
+// empty when there's none
+func releaseArchiveName(goos string, goarch string) string {
+	if (goos != "linux" && goos != "darwin") || (goarch != "amd64" && goarch != "arm64") {
+		return ""
+	}
+	return "r2d2-cli_" + goos + "_" + goarch + ".tar.gz"
+}
+
+// cloneCommand returns the git clone of the repository into dir, at the tag ref
+// or the tip of the default branch when ref is empty
+func cloneCommand(dir string, ref string) plannedCommand {
+	args := []string{"git", "clone", "--progress"}
+	if ref != "" {
+		args = append(args, "--depth", "1", "--branch", ref)
+	}
+	return plannedCommand{step: "clone", args: append(args, repositoryURL, dir)}
+}
+
+// downloadCommands return the shell equivalent of fetchVerified: the archive and
+// checksums.txt are downloaded, the signature and checksum checked, then the archive extracted
+func downloadCommands(archiveURL string, workDir string) []plannedCommand {
+	base := archiveURL[:strings.LastIndex(archiveURL, "/")]
+	name := path.Base(archiveURL)
+
+	commands := []plannedCommand{
+		{step: "clone", args: []string{"mkdir", "-p", workDir}},
+		{step: "clone", args: []string{"curl", "-fsSLo", name, archiveURL}, dir: workDir},
+		{step: "clone", args: []string{"curl", "-fsSLo", checksumsFile, base + "/" + checksumsFile}, dir: workDir},
+	}
+	if releasePublicKey != "" {
+		commands = append(commands,
+			plannedCommand{step: "clone", args: []string{"curl", "-fsSLo", signatureFile, base + "/" + signatureFile}, dir: workDir},
+			plannedCommand{step: "clone", args: []string{"minisign", "-Vm", checksumsFile, "-P", releasePublicKey}, dir: workDir})
+	}
+	return append(commands,
+		plannedCommand{step: "clone", args: []string{"sh", "-c", "grep ' " + name + "$' " + checksumsFile + " | sha256sum -c -"}, dir: workDir},
+		plannedCommand{step: "clone", args: []string{"tar", "-xzf", name}, dir: workDir})
+}
+
+// buildCommands returns the commands building the CLI in repoDir
+func buildCommands(repoDir string, binaryPath string) []plannedCommand {
+	return []plannedCommand{
+		{step: "build", args: []string{"go", "mod", "tidy"}, dir: repoDir},
+		{step: "build", args: []string{"go", "build", "-v", "-o", binaryPath, "."}, dir: repoDir},
+	}
+}
+
+// installPlan lists everything an installation will do
+type installPlan struct {
+	osInfo   OSInfo
+	tools    []string // tools required, without the optional ones turned off
+	missing  []string
+	commands []plannedCommand
+	writes   []string // files and directories written
+	skipped  []string // optional components turned off
+	target   string   // installed binary
+	binDirOK bool     // whether the binary directory is writable without sudo
+	version  string   // release installed, empty for nightly and --source
+}
+
+// buildPlan works out the installation for the detected system and the options
+func buildPlan(osInfo OSInfo, opts options) (installPlan, error) {
+	plan := installPlan{osInfo: osInfo, tools: opts.tools(), skipped: opts.skipped()}
+	if v := opts.targetVersion(); v != "" {
+		plan.version = releaseTag(v)
+	}
+
+	if !opts.noDeps {
+		plan.missing = missingTools(plan.tools)
+		plan.commands = append(plan.commands, depsCommands(osInfo, plan.missing)...)
+		if usesToolchain(plan.commands) {
+			plan.writes = append(plan.writes, toolchainDir())
+		}
+	}
+
+	source := opts.installSource()
+	kind, err := sourceKind(source)
+	if err != nil {
+		return plan, err
+	}
+
+	workDir := installWorkDir()
+	repoDir, binaryPath := workDir, ""
+	if kind != sourceBinary {
+		// Leftovers of an earlier run are removed first
+		plan.commands = append(plan.commands, plannedCommand{step: "clone", args: []string{"rm", "-rf", workDir}})
+	}
+	switch kind {
+	case sourceRemote:
+		plan.commands = append(plan.commands, cloneCommand(workDir, opts.cloneRef()))
+		plan.writes = append(plan.writes, workDir)
+	case sourceDir:
+		plan.commands = append(plan.commands, plannedCommand{step: "clone", args: []string{"cp", "-R", source, workDir}})
+		plan.writes = append(plan.writes, workDir)
+	case sourceArchive:
+		root, prebuilt, err := archiveRoot(source)
+		if err != nil {
+			return plan, err
+		}
+		plan.commands = append(plan.commands,
+			plannedCommand{step: "clone", args: []string{"mkdir", "-p", workDir}},
+			plannedCommand{step: "clone", args: []string{"tar", "-xzf", source, "-C", workDir}})
+		plan.writes = append(plan.writes, workDir)
+		repoDir = filepath.Join(workDir, root)
+		if prebuilt {
+			binaryPath = filepath.Join(repoDir, binaryName())
+		}
+	case sourceBinary:
+		binaryPath = source
+	case sourceURL:
+		plan.commands = append(plan.commands, downloadCommands(source, workDir)...)
+		plan.writes = append(plan.writes, workDir)
+		if isReleaseArchive(source) {
+			binaryPath = filepath.Join(workDir, binaryName())
+		}
+	}
+
+	if binaryPath == "" {
+		binaryPath = filepath.Join(repoDir, binaryName())
+		plan.commands = append(plan.commands, buildCommands(repoDir, binaryPath)...)
+	}
+
+	binDir := opts.binDir(osInfo.BinDir)
+	plan.target = filepath.Join(binDir, binaryName())
+	plan.binDirOK = dirWritable(binDir)
+	plan.commands = append(plan.commands,
+		plannedCommand{step: "install", args: []string{"mkdir", "-p", binDir}},
+		plannedCommand{step: "install", args: []string{"cp", binaryPath, plan.target}},
+		plannedCommand{step: "install", args: []string{"chmod", "755", plan.target}})
+	plan.writes = append(plan.writes, plan.target)
+
+	for _, dir := range pathDirs(binDir, plan.commands) {
+		if !needsPathSetup(opts, dir) {
+			continue
+		}
+		for _, edit := range pathEdits(dir) {
+			plan.commands = append(plan.commands, pathEditCommand(edit))
+			plan.writes = append(plan.writes, edit.File)
+		}
+	}
+
+	if shell := detectedCompletionShell(); !opts.noCompletion && shell != "" {
+		home, _ := os.UserHomeDir()
+		file, edit := completionTarget(shell, home)
+		plan.commands = append(plan.commands, completionCommands(plan.target, shell, file, edit)...)
+		plan.writes = append(plan.writes, file)
+		if edit != nil {
+			plan.writes = append(plan.writes, edit.File)
+		}
+	}
+
+	return plan, nil
+}
+
+// dirWritable checks if files can be created in dir, or in its closest existing parent
+func dirWritable(dir string) bool {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+
+	file, err := os.CreateTemp(dir, ".r2d2-write-")
+	if err != nil {
+		return false
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return true
+}
+
+// needsSudo tells whether any part of the plan needs elevated privileges
+func (p installPlan) needsSudo() bool {
+	for _, c := range p.commands {
+		if c.sudo() {
+			return true
+		}
+	}
+	return !p.binDirOK
+}
+
+// writeScript prints the plan as a shell script that does the same installation
+func (p installPlan) writeScript(w io.Writer) {
+	fmt.Fprintln(w, "#!/bin/sh")
+	fmt.Fprintf(w, "# R2D2 CLI installation plan, generated by r2d2-installer v%s\n", version)
+	fmt.Fprintf(w, "# OS: %s (%s)\n", p.osInfo.OS, p.osInfo.PkgManager)
+	if p.version != "" {
+		fmt.Fprintf(w, "# Version: %s\n", p.version)
+	}
+	if len(p.missing) > 0 {
+		fmt.Fprintf(w, "# Missing tools: %s\n", strings.Join(p.missing, ", "))
+	}
+	for _, skipped := range p.skipped {
+		fmt.Fprintf(w, "# Skipped: %s\n", skipped)
+	}
+	for _, path := range p.writes {
+		fmt.Fprintf(w, "# Writes: %s\n", path)
+	}
+	fmt.Fprintln(w, "set -e")
+
+	step := ""
+	for _, c := range p.commands {
+		if c.step != step {
+			step = c.step
+			fmt.Fprintf(w, "\n# %s\n", step)
+		}
+
+		line := c.String()
+		if c.step == "install" && !p.binDirOK {
+			line = "sudo " + line
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// runDryRun detects the system and prints the plan without changing anything
+func runDryRun(opts options, out io.Writer, errOut io.Writer) int {
+	osInfo, err := detectOSInfo()
+	if err != nil {
+		fmt.Fprintf(errOut, "error: %v\n", err)
+		return exitDetect
+	}
+
+	plan, err := buildPlan(osInfo, opts)
+	if err != nil {
+		fmt.Fprintf(errOut, "error: %v\n", err)
+		return exitUsage
+	}
+
+	plan.writeScript(out)
+	return exitOK
+}
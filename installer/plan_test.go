@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"git", "git"},
+		{"/usr/local/bin", "/usr/local/bin"},
+		{"", "''"},
+		{"my dir", "'my dir'"},
+		{"curl | sh", "'curl | sh'"},
+		{"it's", `'it'"'"'s'`},
+	}
+
+	for _, tt := range tests {
+		if got := shellQuote(tt.word); got != tt.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestDepsCommands(t *testing.T) {
+	fakeExecutables(t, "sudo")
+	runAsUser(t, 1000)
+
+	tests := []struct {
+		name    string
+		osInfo  OSInfo
+		missing []string
+		want    []string
+	}{
+		{"apt", OSInfo{OS: "Linux", PkgManager: "apt-get"}, []string{"git", "go"},
+			[]string{"sudo apt-get update", "sudo apt-get install -y git golang-go"}},
+		{"pacman", OSInfo{OS: "Linux", PkgManager: "pacman"}, []string{"go"},
+			[]string{"sudo pacman -S --noconfirm go"}},
+		{"dnf", OSInfo{OS: "Linux", PkgManager: "dnf"}, []string{"git", "go"},
+			[]string{"sudo dnf install -y git golang"}},
+		{"zypper", OSInfo{OS: "Linux", PkgManager: "zypper"}, []string{"go"},
+			[]string{"sudo zypper --non-interactive install go"}},
+		{"apk has deno", OSInfo{OS: "Linux", PkgManager: "apk"}, []string{"go", "deno"},
+			[]string{"sudo apk add --no-cache go deno"}},
+		{"nix-env without sudo", OSInfo{OS: "Linux", PkgManager: "nix-env"}, []string{"git"},
+			[]string{"nix-env -iA nixpkgs.git"}},
+		{"brew has deno", OSInfo{OS: "macOS", PkgManager: "brew"}, []string{"deno"},
+			[]string{"brew install deno"}},
+		{"deno script", OSInfo{OS: "Linux", PkgManager: "yum"}, []string{"deno"},
+			[]string{"sh -c " + denoInstallScript(denoReleaseURL+"/deno-"+denoTarget(runtime.GOOS, runtime.GOARCH)+".zip")}},
+		{"nothing missing", OSInfo{OS: "Linux", PkgManager: "apt-get"}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range depsCommands(tt.osInfo, tt.missing) {
+				got = append(got, strings.Join(c.args, " "))
+				if c.optional == "deno" && !strings.HasPrefix(c.String(), "DENO_INSTALL=") {
+					t.Errorf("deno install doesn't set DENO_INSTALL: %s", c)
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("depsCommands() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionsToolsNoDeno(t *testing.T) {
+	opts := options{noDeno: true}
+	for _, tool := range opts.tools() {
+		if tool == "deno" {
+			t.Errorf("tools() = %v, deno should be skipped", opts.tools())
+		}
+	}
+	if got := opts.skipped(); len(got) != 1 || got[0] != "deno" {
+		t.Errorf("skipped() = %v, want [deno]", got)
+	}
+}
+
+func TestBuildPlanBinary(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "r2d2")
+	os.WriteFile(binary, []byte("bin"), 0755)
+	prefix := filepath.Join(dir, "prefix")
+
+	opts := options{source: binary, prefix: prefix, noDeps: true, noModifyPath: true, noCompletion: true}
+	plan, err := buildPlan(OSInfo{OS: "Linux", PkgManager: "apt-get"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range plan.commands {
+		if c.step != "install" {
+			t.Errorf("prebuilt binary plan runs %s step: %s", c.step, c)
+		}
+	}
+	if want := filepath.Join(prefix, "bin", binaryName()); plan.target != want {
+		t.Errorf("target = %s, want %s", plan.target, want)
+	}
+	if !plan.binDirOK || plan.needsSudo() {
+		t.Errorf("plan into a temp prefix shouldn't need sudo")
+	}
+}
+
+func TestBuildPlanArchive(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "src.tar.gz")
+	writeArchive(t, archive, map[string]string{"r2d2-cli/go.mod": "module x\n", "r2d2-cli/main.go": "package main\n"})
+
+	opts := options{source: archive, prefix: dir, noDeps: true, noModifyPath: true, noCompletion: true}
+	plan, err := buildPlan(OSInfo{OS: "Linux"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var steps []string
+	for _, c := range plan.commands {
+		steps = append(steps, c.step)
+	}
+	got := strings.Join(steps, ",")
+	if want := "clone,clone,clone,build,build,install,install,install"; got != want {
+		t.Errorf("steps = %s, want %s", got, want)
+	}
+
+	build := plan.commands[3]
+	if want := filepath.Join(installWorkDir(), "r2d2-cli"); build.dir != want {
+		t.Errorf("build runs in %s, want %s", build.dir, want)
+	}
+}
+
+func TestBuildPlanURL(t *testing.T) {
+	url := "https://example.com/download/v0.3.0/r2d2-cli_linux_amd64.tar.gz"
+	plan, err := buildPlan(OSInfo{OS: "Linux"}, options{source: url, prefix: t.TempDir(), noDeps: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	plan.writeScript(&out)
+	for _, want := range []string{
+		"curl -fsSLo checksums.txt https://example.com/download/v0.3.0/checksums.txt",
+		"checksums.txt | sha256sum -c -",
+		"tar -xzf r2d2-cli_linux_amd64.tar.gz",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("script is missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "go build") {
+		t.Errorf("release archive shouldn't be built:\n%s", out.String())
+	}
+}
+
+func TestWriteScript(t *testing.T) {
+	plan := installPlan{
+		osInfo:  OSInfo{OS: "Linux", PkgManager: "apt-get"},
+		missing: []string{"go"},
+		skipped: []string{"deno"},
+		writes:  []string{"/usr/local/bin/r2d2"},
+		commands: []plannedCommand{
+			{step: "deps", args: []string{"sudo", "apt-get", "install", "-y", "golang-go"}},
+			{step: "build", args: []string{"go", "build", "-o", "/tmp/r2d2", "."}, dir: "/tmp/my src"},
+			{step: "install", args: []string{"cp", "/tmp/r2d2", "/usr/local/bin/r2d2"}},
+		},
+	}
+
+	var out bytes.Buffer
+	plan.writeScript(&out)
+	script := out.String()
+
+	for _, want := range []string{
+		"#!/bin/sh\n",
+		"# Missing tools: go\n",
+		"# Skipped: deno\n",
+		"# Writes: /usr/local/bin/r2d2\n",
+		"set -e\n",
+		"\n# deps\nsudo apt-get install -y golang-go\n",
+		"cd '/tmp/my src' && go build -o /tmp/r2d2 .\n",
+		"sudo cp /tmp/r2d2 /usr/local/bin/r2d2\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script is missing %q:\n%s", want, script)
+		}
+	}
+
+	plan.binDirOK = true
+	out.Reset()
+	plan.writeScript(&out)
+	if strings.Contains(out.String(), "sudo cp") {
+		t.Errorf("writable bin dir shouldn't use sudo:\n%s", out.String())
+	}
+}
+
+func TestRunDryRunChangesNothing(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "r2d2")
+	os.WriteFile(binary, []byte("bin"), 0755)
+	prefix := filepath.Join(dir, "prefix")
+
+	var out, errOut bytes.Buffer
+	code := runDryRun(options{source: binary, prefix: prefix, noDeps: true}, &out, &errOut)
+	if code != exitOK {
+		t.Fatalf("runDryRun() = %d, stderr: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "cp "+binary) {
+		t.Errorf("plan doesn't copy the binary:\n%s", out.String())
+	}
+	if _, err := os.Stat(prefix); !os.IsNotExist(err) {
+		t.Errorf("dry run created %s", prefix)
+	}
+}
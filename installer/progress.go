@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// reporter receives the raw output and the progress of the running step
+type reporter interface {
+	logLine(line string)
+	progress(fraction float64, detail string)
+}
+
+// nopReporter drops everything, for callers that don't show progress
+type nopReporter struct{}
+
+func (nopReporter) logLine(string)           {}
+func (nopReporter) progress(float64, string) {}
+
+// lineWriter calls onLine for every line written to it. Carriage returns end lines
+// too, since git and curl redraw their progress with them.
+type lineWriter struct {
+	mu     sync.Mutex
+	buf    []byte
+	onLine func(line string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			return len(p), nil
+		}
+		if line := strings.TrimSpace(string(w.buf[:i])); line != "" {
+			w.onLine(line)
+		}
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// flush passes on the last line when it isn't terminated
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if line := strings.TrimSpace(string(w.buf)); line != "" {
+		w.onLine(line)
+	}
+	w.buf = nil
+}
+
+// progressParser turns a line of output into progress, ok is false for other lines
+type progressParser func(line string) (fraction float64, detail string, ok bool)
+
+// Lines kept to explain a failed command
+const errorTailLines = 10
+
+// runPlanned runs a planned command, streaming its output to rep line by line.
+// The error of a failed command ends with its last lines of output.
+func runPlanned(ctx context.Context, planned plannedCommand, rep reporter, parse progressParser) error {
+	var tail []string
+	w := &lineWriter{onLine: func(line string) {
+		rep.logLine(line)
+		if parse != nil {
+			if fraction, detail, ok := parse(line); ok {
+				rep.progress(fraction, detail)
+			}
+		}
+		tail = append(tail, line)
+		if len(tail) > errorTailLines {
+			tail = tail[1:]
+		}
+	}}
+
+	cmd := planned.command(ctx)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	err := cmd.Run()
+	w.flush()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil && len(tail) > 0 {
+		return fmt.Errorf("%v\n%s", err, strings.Join(tail, "\n"))
+	}
+	return err
+}
+
+var gitProgressPattern = regexp.MustCompile(`^(Receiving objects|Resolving deltas):\s+(\d+)%`)
+
+// gitCloneProgress reads git clone --progress: receiving objects is most of the
+// work, resolving deltas the rest
+func gitCloneProgress(line string) (float64, string, bool) {
+	match := gitProgressPattern.FindStringSubmatch(line)
+	if match == nil {
+		return 0, "", false
+	}
+	percent, _ := strconv.Atoi(match[2])
+	if match[1] == "Receiving objects" {
+		return 0.8 * float64(percent) / 100, line, true
+	}
+	return 0.8 + 0.2*float64(percent)/100, line, true
+}
+
+// goBuildProgress counts the packages go build -v prints as it compiles them
+func goBuildProgress(total int) progressParser {
+	built := 0
+	return func(line string) (float64, string, bool) {
+		// Errors and warnings hold spaces or colons, package paths don't
+		if strings.ContainsAny(line, " :") {
+			return 0, "", false
+		}
+		built++
+		fraction := 1.0
+		if total > 0 && built < total {
+			fraction = float64(built) / float64(total)
+		}
+		return fraction, fmt.Sprintf("compiled %d/%d packages", built, total), true
+	}
+}
+
+// countingReader reports how many bytes of a download were read
+type countingReader struct {
+	r     io.Reader
+	read  int64
+	total int64 // -1 when the server didn't say
+	name  string
+	rep   reporter
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	if n > 0 {
+		if c.total > 0 {
+			c.rep.progress(float64(c.read)/float64(c.total), fmt.Sprintf("%s: %s of %s", c.name, formatBytes(c.read), formatBytes(c.total)))
+		} else {
+			c.rep.progress(-1, fmt.Sprintf("%s: %s", c.name, formatBytes(c.read)))
+		}
+	}
+	return n, err
+}
+
+// formatBytes renders a size like 1.5 MB
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
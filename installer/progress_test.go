@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingReporter keeps everything a step reports
+type recordingReporter struct {
+	mu        sync.Mutex
+	lines     []string
+	fractions []float64
+}
+
+func (r *recordingReporter) logLine(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, line)
+}
+
+func (r *recordingReporter) progress(fraction float64, detail string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fractions = append(r.fractions, fraction)
+}
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := &lineWriter{onLine: func(line string) { lines = append(lines, line) }}
+
+	fmt.Fprint(w, "Cloning into 'r2d2'...\nReceiving objects:  10%\rReceiving obj")
+	fmt.Fprint(w, "ects: 100%\r\n\nlast line")
+	w.flush()
+
+	want := []string{"Cloning into 'r2d2'...", "Receiving objects:  10%", "Receiving objects: 100%", "last line"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
+
+func TestGitCloneProgress(t *testing.T) {
+	tests := []struct {
+		line string
+		want float64
+		ok   bool
+	}{
+		{"Receiving objects:  50% (50/100), 1.2 MiB | 2.4 MiB/s", 0.4, true},
+		{"Receiving objects: 100% (100/100), done.", 0.8, true},
+		{"Resolving deltas:  50% (10/20)", 0.9, true},
+		{"Cloning into '/tmp/r2d2-cli-install'...", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, _, ok := gitCloneProgress(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("gitCloneProgress(%q) = %v, %v, want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGoBuildProgress(t *testing.T) {
+	parse := goBuildProgress(4)
+	for i, want := range []float64{0.25, 0.5} {
+		got, detail, ok := parse(fmt.Sprintf("github.com/example/pkg%d", i))
+		if !ok || got != want {
+			t.Errorf("package %d = %v, %v, want %v", i, got, ok, want)
+		}
+		if detail != fmt.Sprintf("compiled %d/4 packages", i+1) {
+			t.Errorf("detail = %q", detail)
+		}
+	}
+	if _, _, ok := parse("./main.go:3:2: undefined: foo"); ok {
+		t.Error("goBuildProgress() counted an error line")
+	}
+}
+
+func TestCountingReader(t *testing.T) {
+	rep := &recordingReporter{}
+	r := &countingReader{r: strings.NewReader(strings.Repeat("x", 2048)), total: 2048, name: "r2d2.tar.gz", rep: rep}
+	buf := make([]byte, 1024)
+	r.Read(buf)
+	r.Read(buf)
+
+	if len(rep.fractions) != 2 || rep.fractions[0] != 0.5 || rep.fractions[1] != 1 {
+		t.Errorf("progress = %v, want [0.5 1]", rep.fractions)
+	}
+}
+
+func TestRunPlanned(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	rep := &recordingReporter{}
+	err := runPlanned(context.Background(), plannedCommand{args: []string{"sh", "-c", "echo one; echo two >&2; exit 3"}}, rep, nil)
+	if err == nil || !strings.Contains(err.Error(), "one\ntwo") {
+		t.Errorf("runPlanned() = %v, want the output in the error", err)
+	}
+	if len(rep.lines) != 2 {
+		t.Errorf("logged %q, want both lines", rep.lines)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	started := time.Now()
+	err = runPlanned(ctx, plannedCommand{args: []string{"sleep", "10"}}, rep, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("runPlanned() cancelled = %v", err)
+	}
+	if time.Since(started) > 5*time.Second {
+		t.Error("cancelling didn't stop the command")
+	}
+}
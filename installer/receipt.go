@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Install receipt, shared with 'r2d2 self' in the CLI
+const receiptFile = "install-receipt.json"
+
+// pathEdit is a line the installer added to a shell startup file
+type pathEdit struct {
+	File string `json:"file"`
+	Line string `json:"line"`
+}
+
+// installReceipt records what an installation put on the system
+type installReceipt struct {
+	InstallerVersion string     `json:"installerVersion"`
+	Version          string     `json:"version"` // reported by 'r2d2 version'
+	Binary           string     `json:"binary"`
+	Source           string     `json:"source,omitempty"` // --source used, empty for GitHub
+	PathEdits        []pathEdit `json:"pathEdits,omitempty"`
+	TempDirs         []string   `json:"tempDirs,omitempty"`
+	Toolchain        string     `json:"toolchain,omitempty"`      // Go and Deno downloaded when no package manager could install them
+	Completion       string     `json:"completion,omitempty"`     // shell completion script
+	CompletionEdit   *pathEdit  `json:"completionEdit,omitempty"` // startup file line loading the completion script
+	InstalledAt      time.Time  `json:"installedAt"`
+}
+
+// receiptPath is where the receipt lives, ~/.r2d2/install-receipt.json
+func receiptPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".r2d2", receiptFile), nil
+}
+
+// loadReceipt reads the receipt of the last installation
+func loadReceipt() (installReceipt, error) {
+	var receipt installReceipt
+	path, err := receiptPath()
+	if err != nil {
+		return receipt, err
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return receipt, fmt.Errorf("no install receipt at %s, was r2d2 installed with this installer?", path)
+	}
+	if err != nil {
+		return receipt, err
+	}
+	if err := json.Unmarshal(content, &receipt); err != nil {
+		return receipt, fmt.Errorf("invalid install receipt %s: %v", path, err)
+	}
+	return receipt, nil
+}
+
+// saveReceipt writes the receipt, replacing the previous one
+func saveReceipt(receipt installReceipt) error {
+	path, err := receiptPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(receipt, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0644)
+}
+
+// newReceipt describes an installation of the binary at installPath, built from
+// binaryPath, and the toolchain directory it created, empty when it didn't create one.
+// Only what this installation made is recorded, 'self uninstall' removes all of it.
+func newReceipt(opts options, installPath string, binaryPath string, toolchain string) installReceipt {
+	receipt := installReceipt{
+		InstallerVersion: version,
+		Version:          binaryVersion(installPath),
+		Binary:           installPath,
+		Source:           opts.installSource(),
+		Toolchain:        toolchain,
+		InstalledAt:      time.Now().UTC(),
+	}
+	if workDir := installWorkDir(); strings.HasPrefix(binaryPath, workDir+string(os.PathSeparator)) {
+		receipt.TempDirs = []string{workDir}
+	}
+	return receipt
+}
+
+// binaryVersion runs 'r2d2 version' and returns the version it prints, empty when it fails
+func binaryVersion(path string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "version").Output()
+	if err != nil {
+		return ""
+	}
+	// The output is styled, the version is its last word
+	fields := strings.Fields(stripANSI(string(output)))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
+
+// stripANSI removes terminal escape sequences from s
+func stripANSI(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x1b && i+1 < len(s) && s[i+1] == '[' {
+			i += 2
+			for i < len(s) && (s[i] < '@' || s[i] > '~') {
+				i++
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// installAndRecord installs the binary and writes the receipt of the installation
+func installAndRecord(binaryPath string, opts options, binDir string, toolchain string) (string, error) {
+	installPath, err := installBinaryTo(binaryPath, binDir)
+	if err != nil {
+		return "", err
+	}
+
+	receipt := newReceipt(opts, installPath, binaryPath, toolchain)
+	// PATH edits, completion and toolchain of an earlier installation into the same place are kept
+	if previous, err := loadReceipt(); err == nil && previous.Binary == installPath {
+		receipt.PathEdits = previous.PathEdits
+		receipt.Completion, receipt.CompletionEdit = previous.Completion, previous.CompletionEdit
+		if receipt.Toolchain == "" {
+			receipt.Toolchain = previous.Toolchain
+		}
+	}
+	if err := saveReceipt(receipt); err != nil {
+		return installPath, fmt.Errorf("installed %s but couldn't write the install receipt: %v", installPath, err)
+	}
+	return installPath, nil
+}
+
+// removePathEdit deletes the line of a PATH edit from its file, if it's still there
+func removePathEdit(edit pathEdit) error {
+	content, err := os.ReadFile(edit.File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	kept := lines[:0]
+	removed := false
+	for _, line := range lines {
+		if line == edit.Line {
+			removed = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if !removed {
+		return nil
+	}
+	return os.WriteFile(edit.File, []byte(strings.Join(kept, "\n")), 0644)
+}
+
+// hasPathEdit checks if the line of a PATH edit is still in its file
+func hasPathEdit(edit pathEdit) bool {
+	content, err := os.ReadFile(edit.File)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line == edit.Line {
+			return true
+		}
+	}
+	return false
+}
+
+// appendPathEdit adds the line of a PATH edit back to its file
+func appendPathEdit(edit pathEdit) error {
+	file, err := os.OpenFile(edit.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "\n%s\n", edit.Line)
+	return err
+}
+
+// runUninstall removes exactly what the receipt lists, with dryRun only printing it
+func runUninstall(opts options, out io.Writer, errOut io.Writer) int {
+	receipt, err := loadReceipt()
+	if err != nil {
+		fmt.Fprintf(errOut, "error: %v\n", err)
+		return exitFailure
+	}
+	if opts.output == outputQuiet {
+		out = io.Discard
+	}
+
+	failed := false
+	remove := func(what string, action func() error) {
+		if opts.dryRun {
+			fmt.Fprintf(out, "would remove %s\n", what)
+			return
+		}
+		if err := action(); err != nil {
+			fmt.Fprintf(errOut, "error: couldn't remove %s: %v\n", what, err)
+			failed = true
+			return
+		}
+		fmt.Fprintf(out, "removed %s\n", what)
+	}
+
+	if _, err := os.Stat(receipt.Binary); err == nil {
+		remove(receipt.Binary, func() error { return os.Remove(receipt.Binary) })
+	}
+	for _, edit := range receipt.PathEdits {
+		if hasPathEdit(edit) {
+			remove(fmt.Sprintf("PATH setup from %s", edit.File), func() error { return removePathEdit(edit) })
+		}
+	}
+	if fileExists(receipt.Completion) {
+		remove(receipt.Completion, func() error { return os.Remove(receipt.Completion) })
+	}
+	if edit := receipt.CompletionEdit; edit != nil && hasPathEdit(*edit) {
+		remove(fmt.Sprintf("completion setup from %s", edit.File), func() error { return removePathEdit(*edit) })
+	}
+	for _, dir := range receipt.TempDirs {
+		if _, err := os.Stat(dir); err == nil {
+			remove(dir, func() error { return os.RemoveAll(dir) })
+		}
+	}
+	if fileExists(receipt.Toolchain) {
+		remove(receipt.Toolchain, func() error { return os.RemoveAll(receipt.Toolchain) })
+	}
+
+	if failed {
+		return exitFailure
+	}
+	if !opts.dryRun {
+		path, _ := receiptPath()
+		os.Remove(path)
+		fmt.Fprintln(out, "R2D2 CLI uninstalled")
+	}
+	return exitOK
+}
+
+// pathContains checks if dir is one of the entries of a PATH value
+func pathContains(pathEnv string, dir string) bool {
+	for _, entry := range filepath.SplitList(pathEnv) {
+		if entry != "" && filepath.Clean(entry) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// runRepair checks the installation from the receipt, restoring the PATH setup
+// and rebuilding the binary when it's missing or doesn't run
+func runRepair(ctx context.Context, opts options, out io.Writer, errOut io.Writer) int {
+	receipt, err := loadReceipt()
+	if err != nil {
+		fmt.Fprintf(errOut, "error: %v\n", err)
+		return exitFailure
+	}
+	if opts.output == outputQuiet {
+		out = io.Discard
+	}
+
+	for _, edit := range receipt.PathEdits {
+		if hasPathEdit(edit) {
+			continue
+		}
+		if err := appendPathEdit(edit); err != nil {
+			fmt.Fprintf(errOut, "error: couldn't restore PATH setup in %s: %v\n", edit.File, err)
+			return exitInstall
+		}
+		fmt.Fprintf(out, "restored PATH setup in %s\n", edit.File)
+	}
+
+	binDir := filepath.Dir(receipt.Binary)
+	if len(receipt.PathEdits) == 0 && !pathContains(os.Getenv("PATH"), binDir) {
+		fmt.Fprintf(out, "warning: %s is not on the PATH, add it to your shell profile: export PATH=\"%s:$PATH\"\n", binDir, binDir)
+	}
+
+	if version := binaryVersion(receipt.Binary); version != "" {
+		fmt.Fprintf(out, "%s works (version %s)\n", receipt.Binary, version)
+		return exitOK
+	}
+
+	fmt.Fprintf(out, "%s is missing or broken, reinstalling\n", receipt.Binary)
+	if opts.source == "" {
+		opts.source = receipt.Source
+	}
+	steps := installSteps(opts)
+	detect := steps[0].run
+	steps[0].run = func(ctx context.Context, r *installRun) error {
+		err := detect(ctx, r)
+		r.osInfo.BinDir = binDir
+		return err
+	}
+
+	// A repair always starts from scratch, whatever an interrupted install left
+	r := &installRun{opts: opts, rep: nopReporter{}}
+	return runSteps(ctx, steps, r, newInstallLogger(opts.output, out, errOut))
+}
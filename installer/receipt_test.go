@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// The receipt format shared with 'r2d2 self', whose tests read the same file
+const receiptFixture = "testdata/install-receipt.json"
+
+func TestReceiptFormat(t *testing.T) {
+	content, err := os.ReadFile(receiptFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var receipt installReceipt
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&receipt); err != nil {
+		t.Fatalf("%s doesn't match installReceipt: %v", receiptFixture, err)
+	}
+
+	// Every field is set, so a new field has to be added to the fixture, and to 'r2d2 self'
+	value := reflect.ValueOf(receipt)
+	for i := 0; i < value.NumField(); i++ {
+		if value.Field(i).IsZero() {
+			t.Errorf("%s doesn't set %s", receiptFixture, value.Type().Field(i).Name)
+		}
+	}
+
+	// saveReceipt writes the fixture back unchanged
+	t.Setenv("HOME", t.TempDir())
+	if err := saveReceipt(receipt); err != nil {
+		t.Fatal(err)
+	}
+	path, _ := receiptPath()
+	if saved, _ := os.ReadFile(path); !bytes.Equal(saved, content) {
+		t.Errorf("saveReceipt() wrote:\n%s\nwant %s:\n%s", saved, receiptFixture, content)
+	}
+}
+
+// fakeInstall sets up an installed binary, a PATH edit and a temp dir with their receipt
+func fakeInstall(t *testing.T) installReceipt {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake binary is a shell script")
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+
+	binary := filepath.Join(dir, "bin", "r2d2")
+	os.MkdirAll(filepath.Dir(binary), 0755)
+	os.WriteFile(binary, []byte("#!/bin/sh\necho 'R2D2 Language - version 1.2.3'\n"), 0755)
+
+	rc := filepath.Join(home, ".profile")
+	os.WriteFile(rc, []byte("alias ll='ls -l'\nexport PATH=\""+filepath.Dir(binary)+":$PATH\"\n"), 0644)
+
+	tempDir := filepath.Join(dir, "r2d2-cli-install")
+	os.MkdirAll(tempDir, 0755)
+
+	receipt := installReceipt{
+		Binary:    binary,
+		Version:   "1.2.3",
+		PathEdits: []pathEdit{{File: rc, Line: "export PATH=\"" + filepath.Dir(binary) + ":$PATH\""}},
+		TempDirs:  []string{tempDir},
+	}
+	if err := saveReceipt(receipt); err != nil {
+		t.Fatal(err)
+	}
+	return receipt
+}
+
+func TestStripANSI(t *testing.T) {
+	if got := stripANSI("\x1b[1;35mversion 0.2.0\x1b[0m"); got != "version 0.2.0" {
+		t.Errorf("stripANSI() = %q", got)
+	}
+}
+
+func TestBinaryVersion(t *testing.T) {
+	receipt := fakeInstall(t)
+	if got := binaryVersion(receipt.Binary); got != "1.2.3" {
+		t.Errorf("binaryVersion() = %q, want 1.2.3", got)
+	}
+	if got := binaryVersion(filepath.Join(t.TempDir(), "missing")); got != "" {
+		t.Errorf("binaryVersion() of a missing binary = %q", got)
+	}
+}
+
+func TestNewReceiptRecordsOnlyCreated(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TMPDIR", t.TempDir())
+	installPath := filepath.Join(t.TempDir(), "r2d2")
+
+	// A toolchain and work directory left by another installation aren't recorded
+	os.MkdirAll(toolchainDir(), 0755)
+	os.MkdirAll(installWorkDir(), 0755)
+	receipt := newReceipt(options{}, installPath, filepath.Join(t.TempDir(), "r2d2"), "")
+	if receipt.Toolchain != "" || len(receipt.TempDirs) != 0 {
+		t.Errorf("newReceipt() recorded %q and %v, which it didn't create", receipt.Toolchain, receipt.TempDirs)
+	}
+
+	receipt = newReceipt(options{}, installPath, filepath.Join(installWorkDir(), "r2d2"), toolchainDir())
+	if receipt.Toolchain != toolchainDir() || len(receipt.TempDirs) != 1 || receipt.TempDirs[0] != installWorkDir() {
+		t.Errorf("newReceipt() = %q and %v, want the toolchain and work directory it created", receipt.Toolchain, receipt.TempDirs)
+	}
+}
+
+func TestUninstall(t *testing.T) {
+	receipt := fakeInstall(t)
+
+	var out, errOut bytes.Buffer
+	if code := runUninstall(options{dryRun: true}, &out, &errOut); code != exitOK {
+		t.Fatalf("dry run = %d: %s", code, errOut.String())
+	}
+	if _, err := os.Stat(receipt.Binary); err != nil {
+		t.Fatalf("dry run removed the binary")
+	}
+	if !strings.Contains(out.String(), "would remove "+receipt.Binary) {
+		t.Errorf("dry run output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code := runUninstall(options{}, &out, &errOut); code != exitOK {
+		t.Fatalf("runUninstall() = %d: %s", code, errOut.String())
+	}
+
+	for _, path := range []string{receipt.Binary, receipt.TempDirs[0]} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s wasn't removed", path)
+		}
+	}
+	content, _ := os.ReadFile(receipt.PathEdits[0].File)
+	if string(content) != "alias ll='ls -l'\n" {
+		t.Errorf("rc file after uninstall = %q", content)
+	}
+	if _, err := loadReceipt(); err == nil {
+		t.Errorf("receipt wasn't removed")
+	}
+
+	if code := runUninstall(options{}, &out, &errOut); code != exitFailure {
+		t.Errorf("runUninstall() without a receipt = %d, want %d", code, exitFailure)
+	}
+}
+
+func TestRepairRestoresPathEdit(t *testing.T) {
+	receipt := fakeInstall(t)
+	os.WriteFile(receipt.PathEdits[0].File, []byte("alias ll='ls -l'\n"), 0644)
+
+	var out, errOut bytes.Buffer
+	if code := runRepair(context.Background(), options{output: outputPlain}, &out, &errOut); code != exitOK {
+		t.Fatalf("runRepair() = %d: %s", code, errOut.String())
+	}
+	if !hasPathEdit(receipt.PathEdits[0]) {
+		t.Errorf("PATH edit wasn't restored")
+	}
+	if !strings.Contains(out.String(), "works (version 1.2.3)") {
+		t.Errorf("repair output:\n%s", out.String())
+	}
+}
+
+func TestRepairReinstallsBinary(t *testing.T) {
+	receipt := fakeInstall(t)
+
+	// The binary is reinstalled from the source recorded at install time
+	source := filepath.Join(t.TempDir(), "r2d2")
+	os.WriteFile(source, []byte("#!/bin/sh\necho 'R2D2 Language - version 1.2.4'\n"), 0755)
+	receipt.Source = source
+	saveReceipt(receipt)
+	os.Remove(receipt.Binary)
+
+	var out, errOut bytes.Buffer
+	if code := runRepair(context.Background(), options{output: outputPlain, noDeps: true, noModifyPath: true}, &out, &errOut); code != exitOK {
+		t.Fatalf("runRepair() = %d\nstdout:\n%s\nstderr:\n%s", code, out.String(), errOut.String())
+	}
+	if got := binaryVersion(receipt.Binary); got != "1.2.4" {
+		t.Errorf("reinstalled binary version = %q, want 1.2.4", got)
+	}
+
+	updated, err := loadReceipt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != "1.2.4" || len(updated.PathEdits) != 1 {
+		t.Errorf("receipt after repair = %+v", updated)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// State of an unfinished installation, next to the install receipt
+const resumeFile = "install-state.json"
+
+// resumeState records the steps an installation finished, so running the
+// installer again after a failure or Ctrl+C picks up where it stopped
+type resumeState struct {
+	Key        string   `json:"key"` // options the installation ran with
+	Completed  []string `json:"completed"`
+	RepoDir    string   `json:"repoDir,omitempty"`
+	BinaryPath string   `json:"binaryPath,omitempty"`
+	Prebuilt   bool     `json:"prebuilt,omitempty"`
+	Toolchain  string   `json:"toolchain,omitempty"`
+}
+
+// resumeKey identifies the options whose steps can be reused by another run
+func resumeKey(opts options) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%t|%t|%t", version, opts.installSource(), opts.cloneRef(), opts.prefix, opts.user, opts.noDeps, opts.noDeno)
+}
+
+// resumeStatePath is where the state lives, ~/.r2d2/install-state.json
+func resumeStatePath() (string, error) {
+	path, err := receiptPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), resumeFile), nil
+}
+
+// loadResumeState returns the state left by an interrupted run with the same
+// options, or an empty one to start over with
+func loadResumeState(opts options) *resumeState {
+	fresh := &resumeState{Key: resumeKey(opts)}
+	if opts.noResume {
+		return fresh
+	}
+
+	path, err := resumeStatePath()
+	if err != nil {
+		return fresh
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fresh
+	}
+
+	var state resumeState
+	if json.Unmarshal(content, &state) != nil || state.Key != fresh.Key {
+		return fresh
+	}
+	return &state
+}
+
+// stepDone records a finished step along with what it made
+func (r *installRun) stepDone(name string) {
+	if r.state == nil {
+		return
+	}
+	if !contains(r.state.Completed, name) {
+		r.state.Completed = append(r.state.Completed, name)
+	}
+	r.state.RepoDir, r.state.BinaryPath, r.state.Prebuilt, r.state.Toolchain = r.repoDir, r.binaryPath, r.prebuilt, r.toolchain
+
+	path, err := resumeStatePath()
+	if err != nil {
+		return
+	}
+	content, err := json.MarshalIndent(r.state, "", "  ")
+	if err != nil {
+		return
+	}
+	// Not being able to resume later isn't a reason to stop the installation
+	if os.MkdirAll(filepath.Dir(path), 0755) == nil {
+		os.WriteFile(path, append(content, '\n'), 0644)
+	}
+}
+
+// clearState removes the state once the installation went through
+func (r *installRun) clearState() {
+	if r.state == nil {
+		return
+	}
+	if path, err := resumeStatePath(); err == nil {
+		os.Remove(path)
+	}
+}
+
+// resumableSteps lists the steps an interrupted run with these options finished
+func resumableSteps(opts options) []string {
+	return loadResumeState(opts).Completed
+}
+
+// fileExists checks if something is at path
+func fileExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestResumeAfterFailure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	opts := options{output: outputPlain}
+
+	var ran []string
+	var out, errOut bytes.Buffer
+	if code := runSteps(context.Background(), fakeSteps(&ran, "build"), newInstallRun(opts), newInstallLogger(opts.output, &out, &errOut)); code != exitBuild {
+		t.Fatalf("runSteps() = %d, want %d", code, exitBuild)
+	}
+	if got := resumableSteps(opts); strings.Join(got, ",") != "detect,deps,clone" {
+		t.Fatalf("completed steps = %v", got)
+	}
+
+	// The next run skips what was done, and starts over once it went through
+	ran = nil
+	out.Reset()
+	if code := runSteps(context.Background(), fakeSteps(&ran, ""), newInstallRun(opts), newInstallLogger(opts.output, &out, &errOut)); code != exitOK {
+		t.Fatalf("resumed runSteps() = %d", code)
+	}
+	if strings.Join(ran, ",") != "detect,build,install" {
+		t.Errorf("resumed run ran %v, want detect,build,install", ran)
+	}
+	if !strings.Contains(out.String(), "done in the previous run") {
+		t.Errorf("output doesn't mention the skipped steps:\n%s", out.String())
+	}
+	if got := resumableSteps(opts); len(got) != 0 {
+		t.Errorf("state left after a successful run: %v", got)
+	}
+}
+
+func TestResumeNotApplicable(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	opts := options{output: outputQuiet}
+
+	var ran []string
+	var out bytes.Buffer
+	runSteps(context.Background(), fakeSteps(&ran, "install"), newInstallRun(opts), newInstallLogger(opts.output, &out, &out))
+
+	tests := []struct {
+		name string
+		opts options
+	}{
+		{"other options", options{output: outputQuiet, user: true}},
+		{"no resume", options{output: outputQuiet, noResume: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resumableSteps(tt.opts); len(got) != 0 {
+				t.Errorf("resumableSteps() = %v, want none", got)
+			}
+		})
+	}
+}
+
+func TestResumeSkipsMissingOutput(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	opts := options{output: outputQuiet}
+
+	var ran []string
+	var out bytes.Buffer
+	runSteps(context.Background(), fakeSteps(&ran, "install"), newInstallRun(opts), newInstallLogger(opts.output, &out, &out))
+
+	// The clone is gone, so it and every step after it run again
+	steps := fakeSteps(&ran, "")
+	steps[2].finished = func(r *installRun) bool { return false }
+	ran = nil
+	runSteps(context.Background(), steps, newInstallRun(opts), newInstallLogger(opts.output, &out, &out))
+	if strings.Join(ran, ",") != "detect,clone,build,install" {
+		t.Errorf("ran %v, want detect,clone,build,install", ran)
+	}
+}
+
+func TestRunStepsCancelled(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	opts := options{output: outputPlain}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	steps := fakeSteps(new([]string), "")
+	steps[3].run = func(ctx context.Context, r *installRun) error {
+		cancel()
+		return errors.New("signal: killed")
+	}
+
+	var out, errOut bytes.Buffer
+	if code := runSteps(ctx, steps, newInstallRun(opts), newInstallLogger(opts.output, &out, &errOut)); code != exitCancelled {
+		t.Errorf("runSteps() = %d, want %d", code, exitCancelled)
+	}
+	if !strings.Contains(errOut.String(), "resume") {
+		t.Errorf("stderr = %q, want a hint about resuming", errOut.String())
+	}
+	if got := resumableSteps(opts); strings.Join(got, ",") != "detect,deps,clone" {
+		t.Errorf("completed steps after cancelling = %v", got)
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Kinds of installation sources
+const (
+	sourceRemote  = "remote"  // clone the GitHub repository
+	sourceDir     = "dir"     // build from a local checkout
+	sourceArchive = "archive" // a .tar.gz holding either a checkout or a prebuilt binary
+	sourceBinary  = "binary"  // install a prebuilt binary as is
+	sourceURL     = "url"     // download a .tar.gz, verified against checksums.txt
+)
+
+// errNoCLI is returned for archives holding neither a source tree nor the CLI,
+// like the releases that only archived the installer
+var errNoCLI = fmt.Errorf("archive holds neither go.mod nor a %s binary", binaryName())
+
+// preparedSource is what the build and install steps start from.
+// Exactly one of the fields is set.
+type preparedSource struct {
+	repoDir    string // source tree to build
+	binaryPath string // prebuilt binary, nothing to build
+}
+
+// sourceKind tells what --source points to
+func sourceKind(source string) (string, error) {
+	if source == "" {
+		return sourceRemote, nil
+	}
+
+	if isURL(source) {
+		if !isArchiveName(source) {
+			return "", fmt.Errorf("only .tar.gz archives can be downloaded: %s", source)
+		}
+		return sourceURL, nil
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return "", fmt.Errorf("source not found: %s", source)
+	}
+
+	switch {
+	case info.IsDir():
+		return sourceDir, nil
+	case isArchiveName(source):
+		return sourceArchive, nil
+	default:
+		return sourceBinary, nil
+	}
+}
+
+// isURL tells whether source is downloaded rather than read from disk
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://")
+}
+
+// isArchiveName tells whether a file name is a gzipped tarball
+func isArchiveName(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+// isReleaseArchive tells whether a downloaded archive is a release holding a prebuilt
+// binary, named like goreleaser does, rather than a source tarball
+func isReleaseArchive(url string) bool {
+	return strings.HasPrefix(path.Base(url), "r2d2-cli_")
+}
+
+// binaryName is the file name of the CLI on this platform
+func binaryName() string {
+	if runtime.GOOS == "windows" {
+		return "r2d2.exe"
+	}
+	return "r2d2"
+}
+
+// requiredTools lists the tools an installation from source needs
+func requiredTools(source string) []string {
+	kind, err := sourceKind(source)
+	if err != nil {
+		return nil
+	}
+
+	switch kind {
+	case sourceRemote:
+		return []string{"git", "go", "deno"}
+	case sourceDir:
+		return []string{"go", "deno"}
+	case sourceArchive:
+		if _, prebuilt, err := archiveRoot(source); err == nil && prebuilt {
+			return []string{"deno"}
+		}
+		return []string{"go", "deno"}
+	case sourceURL:
+		if isReleaseArchive(source) {
+			return []string{"deno"}
+		}
+		return []string{"go", "deno"}
+	default:
+		return []string{"deno"}
+	}
+}
+
+// missingTools returns the tools that aren't on the PATH
+func missingTools(tools []string) []string {
+	var missing []string
+	for _, tool := range tools {
+		if !commandExists(tool) {
+			missing = append(missing, tool)
+		}
+	}
+	return missing
+}
+
+// prepareSource gets the source of the installation ready in a temporary directory
+func prepareSource(ctx context.Context, source string, ref string, rep reporter) (preparedSource, error) {
+	kind, err := sourceKind(source)
+	if err != nil {
+		return preparedSource{}, err
+	}
+
+	switch kind {
+	case sourceRemote:
+		repoDir, err := cloneRepo(ctx, ref, rep)
+		return preparedSource{repoDir: repoDir}, err
+
+	case sourceBinary:
+		return preparedSource{binaryPath: source}, nil
+	}
+
+	workDir := installWorkDir()
+	os.RemoveAll(workDir)
+
+	if kind == sourceDir {
+		// Building in a copy keeps go mod tidy and the binary out of the checkout
+		if err := copyTree(source, workDir); err != nil {
+			return preparedSource{}, fmt.Errorf("failed to copy source: %v", err)
+		}
+		return preparedSource{repoDir: workDir}, nil
+	}
+
+	var archive io.Reader
+	if kind == sourceURL {
+		content, err := fetchVerified(ctx, source, rep)
+		if err != nil {
+			return preparedSource{}, err
+		}
+		archive = bytes.NewReader(content)
+	} else {
+		file, err := os.Open(source)
+		if err != nil {
+			return preparedSource{}, err
+		}
+		defer file.Close()
+		archive = file
+	}
+
+	if err := extractArchive(archive, workDir); err != nil {
+		return preparedSource{}, fmt.Errorf("failed to extract %s: %v", source, err)
+	}
+	return findPreparedSource(workDir)
+}
+
+// findPreparedSource looks inside an extracted archive for a source tree or a binary.
+// Both may sit at the top or inside a single top-level directory.
+func findPreparedSource(dir string) (preparedSource, error) {
+	roots := []string{dir}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 1 && entries[0].IsDir() {
+		roots = append(roots, filepath.Join(dir, entries[0].Name()))
+	}
+
+	for _, root := range roots {
+		if _, err := os.Stat(filepath.Join(root, "go.mod")); err == nil {
+			return preparedSource{repoDir: root}, nil
+		}
+		if info, err := os.Stat(filepath.Join(root, binaryName())); err == nil && !info.IsDir() {
+			return preparedSource{binaryPath: filepath.Join(root, binaryName())}, nil
+		}
+	}
+	return preparedSource{}, errNoCLI
+}
+
+// archiveRoot finds the directory of a .tar.gz holding go.mod or, for release archives,
+// the prebuilt binary. Both may sit at the top or inside a single top-level directory.
+func archiveRoot(path string) (string, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return "", false, err
+	}
+	defer gz.Close()
+
+	binaryDir, hasBinary := "", false
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", false, err
+		}
+
+		name := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(header.Name)), "./")
+		if strings.Count(name, "/") > 1 || header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		dir := filepath.Dir(filepath.FromSlash(name))
+		if dir == "." {
+			dir = ""
+		}
+		switch filepath.Base(name) {
+		case "go.mod":
+			return dir, false, nil
+		case binaryName():
+			binaryDir, hasBinary = dir, true
+		}
+	}
+
+	if !hasBinary {
+		return "", false, fmt.Errorf("%s holds neither go.mod nor a %s binary", path, binaryName())
+	}
+	return binaryDir, true, nil
+}
+
+// extractArchive extracts a gzipped tar stream into dir
+func extractArchive(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm()|0600)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(file, tr); err != nil {
+				file.Close()
+				return err
+			}
+			file.Close()
+		}
+	}
+}
+
+// copyTree copies a directory, skipping .git
+func copyTree(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, info.Mode().Perm())
+	})
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeArchive writes a .tar.gz holding files
+func writeArchive(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSourceKind(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "r2d2.tar.gz")
+	binary := filepath.Join(dir, "r2d2")
+	writeArchive(t, archive, map[string]string{"r2d2": "bin"})
+	os.WriteFile(binary, []byte("bin"), 0755)
+
+	tests := []struct {
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{"", sourceRemote, false},
+		{dir, sourceDir, false},
+		{archive, sourceArchive, false},
+		{binary, sourceBinary, false},
+		{filepath.Join(dir, "missing"), "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := sourceKind(tt.source)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("sourceKind(%q) = %q, %v, want %q", tt.source, got, err, tt.want)
+		}
+	}
+}
+
+func TestRequiredTools(t *testing.T) {
+	dir := t.TempDir()
+	prebuilt := filepath.Join(dir, "prebuilt.tar.gz")
+	sources := filepath.Join(dir, "sources.tar.gz")
+	writeArchive(t, prebuilt, map[string]string{"r2d2-cli_linux_amd64/" + binaryName(): "bin", "README.md": "readme"})
+	writeArchive(t, sources, map[string]string{"r2d2-cli/go.mod": "module x", "r2d2-cli/main.go": "package main"})
+
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"", "git go deno"},
+		{dir, "go deno"},
+		{prebuilt, "deno"},
+		{sources, "go deno"},
+	}
+
+	for _, tt := range tests {
+		if got := strings.Join(requiredTools(tt.source), " "); got != tt.want {
+			t.Errorf("requiredTools(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestMissingTools(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go"), []byte("#!/bin/sh\n"), 0755)
+	t.Setenv("PATH", dir)
+
+	if got := missingTools([]string{"git", "go", "deno"}); strings.Join(got, " ") != "git deno" {
+		t.Errorf("missingTools() = %v, want git and deno", got)
+	}
+}
+
+func TestPrepareSource(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+
+	checkout := filepath.Join(dir, "checkout")
+	os.MkdirAll(filepath.Join(checkout, ".git"), 0755)
+	os.WriteFile(filepath.Join(checkout, "go.mod"), []byte("module x"), 0644)
+	os.WriteFile(filepath.Join(checkout, ".git", "HEAD"), []byte("ref"), 0644)
+
+	prebuilt := filepath.Join(dir, "prebuilt.tar.gz")
+	writeArchive(t, prebuilt, map[string]string{"r2d2-cli_linux_amd64/" + binaryName(): "bin"})
+
+	sources := filepath.Join(dir, "sources.tar.gz")
+	writeArchive(t, sources, map[string]string{"r2d2-cli/go.mod": "module x"})
+
+	t.Run("checkout is copied without .git", func(t *testing.T) {
+		prepared, err := prepareSource(context.Background(), checkout, "", nopReporter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prepared.repoDir == checkout || prepared.binaryPath != "" {
+			t.Errorf("prepareSource() = %+v, want a copy of the checkout", prepared)
+		}
+		if _, err := os.Stat(filepath.Join(prepared.repoDir, "go.mod")); err != nil {
+			t.Error("go.mod not copied")
+		}
+		if _, err := os.Stat(filepath.Join(prepared.repoDir, ".git")); err == nil {
+			t.Error(".git copied")
+		}
+	})
+
+	t.Run("prebuilt archive", func(t *testing.T) {
+		prepared, err := prepareSource(context.Background(), prebuilt, "", nopReporter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(prepared.binaryPath) != binaryName() || prepared.repoDir != "" {
+			t.Errorf("prepareSource() = %+v, want the binary", prepared)
+		}
+	})
+
+	t.Run("source archive", func(t *testing.T) {
+		prepared, err := prepareSource(context.Background(), sources, "", nopReporter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(prepared.repoDir) != "r2d2-cli" {
+			t.Errorf("prepareSource() = %+v, want the r2d2-cli directory", prepared)
+		}
+	})
+
+	t.Run("empty archive", func(t *testing.T) {
+		empty := filepath.Join(dir, "empty.tar.gz")
+		writeArchive(t, empty, map[string]string{"README.md": "readme"})
+		if _, err := prepareSource(context.Background(), empty, "", nopReporter{}); err == nil {
+			t.Error("prepareSource() of an archive without go.mod or binary should fail")
+		}
+	})
+}
+
+func TestHeadlessInstallFromBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("installBinaryTo copies with cp")
+	}
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "r2d2-cli_linux_amd64.tar.gz")
+	writeArchive(t, archive, map[string]string{binaryName(): "#!/bin/sh\necho r2d2\n"})
+	prefix := filepath.Join(dir, "prefix")
+
+	opts, err := parseOptions([]string{"--yes", "--no-deps", "--source", archive, "--prefix", prefix})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	if code := runHeadless(context.Background(), opts, &out, &errOut); code != exitOK {
+		t.Fatalf("runHeadless() = %d\nstdout:\n%s\nstderr:\n%s", code, out.String(), errOut.String())
+	}
+
+	installed := filepath.Join(prefix, "bin", binaryName())
+	content, err := os.ReadFile(installed)
+	if err != nil || !strings.Contains(string(content), "echo r2d2") {
+		t.Errorf("installed binary = %q, %v", content, err)
+	}
+	if !strings.Contains(out.String(), "skipped, using the prebuilt binary") {
+		t.Errorf("output doesn't mention the skipped build:\n%s", out.String())
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Go release installed when no package manager can provide one, the version go.mod asks for
+var goToolchainVersion = "1.24.4"
+
+// Where the Go release tarballs and their .sha256 files are downloaded from
+var goDownloadURL = "https://dl.google.com/go"
+
+// toolchainDir is the private prefix Go and Deno are downloaded into when the
+// system can't install them: Go in go/, Deno in bin/
+func toolchainDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".r2d2", "toolchain")
+}
+
+// toolchainBinDirs are the directories of the downloaded tools
+func toolchainBinDirs() []string {
+	dir := toolchainDir()
+	return []string{filepath.Join(dir, "go", "bin"), filepath.Join(dir, "bin")}
+}
+
+// useToolchain puts the downloaded tools that exist on the PATH of the installer,
+// so the build finds them
+func useToolchain() {
+	pathEnv := os.Getenv("PATH")
+	for _, dir := range toolchainBinDirs() {
+		if fileExists(dir) && !pathContains(pathEnv, dir) {
+			pathEnv = dir + string(os.PathListSeparator) + pathEnv
+		}
+	}
+	os.Setenv("PATH", pathEnv)
+}
+
+// goTarget is the name Go releases use for a platform, empty when there's none
+func goTarget(goos string, goarch string) string {
+	if goos != "linux" && goos != "darwin" {
+		return ""
+	}
+	switch goarch {
+	case "amd64", "arm64":
+		return goos + "-" + goarch
+	case "386":
+		if goos == "linux" {
+			return "linux-386"
+		}
+	}
+	return ""
+}
+
+// verifiedDownloadScript returns shell lines downloading url into file, compared
+// with the checksum published at sumURL
+func verifiedDownloadScript(url string, sumURL string, file string) []string {
+	name := filepath.Base(file)
+	return []string{
+		`curl -fsSLo "` + file + `" ` + shellQuote(url),
+		`expected=$(curl -fsSL ` + shellQuote(sumURL) + ` | awk '{print tolower($1); exit}')`,
+		`if command -v sha256sum >/dev/null; then actual=$(sha256sum "` + file + `" | awk '{print $1}'); else actual=$(shasum -a 256 "` + file + `" | awk '{print $1}'); fi`,
+		`if [ "$expected" != "$actual" ]; then echo "checksum mismatch for ` + name + `: expected $expected, got $actual" >&2; exit 1; fi`,
+	}
+}
+
+// goInstallScript downloads a Go release tarball, compares it with the .sha256
+// published next to it and unpacks it into $R2D2_TOOLCHAIN/go
+func goInstallScript(tarball string) string {
+	lines := []string{
+		`set -e`,
+		`tmp=$(mktemp -d)`,
+		`trap 'rm -rf "$tmp"' EXIT`,
+	}
+	lines = append(lines, verifiedDownloadScript(tarball, tarball+".sha256", "$tmp/go.tar.gz")...)
+	return strings.Join(append(lines,
+		`rm -rf "$R2D2_TOOLCHAIN/go"`,
+		`mkdir -p "$R2D2_TOOLCHAIN"`,
+		`tar -xzf "$tmp/go.tar.gz" -C "$R2D2_TOOLCHAIN"`,
+	), "\n")
+}
+
+// toolchainCommands returns the downloads of the missing tools that have a release
+// for this platform
+func toolchainCommands(missing []string) []plannedCommand {
+	var commands []plannedCommand
+	for _, tool := range missing {
+		switch {
+		case tool == "go" && goTarget(runtime.GOOS, runtime.GOARCH) != "":
+			tarball := goDownloadURL + "/go" + goToolchainVersion + "." + goTarget(runtime.GOOS, runtime.GOARCH) + ".tar.gz"
+			commands = append(commands, plannedCommand{
+				step:  "deps",
+				args:  []string{"sh", "-c", goInstallScript(tarball)},
+				env:   []string{"R2D2_TOOLCHAIN=" + toolchainDir()},
+				label: "install Go " + goToolchainVersion + " into " + toolchainDir() + " from " + tarball + ", checked against its .sha256",
+			})
+		case tool == "deno" && denoTarget(runtime.GOOS, runtime.GOARCH) != "":
+			zip := denoReleaseURL + "/deno-" + denoTarget(runtime.GOOS, runtime.GOARCH) + ".zip"
+			commands = append(commands, plannedCommand{
+				step:     "deps",
+				args:     []string{"sh", "-c", denoInstallScript(zip)},
+				env:      []string{"DENO_INSTALL=" + toolchainDir()},
+				optional: "deno",
+				label:    "install Deno into " + toolchainDir() + " from " + zip + ", checked against its .sha256sum",
+			})
+		}
+	}
+	return commands
+}
+
+// usesToolchain tells whether any of the commands downloads into the toolchain
+func usesToolchain(commands []plannedCommand) bool {
+	for _, c := range commands {
+		for _, env := range c.env {
+			if strings.HasSuffix(env, "="+toolchainDir()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pathDirs lists the directories the path step puts on the PATH: binDir, and the
+// toolchain when Deno, which 'r2d2 run' needs, is downloaded into it
+func pathDirs(binDir string, commands []plannedCommand) []string {
+	for _, c := range commands {
+		if c.optional == "deno" && usesToolchain([]plannedCommand{c}) {
+			return []string{binDir, filepath.Join(toolchainDir(), "bin")}
+		}
+	}
+	return []string{binDir}
+}
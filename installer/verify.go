@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// Minisign public key of the releases, set with -X main.releasePublicKey=RW... when
+// building them. Without it downloads are only checked against checksums.txt.
+var releasePublicKey = ""
+
+// Files published next to the release archives, as goreleaser names them
+const (
+	checksumsFile = "checksums.txt"
+	signatureFile = "checksums.txt.minisig"
+)
+
+// verificationError is a download that doesn't match its checksum or signature
+type verificationError struct {
+	msg string
+}
+
+func (e *verificationError) Error() string {
+	return e.msg + ". The download may be corrupted or tampered with, nothing was installed"
+}
+
+// downloadBytes fetches url, failing on any status other than 200
+func downloadBytes(ctx context.Context, url string, rep reporter) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download of %s failed: %s", url, resp.Status)
+	}
+	rep.logLine("downloading " + url)
+	return io.ReadAll(&countingReader{r: resp.Body, total: resp.ContentLength, name: path.Base(url), rep: rep})
+}
+
+// parseChecksums reads a checksums.txt, one "<sha256>  <file>" per line
+func parseChecksums(content []byte) map[string]string {
+	sums := map[string]string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		// sha256sum marks binary mode with a * before the name
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return sums
+}
+
+// verifyChecksum checks data against the checksum listed for name
+func verifyChecksum(name string, data []byte, sums map[string]string) error {
+	expected, ok := sums[name]
+	if !ok {
+		return &verificationError{fmt.Sprintf("%s isn't listed in %s", name, checksumsFile)}
+	}
+
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return &verificationError{fmt.Sprintf("checksum mismatch for %s: expected %s, got %s", name, expected, actual)}
+	}
+	return nil
+}
+
+// minisignKey is a decoded minisign public key
+type minisignKey struct {
+	id  []byte
+	key ed25519.PublicKey
+}
+
+// parseMinisignKey decodes the base64 line of a minisign public key
+func parseMinisignKey(encoded string) (minisignKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return minisignKey{}, fmt.Errorf("invalid minisign public key")
+	}
+	return minisignKey{id: raw[2:10], key: ed25519.PublicKey(raw[10:])}, nil
+}
+
+// verifyMinisign checks a minisign signature of data, including its trusted comment.
+// Only legacy signatures (minisign -S -l) are supported, prehashed ones need BLAKE2b.
+func verifyMinisign(key minisignKey, data []byte, signature []byte) error {
+	lines := strings.Split(strings.TrimSpace(string(signature)), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return &verificationError{"malformed signature " + signatureFile}
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return &verificationError{"malformed signature " + signatureFile}
+	}
+	switch {
+	case string(sig[:2]) == "ED":
+		return &verificationError{"prehashed minisign signatures aren't supported, sign with minisign -S -l"}
+	case string(sig[:2]) != "Ed":
+		return &verificationError{"unknown signature algorithm in " + signatureFile}
+	case string(sig[2:10]) != string(key.id):
+		return &verificationError{signatureFile + " was signed with another key"}
+	}
+	if !ed25519.Verify(key.key, data, sig[10:]) {
+		return &verificationError{"invalid signature of " + checksumsFile}
+	}
+
+	// The global signature covers the signature and the trusted comment
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	comment := strings.TrimPrefix(strings.TrimRight(lines[2], "\r"), "trusted comment: ")
+	message := append(append([]byte{}, sig[10:]...), comment...)
+	if err != nil || !ed25519.Verify(key.key, message, global) {
+		return &verificationError{"invalid trusted comment in " + signatureFile}
+	}
+	return nil
+}
+
+// fetchVerified downloads a release archive and checks it against the checksums.txt
+// next to it, itself checked against its signature when a release key is embedded
+func fetchVerified(ctx context.Context, archiveURL string, rep reporter) ([]byte, error) {
+	base := archiveURL[:strings.LastIndex(archiveURL, "/")]
+
+	sums, err := downloadBytes(ctx, base+"/"+checksumsFile, rep)
+	if err != nil {
+		return nil, err
+	}
+
+	if releasePublicKey != "" {
+		key, err := parseMinisignKey(releasePublicKey)
+		if err != nil {
+			return nil, err
+		}
+		signature, err := downloadBytes(ctx, base+"/"+signatureFile, rep)
+		if err != nil {
+			return nil, &verificationError{fmt.Sprintf("couldn't get %s: %v", signatureFile, err)}
+		}
+		if err := verifyMinisign(key, sums, signature); err != nil {
+			return nil, err
+		}
+		rep.logLine("signature of " + checksumsFile + " verified")
+	}
+
+	archive, err := downloadBytes(ctx, archiveURL, rep)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyChecksum(path.Base(archiveURL), archive, parseChecksums(sums)); err != nil {
+		return nil, err
+	}
+	rep.logLine("checksum of " + path.Base(archiveURL) + " verified")
+	return archive, nil
+}
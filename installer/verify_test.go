@@ -0,0 +1,383 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// releaseServer serves dir over HTTP like a release download directory
+func releaseServer(t *testing.T, dir string) string {
+	t.Helper()
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// sha256Hex returns the checksum of the file at path as checksums.txt lists it
+func sha256Hex(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeRelease writes a release archive holding the binary and its checksums.txt
+func writeRelease(t *testing.T, dir string, name string) {
+	t.Helper()
+	archive := filepath.Join(dir, name)
+	writeArchive(t, archive, map[string]string{binaryName(): "#!/bin/sh\necho 'R2D2 Language - version 0.3.0'\n"})
+	sums := fmt.Sprintf("%s  %s\nabc123  other.tar.gz\n", sha256Hex(t, archive), name)
+	if err := os.WriteFile(filepath.Join(dir, checksumsFile), []byte(sums), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// minisignFixture returns a minisign public key and a legacy signature of data
+func minisignFixture(t *testing.T, data []byte, comment string) (string, []byte, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := []byte("r2d2key!")
+
+	key := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...))
+	return key, signMinisign(priv, keyID, data, comment), priv
+}
+
+// signMinisign writes a minisign signature file for data
+func signMinisign(priv ed25519.PrivateKey, keyID []byte, data []byte, comment string) []byte {
+	sig := ed25519.Sign(priv, data)
+	global := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+	return []byte(fmt.Sprintf("untrusted comment: signature from r2d2 release key\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), sig...)),
+		comment,
+		base64.StdEncoding.EncodeToString(global)))
+}
+
+// withAlgorithm changes the algorithm recorded in a minisign signature
+func withAlgorithm(t *testing.T, signature []byte, alg string) []byte {
+	t.Helper()
+	lines := strings.Split(string(signature), "\n")
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(raw, alg)
+	lines[1] = base64.StdEncoding.EncodeToString(raw)
+	return []byte(strings.Join(lines, "\n"))
+}
+
+func TestParseChecksums(t *testing.T) {
+	sums := parseChecksums([]byte("ABC123  r2d2-cli_linux_amd64.tar.gz\ndef456 *r2d2-cli_darwin_arm64.tar.gz\n\nbroken line here\n"))
+	if len(sums) != 2 || sums["r2d2-cli_linux_amd64.tar.gz"] != "abc123" || sums["r2d2-cli_darwin_arm64.tar.gz"] != "def456" {
+		t.Errorf("parseChecksums() = %v", sums)
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("release")
+	sum := sha256.Sum256(data)
+	sums := map[string]string{"r2d2.tar.gz": hex.EncodeToString(sum[:])}
+
+	if err := verifyChecksum("r2d2.tar.gz", data, sums); err != nil {
+		t.Errorf("verifyChecksum() = %v", err)
+	}
+
+	var verr *verificationError
+	if err := verifyChecksum("r2d2.tar.gz", []byte("tampered"), sums); !errors.As(err, &verr) || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("verifyChecksum() of tampered data = %v", err)
+	}
+	if err := verifyChecksum("other.tar.gz", data, sums); !errors.As(err, &verr) {
+		t.Errorf("verifyChecksum() of an unlisted file = %v", err)
+	}
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	data := []byte("abc123  r2d2-cli_linux_amd64.tar.gz\n")
+	encoded, signature, priv := minisignFixture(t, data, "timestamp:1700000000")
+	key, err := parseMinisignKey(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyMinisign(key, data, signature); err != nil {
+		t.Errorf("verifyMinisign() = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		data      []byte
+		signature []byte
+		want      string
+	}{
+		{"tampered data", []byte("def456  r2d2-cli_linux_amd64.tar.gz\n"), signature, "invalid signature"},
+		{"tampered comment", data, bytes.Replace(signature, []byte("1700000000"), []byte("1800000000"), 1), "invalid trusted comment"},
+		{"other key", data, signMinisign(priv, []byte("otherkey"), data, "x"), "another key"},
+		{"prehashed", data, withAlgorithm(t, signature, "ED"), "prehashed"},
+		{"malformed", data, []byte("not a signature"), "malformed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyMinisign(key, tt.data, tt.signature)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("verifyMinisign() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseMinisignKey("not a key"); err == nil {
+		t.Error("parseMinisignKey() of garbage should fail")
+	}
+}
+
+func TestFetchVerified(t *testing.T) {
+	dir := t.TempDir()
+	name := "r2d2-cli_linux_amd64.tar.gz"
+	writeRelease(t, dir, name)
+	url := releaseServer(t, dir) + "/" + name
+
+	if _, err := fetchVerified(context.Background(), url, nopReporter{}); err != nil {
+		t.Fatalf("fetchVerified() = %v", err)
+	}
+
+	t.Run("signed", func(t *testing.T) {
+		sums, _ := os.ReadFile(filepath.Join(dir, checksumsFile))
+		key, signature, _ := minisignFixture(t, sums, "r2d2 release")
+		releasePublicKey = key
+		t.Cleanup(func() { releasePublicKey = "" })
+
+		if _, err := fetchVerified(context.Background(), url, nopReporter{}); err == nil || !strings.Contains(err.Error(), signatureFile) {
+			t.Errorf("fetchVerified() without a signature = %v", err)
+		}
+
+		os.WriteFile(filepath.Join(dir, signatureFile), signature, 0644)
+		if _, err := fetchVerified(context.Background(), url, nopReporter{}); err != nil {
+			t.Errorf("fetchVerified() with a valid signature = %v", err)
+		}
+
+		// A new checksums.txt for a tampered archive doesn't match the signature
+		os.WriteFile(filepath.Join(dir, checksumsFile), append(sums, "abc  extra.tar.gz\n"...), 0644)
+		var verr *verificationError
+		if _, err := fetchVerified(context.Background(), url, nopReporter{}); !errors.As(err, &verr) {
+			t.Errorf("fetchVerified() with a tampered checksums.txt = %v", err)
+		}
+		os.WriteFile(filepath.Join(dir, checksumsFile), sums, 0644)
+	})
+
+	t.Run("tampered archive", func(t *testing.T) {
+		writeArchive(t, filepath.Join(dir, name), map[string]string{binaryName(): "#!/bin/sh\necho evil\n"})
+		var verr *verificationError
+		if _, err := fetchVerified(context.Background(), url, nopReporter{}); !errors.As(err, &verr) {
+			t.Errorf("fetchVerified() of a tampered archive = %v", err)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if _, err := fetchVerified(context.Background(), releaseServer(t, t.TempDir())+"/"+name, nopReporter{}); err == nil {
+			t.Error("fetchVerified() without checksums.txt should fail")
+		}
+	})
+}
+
+func TestHeadlessInstallFromURL(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("installBinaryTo copies with cp")
+	}
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	name := "r2d2-cli_linux_amd64.tar.gz"
+	writeRelease(t, dir, name)
+	url := releaseServer(t, dir) + "/" + name
+	prefix := filepath.Join(t.TempDir(), "prefix")
+
+	opts, err := parseOptions([]string{"--yes", "--no-deps", "--source", url, "--prefix", prefix})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	if code := runHeadless(context.Background(), opts, &out, &errOut); code != exitOK {
+		t.Fatalf("runHeadless() = %d\nstdout:\n%s\nstderr:\n%s", code, out.String(), errOut.String())
+	}
+	if _, err := os.Stat(filepath.Join(prefix, "bin", binaryName())); err != nil {
+		t.Errorf("binary wasn't installed: %v", err)
+	}
+
+	// A tampered archive stops the installation with its own exit code
+	os.Remove(filepath.Join(prefix, "bin", binaryName()))
+	writeArchive(t, filepath.Join(dir, name), map[string]string{binaryName(): "#!/bin/sh\necho evil\n"})
+	out.Reset()
+	errOut.Reset()
+	if code := runHeadless(context.Background(), opts, &out, &errOut); code != exitVerify {
+		t.Errorf("runHeadless() of a tampered archive = %d, want %d\nstderr:\n%s", code, exitVerify, errOut.String())
+	}
+	if !strings.Contains(errOut.String(), "checksum mismatch") {
+		t.Errorf("stderr doesn't explain the failure:\n%s", errOut.String())
+	}
+	if _, err := os.Stat(filepath.Join(prefix, "bin", binaryName())); !os.IsNotExist(err) {
+		t.Errorf("tampered binary was installed")
+	}
+}
+
+func TestDenoInstallScript(t *testing.T) {
+	for _, tool := range []string{"sh", "curl", "unzip", "awk"} {
+		if !commandExists(tool) {
+			t.Skipf("%s not found", tool)
+		}
+	}
+	if !commandExists("sha256sum") && !commandExists("shasum") {
+		t.Skip("sha256sum not found")
+	}
+
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("deno")
+	w.Write([]byte("#!/bin/sh\necho deno 2.0.0\n"))
+	zw.Close()
+	os.WriteFile(filepath.Join(dir, "deno-test.zip"), buf.Bytes(), 0644)
+	sum := sha256.Sum256(buf.Bytes())
+	os.WriteFile(filepath.Join(dir, "deno-test.zip.sha256sum"), []byte(strings.ToUpper(hex.EncodeToString(sum[:]))+"  deno-test.zip\n"), 0644)
+
+	zipURL := releaseServer(t, dir) + "/deno-test.zip"
+	run := func() (string, error) {
+		denoDir := t.TempDir()
+		cmd := exec.Command("sh", "-c", denoInstallScript(zipURL))
+		cmd.Env = append(os.Environ(), "DENO_INSTALL="+denoDir)
+		output, err := cmd.CombinedOutput()
+		if err == nil {
+			if _, statErr := os.Stat(filepath.Join(denoDir, "bin", "deno")); statErr != nil {
+				return string(output), statErr
+			}
+		}
+		return string(output), err
+	}
+
+	if output, err := run(); err != nil {
+		t.Fatalf("deno install failed: %v\n%s", err, output)
+	}
+
+	os.WriteFile(filepath.Join(dir, "deno-test.zip"), append(buf.Bytes(), "tampered"...), 0644)
+	output, err := run()
+	if err == nil || !strings.Contains(output, "checksum mismatch") {
+		t.Errorf("tampered deno.zip = %v\n%s", err, output)
+	}
+}
+
+func TestHeadlessInstallPinnedVersion(t *testing.T) {
+	archive := releaseArchiveName(runtime.GOOS, runtime.GOARCH)
+	if archive == "" {
+		t.Skip("no release archive for this platform")
+	}
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	// The release server holds v0.3.0, a v0.4.0 whose binary reports 0.3.0,
+	// and a v0.2.3 that, like the releases before 0.2.4, only archived the installer
+	dir := t.TempDir()
+	for _, tag := range []string{"v0.3.0", "v0.4.0"} {
+		tagDir := filepath.Join(dir, "download", tag)
+		os.MkdirAll(tagDir, 0755)
+		writeRelease(t, tagDir, archive)
+	}
+	oldDir := filepath.Join(dir, "download", "v0.2.3")
+	os.MkdirAll(oldDir, 0755)
+	writeArchive(t, filepath.Join(oldDir, archive), map[string]string{"r2d2-installer": "#!/bin/sh\n"})
+	sums := fmt.Sprintf("%s  %s\n", sha256Hex(t, filepath.Join(oldDir, archive)), archive)
+	os.WriteFile(filepath.Join(oldDir, checksumsFile), []byte(sums), 0644)
+	defer func(url string) { releaseURL = url }(releaseURL)
+	releaseURL = releaseServer(t, dir)
+
+	tests := []struct {
+		pin      string
+		noTools  bool // git and go aren't on the PATH, so falling back to a clone fails early
+		wantCode int
+		want     string
+	}{
+		{"v0.3.0", false, exitOK, "r2d2 version 0.3.0"},
+		{"v0.4.0", false, exitInstall, "reports version 0.3.0, expected 0.4.0"},
+		{"v0.2.3", true, exitFetch, "release v0.2.3 has no r2d2 binary, building it from the tag needs git and go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pin, func(t *testing.T) {
+			if tt.noTools {
+				t.Setenv("PATH", t.TempDir())
+			}
+			opts, err := parseOptions([]string{"--yes", "--no-deps", "--no-modify-path", "--version-pin", tt.pin, "--prefix", t.TempDir()})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			if code := runHeadless(context.Background(), opts, &out, &out); code != tt.wantCode {
+				t.Fatalf("runHeadless() = %d, want %d\n%s", code, tt.wantCode, out.String())
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("output doesn't contain %q:\n%s", tt.want, out.String())
+			}
+		})
+	}
+}
+
+func TestGoInstallScript(t *testing.T) {
+	for _, tool := range []string{"sh", "curl", "tar", "awk"} {
+		if !commandExists(tool) {
+			t.Skipf("%s not found", tool)
+		}
+	}
+	if !commandExists("sha256sum") && !commandExists("shasum") {
+		t.Skip("sha256sum not found")
+	}
+
+	dir := t.TempDir()
+	tarball := filepath.Join(dir, "go1.0.0.test.tar.gz")
+	writeArchive(t, tarball, map[string]string{"go/bin/go": "#!/bin/sh\necho go1.0.0\n"})
+	os.WriteFile(tarball+".sha256", []byte(sha256Hex(t, tarball)), 0644)
+
+	url := releaseServer(t, dir) + "/go1.0.0.test.tar.gz"
+	run := func() (string, error) {
+		toolchain := t.TempDir()
+		cmd := exec.Command("sh", "-c", goInstallScript(url))
+		cmd.Env = append(os.Environ(), "R2D2_TOOLCHAIN="+toolchain)
+		output, err := cmd.CombinedOutput()
+		if err == nil {
+			if _, statErr := os.Stat(filepath.Join(toolchain, "go", "bin", "go")); statErr != nil {
+				return string(output), statErr
+			}
+		}
+		return string(output), err
+	}
+
+	if output, err := run(); err != nil {
+		t.Fatalf("go install failed: %v\n%s", err, output)
+	}
+
+	writeArchive(t, tarball, map[string]string{"go/bin/go": "#!/bin/sh\necho evil\n"})
+	output, err := run()
+	if err == nil || !strings.Contains(output, "checksum mismatch") {
+		t.Errorf("tampered go tarball = %v\n%s", err, output)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+// Matches `use "path";` declarations
+var useDeclPattern = regexp.MustCompile(`(?m)^\s*use\s+"([^"]+)"\s*;`)
+
+// jsSource is a .r2d2 file found under one of the inputs of a tree build
+type jsSource struct {
+	path string // path on disk
+	rel  string // path mirrored under the output directory
+}
+
+// jsBuildResult reports what happened to one source of a tree build
+type jsBuildResult struct {
+	source   string
+	output   string
+	skipped  bool
+	duration time.Duration
+	err      error
+}
+
+// collectR2D2Sources finds every .r2d2 file of the inputs.
+// Files inside a directory keep their path relative to it, single files keep their name.
+func collectR2D2Sources(inputs []string) ([]jsSource, error) {
+	var sources []jsSource
+
+	for _, input := range inputs {
+		info, err := os.Stat(input)
+		if err != nil {
+			return nil, fmt.Errorf("file not found: %v", input)
+		}
+
+		if !info.IsDir() {
+			sources = append(sources, jsSource{path: input, rel: filepath.Base(input)})
+			continue
+		}
+
+		err = filepath.WalkDir(input, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || filepath.Ext(path) != ".r2d2" {
+				return nil
+			}
+
+			rel, err := filepath.Rel(input, path)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, jsSource{path: path, rel: rel})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sources, nil
+}
+
+// resolveUse returns the file a `use` path of source refers to, or "" when it isn't one of sources
+func resolveUse(source jsSource, usePath string, byPath map[string]jsSource) string {
+	candidates := []string{
+		filepath.Join(filepath.Dir(source.path), filepath.FromSlash(usePath)),
+		filepath.FromSlash(usePath),
+	}
+
+	for _, candidate := range candidates {
+		if _, ok := byPath[filepath.Clean(candidate)]; ok {
+			return filepath.Clean(candidate)
+		}
+	}
+	return ""
+}
+
+// libraryOnlySources returns the sources that are imported by another source and aren't entries
+func libraryOnlySources(sources []jsSource, entries []string) (map[string]bool, error) {
+	byPath := make(map[string]jsSource)
+	for _, source := range sources {
+		byPath[filepath.Clean(source.path)] = source
+	}
+
+	isEntry := make(map[string]bool)
+	for _, entry := range entries {
+		isEntry[filepath.Clean(entry)] = true
+	}
+
+	libraries := make(map[string]bool)
+	for _, source := range sources {
+		content, err := os.ReadFile(source.path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range useDeclPattern.FindAllStringSubmatch(string(content), -1) {
+			if imported := resolveUse(source, match[1], byPath); imported != "" && !isEntry[imported] {
+				libraries[imported] = true
+			}
+		}
+	}
+
+	return libraries, nil
+}
+
+// BuildJsTree transpiles every .r2d2 file of the inputs into outDir, mirroring the
+// directory structure. The compiler bundles the files a source uses into its output,
+// so with entries, files only imported as libraries are skipped.
+func BuildJsTree(inputs []string, outDir string, entries []string) ([]jsBuildResult, error) {
+	sources, err := collectR2D2Sources(inputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no .r2d2 files found in %s", strings.Join(inputs, ", "))
+	}
+
+	byPath := make(map[string]jsSource)
+	for _, source := range sources {
+		byPath[filepath.Clean(source.path)] = source
+	}
+	for _, entry := range entries {
+		if _, ok := byPath[filepath.Clean(entry)]; !ok {
+			return nil, fmt.Errorf("entry %s isn't one of the files of %s", entry, strings.Join(inputs, ", "))
+		}
+	}
+
+	libraries := map[string]bool{}
+	if len(entries) > 0 {
+		libraries, err = libraryOnlySources(sources, entries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]jsBuildResult, 0, len(sources))
+	for _, source := range sources {
+		output := filepath.Join(outDir, strings.TrimSuffix(source.rel, ".r2d2")+".js")
+		result := jsBuildResult{source: source.path, output: output}
+
+		if libraries[filepath.Clean(source.path)] {
+			result.skipped = true
+			results = append(results, result)
+			continue
+		}
+
+		start := time.Now()
+		result.err = buildJsSource(source, output)
+		result.duration = time.Since(start)
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// buildJsSource transpiles one source of a tree build to output. It is compiled in
+// its own directory, where its relative `use` paths point.
+func buildJsSource(source jsSource, output string) error {
+	code, err := os.ReadFile(source.path)
+	if err != nil {
+		return err
+	}
+
+	resolved, err := resolvePackageImports(string(code))
+	if err != nil {
+		return err
+	}
+	js, err := transpileJsIn(resolved, filepath.Dir(source.path))
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(output, []byte(js), 0644)
+}
+
+// Styles for the build summary (using colors from styles.go)
+var (
+	summaryHeaderStyle = lipgloss.NewStyle().
+				Foreground(highlightColor).
+				Bold(true).
+				Padding(0, 1)
+
+	summaryCellStyle = lipgloss.NewStyle().
+				Padding(0, 1)
+
+	summaryOkStyle = summaryCellStyle.
+			Foreground(specialColor)
+
+	summaryFailStyle = summaryCellStyle.
+				Foreground(errorColor)
+
+	summarySkipStyle = summaryCellStyle.
+				Foreground(subtleColor)
+)
+
+// jsBuildSummary renders the results of a tree build as a table
+func jsBuildSummary(results []jsBuildResult) string {
+	rows := make([][]string, 0, len(results))
+	built, failed, skipped := 0, 0, 0
+
+	for _, result := range results {
+		status := "ok"
+		detail := result.duration.Round(time.Millisecond).String()
+		switch {
+		case result.skipped:
+			status = "skipped"
+			detail = "library"
+			skipped++
+		case result.err != nil:
+			status = "failed"
+			detail = strings.SplitN(result.err.Error(), "\n", 2)[0]
+			failed++
+		default:
+			built++
+		}
+		rows = append(rows, []string{result.source, result.output, status, detail})
+	}
+
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(subtleColor)).
+		Headers("SOURCE", "OUTPUT", "STATUS", "DETAIL").
+		Rows(rows...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == table.HeaderRow {
+				return summaryHeaderStyle
+			}
+			if col != 2 {
+				return summaryCellStyle
+			}
+			switch rows[row][2] {
+			case "failed":
+				return summaryFailStyle
+			case "skipped":
+				return summarySkipStyle
+			}
+			return summaryOkStyle
+		})
+
+	totals := fmt.Sprintf("%d built, %d failed, %d skipped", built, failed, skipped)
+	return t.Render() + "\n" + statusMessageStyle.Render(totals)
+}
+
+// RunJsTree builds the inputs into outDir and prints the summary table.
+func RunJsTree(inputs []string, outDir string, entries []string) (err error) {
+	results, err := BuildJsTree(inputs, outDir, entries)
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+
+	fmt.Println(jsBuildSummary(results))
+
+	for _, result := range results {
+		if result.err != nil {
+			return fmt.Errorf("%d file(s) failed to compile", countFailed(results))
+		}
+	}
+	return nil
+}
+
+// countFailed returns how many sources of a tree build failed
+func countFailed(results []jsBuildResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.err != nil {
+			failed++
+		}
+	}
+	return failed
+}
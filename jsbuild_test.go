@@ -0,0 +1,193 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// writeTree creates the given files under a temporary directory and returns it
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("error writing %s: %v", name, err)
+		}
+	}
+	return root
+}
+
+// Test that sources keep their path relative to the input directory
+func TestCollectR2D2Sources(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.r2d2":      "module Main { export fn main() { } }",
+		"lib/util.r2d2":  "module Util { }",
+		"lib/notes.txt":  "not a source",
+		"deep/a/b.r2d2":  "module B { }",
+		"other/one.r2d2": "module One { }",
+	})
+
+	sources, err := collectR2D2Sources([]string{root})
+	if err != nil {
+		t.Fatalf("collectR2D2Sources() error = %v", err)
+	}
+
+	rels := make(map[string]bool)
+	for _, source := range sources {
+		rels[filepath.ToSlash(source.rel)] = true
+	}
+
+	for _, expected := range []string{"main.r2d2", "lib/util.r2d2", "deep/a/b.r2d2", "other/one.r2d2"} {
+		if !rels[expected] {
+			t.Errorf("collectR2D2Sources() should find %s, got %v", expected, rels)
+		}
+	}
+	if rels["lib/notes.txt"] {
+		t.Error("collectR2D2Sources() should ignore files that aren't .r2d2")
+	}
+
+	// A single file keeps only its name
+	sources, err = collectR2D2Sources([]string{filepath.Join(root, "lib", "util.r2d2")})
+	if err != nil || len(sources) != 1 || sources[0].rel != "util.r2d2" {
+		t.Errorf("collectR2D2Sources() for a file = %v, %v", sources, err)
+	}
+
+	if _, err := collectR2D2Sources([]string{filepath.Join(root, "missing")}); err == nil {
+		t.Error("collectR2D2Sources() should fail for a missing input")
+	}
+}
+
+// Test that imported files are only treated as libraries when they aren't entries
+func TestLibraryOnlySources(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.r2d2":     "use \"lib/util.r2d2\";\nmodule Main { export fn main() { } }",
+		"tool.r2d2":     "use \"lib/util.r2d2\";\nuse \"main.r2d2\";\nmodule Tool { export fn main() { } }",
+		"lib/util.r2d2": "module Util { }",
+	})
+
+	sources, err := collectR2D2Sources([]string{root})
+	if err != nil {
+		t.Fatalf("collectR2D2Sources() error = %v", err)
+	}
+
+	libraries, err := libraryOnlySources(sources, []string{filepath.Join(root, "main.r2d2"), filepath.Join(root, "tool.r2d2")})
+	if err != nil {
+		t.Fatalf("libraryOnlySources() error = %v", err)
+	}
+
+	if !libraries[filepath.Join(root, "lib", "util.r2d2")] {
+		t.Errorf("lib/util.r2d2 should be a library, got %v", libraries)
+	}
+	if libraries[filepath.Join(root, "main.r2d2")] {
+		t.Error("main.r2d2 is an entry and shouldn't be a library even if imported")
+	}
+}
+
+// Test that the summary table lists every file with its status
+func TestJsBuildSummary(t *testing.T) {
+	results := []jsBuildResult{
+		{source: "src/main.r2d2", output: "dist/main.js"},
+		{source: "src/lib.r2d2", output: "dist/lib.js", skipped: true},
+		{source: "src/bad.r2d2", output: "dist/bad.js", err: os.ErrInvalid},
+	}
+
+	summary := jsBuildSummary(results)
+	for _, expected := range []string{"src/main.r2d2", "dist/main.js", "ok", "skipped", "failed", "1 built, 1 failed, 1 skipped"} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("jsBuildSummary() should contain %q, got:\n%s", expected, summary)
+		}
+	}
+
+	if countFailed(results) != 1 {
+		t.Errorf("countFailed() = %d, want 1", countFailed(results))
+	}
+}
+
+// Test that a tree build mirrors the directory structure in the output directory
+func TestBuildJsTree(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.r2d2":     "module Main { export fn main() { console.log(\"main\"); } }",
+		"lib/util.r2d2": "module Util { export fn main() { console.log(\"util\"); } }",
+	})
+	outDir := filepath.Join(t.TempDir(), "dist")
+
+	results, err := BuildJsTree([]string{root}, outDir, nil)
+	if err != nil {
+		t.Fatalf("BuildJsTree() error = %v", err)
+	}
+
+	for _, result := range results {
+		if result.err != nil {
+			t.Errorf("BuildJsTree() failed for %s: %v", result.source, result.err)
+		}
+	}
+
+	for _, expected := range []string{"main.js", filepath.Join("lib", "util.js")} {
+		if _, err := os.Stat(filepath.Join(outDir, expected)); err != nil {
+			t.Errorf("BuildJsTree() should emit %s: %v", expected, err)
+		}
+	}
+}
+
+// Test that only the entries are emitted with --entry, and that entries must be inputs
+func TestBuildJsTreeEntries(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.r2d2":     "use \"lib/util.r2d2\";\nmodule Main { export fn main() { } }",
+		"lib/util.r2d2": "module Util { }",
+	})
+	outDir := filepath.Join(t.TempDir(), "dist")
+
+	results, err := BuildJsTree([]string{root}, outDir, []string{filepath.Join(root, "main.r2d2")})
+	if err != nil {
+		t.Fatalf("BuildJsTree() error = %v", err)
+	}
+	for _, result := range results {
+		if skipped := filepath.Base(result.source) == "util.r2d2"; result.skipped != skipped || result.err != nil {
+			t.Errorf("result for %s = %+v", result.source, result)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "lib", "util.js")); err == nil {
+		t.Error("BuildJsTree() shouldn't emit a library")
+	}
+
+	if _, err := BuildJsTree([]string{root}, outDir, []string{"elsewhere.r2d2"}); err == nil {
+		t.Error("BuildJsTree() should reject an entry outside the inputs")
+	}
+}
+
+// Matches imports of relative paths in generated JavaScript
+var relativeImportPattern = regexp.MustCompile(`(?m)(\bfrom\s*|\bimport\s*\(?\s*|\brequire\(\s*)["']\.{1,2}/`)
+
+// Test that the output of an entry holds the files it uses, since libraries aren't emitted
+// and nothing rewrites the imports to point at them
+func TestBuildJsTreeBundlesUse(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.r2d2":     "use \"lib/util.r2d2\";\nmodule Main { export fn main() { Util.shout(); } }",
+		"lib/util.r2d2": "module Util { export fn shout() { console.log(\"bundled from util\"); } }",
+	})
+	outDir := filepath.Join(t.TempDir(), "dist")
+
+	if _, err := BuildJsTree([]string{root}, outDir, []string{filepath.Join(root, "main.r2d2")}); err != nil {
+		t.Fatalf("BuildJsTree() error = %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(outDir, "main.js"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	js := string(content)
+	if !strings.Contains(js, "bundled from util") {
+		t.Errorf("main.js doesn't hold the code of lib/util.r2d2:\n%s", js)
+	}
+	if match := relativeImportPattern.FindString(js); match != "" {
+		t.Errorf("main.js imports a relative path (%s):\n%s", match, js)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Ends the output of one evaluation, after whatever the program printed on its last line
+const jsRuntimeDone = "\x00"
+
+// Starts the report of an uncaught JavaScript exception, which runs to the end of the line
+const jsRuntimeError = "\x01"
+
+// Driver script run by Deno. It reads one JSON-encoded program per line,
+// evaluates it in the global scope and prints a sentinel line afterwards.
+// let/const bindings stay local to each evaluation, so modules can be
+// emitted again without "already declared" errors, while globals persist.
+const jsRuntimeDriver = `const decoder = new TextDecoder();
+const encoder = new TextEncoder();
+let buffer = "";
+for await (const chunk of Deno.stdin.readable) {
+  buffer += decoder.decode(chunk, { stream: true });
+  let newline;
+  while ((newline = buffer.indexOf("\n")) >= 0) {
+    const line = buffer.slice(0, newline);
+    buffer = buffer.slice(newline + 1);
+    try {
+      await (0, eval)(JSON.parse(line));
+    } catch (e) {
+      console.log("\u0001" + String(e && e.message ? e.message : e).replaceAll("\n", " "));
+    }
+    await new Promise((resolve) => setTimeout(resolve, 0));
+    await Deno.stdout.write(encoder.encode("\u0000\n"));
+  }
+}
+`
+
+// jsRuntime is a persistent Deno process evaluating generated JavaScript
+type jsRuntime struct {
+	mu     sync.Mutex // held by an evaluation
+	procMu sync.Mutex // guards cmd, so a running evaluation can be killed
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	deno   string
+	dir    string
+}
+
+// newJSRuntime starts the Deno driver process
+func newJSRuntime() (*jsRuntime, error) {
+	deno, err := exec.LookPath("deno")
+	if err != nil {
+		return nil, fmt.Errorf("deno not found in PATH, it is needed to evaluate R2D2 code")
+	}
+
+	dir, err := os.MkdirTemp("", "r2d2-runtime-")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "driver.js"), []byte(jsRuntimeDriver), 0644); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	rt := &jsRuntime{deno: deno, dir: dir}
+	if err := rt.start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return rt, nil
+}
+
+// start runs a new driver process, the caller holding mu or being the only user
+func (rt *jsRuntime) start() error {
+	cmd := exec.Command(rt.deno, "run", "--allow-all", "--quiet", filepath.Join(rt.dir, "driver.js"))
+	cmd.Stderr = io.Discard
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start deno: %v", err)
+	}
+
+	rt.procMu.Lock()
+	rt.cmd = cmd
+	rt.procMu.Unlock()
+	rt.stdin, rt.stdout = stdin, bufio.NewReader(stdout)
+	return nil
+}
+
+// Restart kills the runtime, stopping an evaluation that doesn't end, and starts
+// a new one. Globals of the old runtime are gone.
+func (rt *jsRuntime) Restart() error {
+	// The running evaluation sees the output end and gives mu back
+	rt.procMu.Lock()
+	rt.cmd.Process.Kill()
+	rt.procMu.Unlock()
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.stdin.Close()
+	rt.cmd.Wait()
+	return rt.start()
+}
+
+// Eval runs a JavaScript program and returns what it printed.
+// An uncaught exception is returned as an error, along with the output printed before it.
+func (rt *jsRuntime) Eval(js string) (string, error) {
+	output, _, err := rt.evalIf(func() bool { return true }, js)
+	return output, err
+}
+
+// evalIf is Eval, unless start, called once the runtime is free, returns false.
+// A Restart coming after start can't miss the evaluation.
+func (rt *jsRuntime) evalIf(start func() bool, js string) (string, bool, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if !start() {
+		return "", false, nil
+	}
+	output, err := rt.eval(js)
+	return output, true, err
+}
+
+// eval runs a program, the caller holding mu
+func (rt *jsRuntime) eval(js string) (string, error) {
+
+	encoded, err := json.Marshal(js)
+	if err != nil {
+		return "", err
+	}
+	if _, err := rt.stdin.Write(append(encoded, '\n')); err != nil {
+		return "", fmt.Errorf("the JavaScript runtime stopped: %v", err)
+	}
+
+	var output strings.Builder
+	var evalErr error
+	for {
+		line, err := rt.stdout.ReadString('\n')
+		if err != nil {
+			return output.String(), fmt.Errorf("the JavaScript runtime stopped: %v", err)
+		}
+
+		// Output without a trailing newline runs into the markers, it's kept as is
+		line = strings.TrimSuffix(line, "\n")
+		if text, ok := strings.CutSuffix(line, jsRuntimeDone); ok {
+			output.WriteString(text)
+			break
+		}
+		if text, message, ok := strings.Cut(line, jsRuntimeError); ok {
+			output.WriteString(text)
+			evalErr = fmt.Errorf("%s", message)
+			continue
+		}
+		output.WriteString(line + "\n")
+	}
+
+	return output.String(), evalErr
+}
+
+// Close stops the runtime process and removes its files
+func (rt *jsRuntime) Close() error {
+	rt.stdin.Close()
+	err := rt.cmd.Wait()
+	os.RemoveAll(rt.dir)
+	return err
+}
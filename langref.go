@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Runnable programs of the language topics, one per topic
+//
+//go:embed examples/lang/*.r2d2
+var langExamples embed.FS
+
+// LangTopic is a page of the language reference, 'r2d2 help lang <topic>'
+type LangTopic struct {
+	name    string // also the name of its example in examples/lang
+	title   string
+	summary string
+	long    string // markdown shown above the example
+	seeAlso []string
+}
+
+// Implementing list.Item interface, like Command
+func (t LangTopic) Title() string       { return t.title }
+func (t LangTopic) Description() string { return t.summary }
+func (t LangTopic) FilterValue() string { return t.name + " " + t.title + " " + t.summary }
+
+// Topics of the language reference
+var langTopics = []LangTopic{
+	{
+		name:    "modules",
+		title:   "Modules",
+		summary: "Every program is made of modules holding variables and functions",
+		long: `A **module** groups variables and functions, and compiles into a
+JavaScript object. Members are private unless declared with
+**export**, and the exported ` + "`main`" + ` of a module is where the
+program starts.
+
+- ` + "`var`" + `, ` + "`let`" + ` and ` + "`const`" + ` declare the variables of a module
+- ` + "`Module.function()`" + ` calls the exported function of another module
+- ` + "`use \"file.r2d2\";`" + ` at the top brings in the modules of another file`,
+		seeAlso: []string{"interfaces", "pseudo"},
+	},
+	{
+		name:    "interfaces",
+		title:   "Interfaces",
+		summary: "Functions a module promises to define, with implements",
+		long: `An **interface** lists function signatures without bodies. A module
+declared with ` + "`implements`" + ` and the name of the interface has to define
+every one of them, with the same parameters.`,
+		seeAlso: []string{"modules", "types"},
+	},
+	{
+		name:    "pseudo",
+		title:   "Pseudo-functions",
+		summary: "Functions made only of calls to other functions",
+		long: `A **pseudo** function can only contain calls to other functions: no
+variables, conditions or loops. Behaviour is built by composing small
+functions, and the pseudo-function reads as the list of its steps.
+
+Declare it with ` + "`pseudo fn`" + `, after ` + "`export`" + ` when it's exported.`,
+		seeAlso: []string{"modules"},
+	},
+	{
+		name:    "js",
+		title:   "@js blocks",
+		summary: "JavaScript written inline, for what R2D2 has no syntax for",
+		long: `An ` + "`@js << ... >>;`" + ` statement copies its JavaScript into the output
+unchanged. The parameters and variables of the function are in scope,
+and a ` + "`return`" + ` inside the block returns from the function.
+
+It's how the std library reaches the runtime, and how programs use the
+DOM or JavaScript libraries.`,
+		seeAlso: []string{"types"},
+	},
+	{
+		name:    "arrow-if",
+		title:   "Arrow if",
+		summary: "if, else if and else with => and a single statement",
+		long: `Each branch of an ` + "`if`" + ` takes either a block or ` + "`=>`" + ` followed by
+one statement, which keeps short conditions on one line. The
+parentheses around conditions are optional.
+
+` + "```" + `
+if (done) => break;
+` + "```",
+		seeAlso: []string{"switch", "loops"},
+	},
+	{
+		name:    "loops",
+		title:   "Loops",
+		summary: "for, while and loop, with break and continue",
+		long: `- ` + "`for var i number = 0; i < n; i++ { }`" + ` counts, C style
+- ` + "`while condition { }`" + ` repeats while the condition holds
+- ` + "`loop { }`" + ` repeats until a ` + "`break`" + `
+
+` + "`continue`" + ` skips to the next turn of any of them.`,
+		seeAlso: []string{"arrow-if", "switch"},
+	},
+	{
+		name:    "switch",
+		title:   "Switch",
+		summary: "Picks the case matching a value, or default",
+		long: `A **switch** compares a value with each ` + "`case`" + ` and runs the one that
+matches, or ` + "`default`" + ` when none does. Like the branches of an if, a
+case takes a block or ` + "`=>`" + ` and one statement.`,
+		seeAlso: []string{"arrow-if", "loops"},
+	},
+	{
+		name:    "types",
+		title:   "Types",
+		summary: "number, string, boolean, array, object and arrays of a type",
+		long: `Types are written after the name of a variable or parameter, and
+after the parameter list for what a function returns. They're optional.
+
+- built-in: ` + "`number`" + `, ` + "`string`" + `, ` + "`boolean`" + `, ` + "`array`" + `, ` + "`object`" + `, ` + "`void`" + `
+- ` + "`[]number`" + ` is an array of numbers`,
+		seeAlso: []string{"modules", "interfaces"},
+	},
+}
+
+// findLangTopic returns the topic called name
+func findLangTopic(name string) (LangTopic, bool) {
+	for _, topic := range langTopics {
+		if topic.name == name {
+			return topic, true
+		}
+	}
+	return LangTopic{}, false
+}
+
+// langTopicNames lists the names of every topic
+func langTopicNames() []string {
+	names := make([]string, len(langTopics))
+	for i, topic := range langTopics {
+		names[i] = topic.name
+	}
+	return names
+}
+
+// examplePath is where the example of the topic lives in the repository
+func (t LangTopic) examplePath() string {
+	return "examples/lang/" + t.name + ".r2d2"
+}
+
+// example returns the runnable program of the topic
+func (t LangTopic) example() string {
+	content, err := langExamples.ReadFile(t.examplePath())
+	if err != nil {
+		return ""
+	}
+	return string(content)
+}
+
+// renderLangTopic renders the page of a topic, wrapped to width: the
+// explanation, then the highlighted example
+func renderLangTopic(topic LangTopic, width int) string {
+	var sb strings.Builder
+	section := func(title string) {
+		sb.WriteString("\n" + sectionStyle.Render(title) + "\n")
+	}
+
+	sb.WriteString(lipgloss.NewStyle().Foreground(highlightColor).Bold(true).Render(topic.title))
+	sb.WriteString(" " + categoryStyle.Render("language") + "\n")
+	sb.WriteString(renderLines(lipgloss.NewStyle().Foreground(infoColor), wrapText(topic.summary, width)) + "\n")
+
+	section("Description")
+	sb.WriteString(renderMarkdown(topic.long, width) + "\n")
+
+	section("Example")
+	sb.WriteString(categoryStyle.Render(topic.examplePath()) + "\n\n")
+	sb.WriteString(highlightR2D2(strings.TrimRight(topic.example(), "\n")) + "\n")
+
+	if len(topic.seeAlso) > 0 {
+		section("See also")
+		entries := make([]string, len(topic.seeAlso))
+		for i, name := range topic.seeAlso {
+			entries[i] = seeAlsoStyle.Render(name)
+		}
+		sb.WriteString("  " + strings.Join(entries, "  ") + "\n")
+	}
+
+	return sb.String()
+}
+
+// writeExample copies the example of a topic into a new file in dir, named
+// after the topic, without overwriting anything. It returns the file's path.
+func writeExample(topic LangTopic, dir string) (string, error) {
+	for i := 1; ; i++ {
+		name := topic.name + ".r2d2"
+		if i > 1 {
+			name = fmt.Sprintf("%s-%d.r2d2", topic.name, i)
+		}
+		path := filepath.Join(dir, name)
+
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = file.WriteString(topic.example())
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return path, err
+	}
+}
+
+// exampleRun runs the example of a topic through Run while the help TUI has
+// left the terminal to it, then waits for enter before going back
+type exampleRun struct {
+	topic  LangTopic
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+func (e *exampleRun) SetStdin(r io.Reader)  { e.stdin = r }
+func (e *exampleRun) SetStdout(w io.Writer) { e.stdout = w }
+func (e *exampleRun) SetStderr(w io.Writer) {}
+
+func (e *exampleRun) Run() error {
+	fmt.Fprintln(e.stdout, InfoMessage("Running "+e.topic.examplePath()))
+	err := Run(e.topic.example())
+
+	fmt.Fprintln(e.stdout)
+	fmt.Fprint(e.stdout, HelpMessage("Press enter to go back to the help"))
+	bufio.NewReader(e.stdin).ReadString('\n')
+	return err
+}
+
+// exampleRunMsg reports the end of an exampleRun
+type exampleRunMsg struct {
+	topic LangTopic
+	err   error
+}
+
+// ShowLangTopic prints the page of one topic, what 'r2d2 help lang <topic>' shows
+func ShowLangTopic(topic LangTopic) {
+	fmt.Print(renderLangTopic(topic, helpPageWidth()))
+}
+
+// ShowLangTopics lists the topics, what 'r2d2 help lang' prints when piped
+func ShowLangTopics() {
+	fmt.Println(headingStyle.Render("R2D2 Language"))
+	fmt.Println()
+	for _, topic := range langTopics {
+		fmt.Printf("  %s - %s\n", highlightedCmdStyle.Render(fmt.Sprintf("%-10s", topic.name)), topic.summary)
+	}
+	fmt.Println()
+	fmt.Println(statusMessageStyle.Render("Read one with: r2d2 help lang <topic>"))
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestLangTopics(t *testing.T) {
+	for _, topic := range langTopics {
+		t.Run(topic.name, func(t *testing.T) {
+			if topic.title == "" || topic.summary == "" || topic.long == "" {
+				t.Error("topic needs a title, a summary and a description")
+			}
+			example := topic.example()
+			if !strings.Contains(example, "export fn main()") {
+				t.Errorf("%s isn't a runnable program:\n%s", topic.examplePath(), example)
+			}
+			// The TUI writes the example on its own before running it, so it compiles alone
+			if _, err := transpileJsIn(example, t.TempDir()); err != nil {
+				t.Errorf("%s doesn't compile: %v", topic.examplePath(), err)
+			}
+			for _, name := range topic.seeAlso {
+				if _, ok := findLangTopic(name); !ok || name == topic.name {
+					t.Errorf("see also %q isn't another topic", name)
+				}
+			}
+		})
+	}
+
+	// Every example belongs to a topic
+	files, _ := filepath.Glob("examples/lang/*.r2d2")
+	for _, file := range files {
+		if _, ok := findLangTopic(strings.TrimSuffix(filepath.Base(file), ".r2d2")); !ok {
+			t.Errorf("%s has no topic", file)
+		}
+	}
+}
+
+func TestRenderLangTopic(t *testing.T) {
+	topic, _ := findLangTopic("arrow-if")
+	got := renderLangTopic(topic, 80)
+
+	text := strings.Join(strings.Fields(got), " ")
+	for _, want := range []string{"Arrow if", "Description", "Example", "examples/lang/arrow-if.r2d2", `else => console.log(x, "is zero");`, "See also"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("page is missing %q", want)
+		}
+	}
+	for _, line := range strings.Split(got, "\n") {
+		if lipgloss.Width(line) > 80 {
+			t.Errorf("line %q is wider than 80", line)
+		}
+	}
+}
+
+func TestWriteExample(t *testing.T) {
+	dir := t.TempDir()
+	topic, _ := findLangTopic("loops")
+
+	// A second copy doesn't overwrite the first
+	for _, want := range []string{"loops.r2d2", "loops-2.r2d2"} {
+		path, err := writeExample(topic, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(path) != want {
+			t.Errorf("writeExample() = %s, want %s", path, want)
+		}
+		if content, _ := os.ReadFile(path); string(content) != topic.example() {
+			t.Errorf("%s doesn't hold the example", path)
+		}
+	}
+}
+
+func TestHelpModelLanguageTab(t *testing.T) {
+	t.Chdir(t.TempDir())
+	m := newHelpModel(helpTabCommands, 80, 24)
+
+	key := func(k string) {
+		var msg tea.KeyMsg
+		switch k {
+		case "tab":
+			msg = tea.KeyMsg{Type: tea.KeyTab}
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		model, _ := m.Update(msg)
+		m = model.(HelpModel)
+	}
+
+	key("tab")
+	if m.tab != helpTabLanguage {
+		t.Fatal("tab didn't switch to the language tab")
+	}
+
+	// Enter opens the first topic
+	modules := langTopics[0]
+	key("enter")
+	if !m.detailed || m.topic.name != modules.name {
+		t.Fatalf("enter opened %q, want %q", m.topic.name, modules.name)
+	}
+
+	// w writes the example into the working directory
+	key("w")
+	if _, err := os.Stat("modules.r2d2"); err != nil {
+		t.Errorf("w didn't write modules.r2d2: %v", err)
+	}
+	if !strings.Contains(m.status, "modules.r2d2") {
+		t.Errorf("status = %q", m.status)
+	}
+
+	// r hands the terminal to the example
+	model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m = model.(HelpModel)
+	if cmd == nil {
+		t.Error("r didn't run the example")
+	}
+
+	// A number opens a "see also" topic, esc goes back to the list
+	key("1")
+	if m.topic.name != modules.seeAlso[0] {
+		t.Errorf("1 opened %q, want %q", m.topic.name, modules.seeAlso[0])
+	}
+	key("esc")
+	if m.detailed || m.tab != helpTabLanguage {
+		t.Error("esc didn't go back to the language list")
+	}
+
+	key("tab")
+	if m.tab != helpTabCommands {
+		t.Error("tab didn't switch back to the commands")
+	}
+}
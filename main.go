@@ -3,12 +3,21 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/ArturC03/r2d2Styles"
 )
 
+// File name meaning the code is read from stdin
+const stdinPath = "-"
+
+// Checks if the code should be read from stdin
+func readsStdin() bool {
+	return len(os.Args) > 2 && os.Args[2] == stdinPath
+}
+
 // Gets the filename from the command line arguments
 func getFilename() string {
 
@@ -28,6 +37,11 @@ func getFilename() string {
 		os.Exit(1)
 	}
 
+	// Code from stdin has no name of its own
+	if filePath == stdinPath {
+		return "stdin.r2d2"
+	}
+
 	// Extract just the filename from the path
 	parts := strings.Split(filePath, string(os.PathSeparator))
 	filename := parts[len(parts)-1]
@@ -54,6 +68,17 @@ func readR2D2File() string {
 		os.Exit(1)
 	}
 
+	// Reads the code from stdin
+	if filePath == stdinPath {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Println(r2d2Styles.ErrorMessage(fmt.Sprintf("Error reading stdin: %v", err)))
+			os.Exit(1)
+		}
+		currentSource = string(content)
+		return currentSource
+	}
+
 	// Check if the file exists
 	_, err := os.Stat(filePath)
 	if os.IsNotExist(err) {
@@ -69,7 +94,8 @@ func readR2D2File() string {
 	}
 
 	// Converts the contents to a string
-	return string(content)
+	currentSource = string(content)
+	return currentSource
 }
 
 // Checks for -o flag and returns the output filename
@@ -102,9 +128,55 @@ func removeOutputFlag() {
 	os.Args = newArgs
 }
 
+// Takes every value of a long flag (--name value or --name=value) out of os.Args.
+// Comma-separated values are split.
+func takeFlagValues(name string) []string {
+	var values []string
+	var newArgs []string
+
+	for i := 0; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		switch {
+		case arg == name && i+1 < len(os.Args):
+			values = append(values, strings.Split(os.Args[i+1], ",")...)
+			i++ // Skip the flag value
+		case strings.HasPrefix(arg, name+"="):
+			values = append(values, strings.Split(strings.TrimPrefix(arg, name+"="), ",")...)
+		default:
+			newArgs = append(newArgs, arg)
+		}
+	}
+
+	os.Args = newArgs
+	return values
+}
+
+// Checks if the command got a directory or several files to compile
+func hasTreeInput() bool {
+	if len(os.Args) > 3 {
+		return true
+	}
+	if len(os.Args) == 3 {
+		info, err := os.Stat(os.Args[2])
+		return err == nil && info.IsDir()
+	}
+	return false
+}
+
+// Checks if the command was asked for its page with --help or -h
+func wantsHelp() bool {
+	for _, arg := range os.Args[2:] {
+		if arg == "--help" || arg == "-h" {
+			return true
+		}
+	}
+	return false
+}
+
 var commandLine = strings.Join(os.Args[1:], " ")
 
 func main() {
+	defer recoverInternalError()
 
 	var err error = nil
 	if len(os.Args) < 2 {
@@ -112,6 +184,18 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Every command shows its page with --help, and stops at a flag it doesn't take
+	if page, ok := findCommand(os.Args[1]); ok {
+		if wantsHelp() {
+			ShowCommandHelp(page)
+			return
+		}
+		if index := unknownFlag(page, os.Args); index >= 0 {
+			UnknownFlag(page, index)
+			os.Exit(1)
+		}
+	}
+
 	// Parse -o flag before processing other arguments
 	outputFile, hasOutput := parseOutputFlag()
 	if hasOutput {
@@ -119,17 +203,70 @@ func main() {
 	}
 
 	cmd := os.Args[1]
+
 	switch cmd {
 	case "-help", "-h", "--help", "help", "--h":
+		manDirs, markdownDirs := takeFlagValues("--man"), takeFlagValues("--markdown")
+
 		// Check if there's a sub-argument for help
-		if len(os.Args) > 2 && os.Args[2] == "static" {
+		switch {
+		case len(manDirs) > 0:
+			err = RunHelpDocs(HelpFormatMan, manDirs[0])
+		case len(markdownDirs) > 0:
+			err = RunHelpDocs(HelpFormatMarkdown, markdownDirs[0])
+		case len(os.Args) > 2 && (os.Args[2] == "--man" || os.Args[2] == "--markdown"):
+			fmt.Println(ErrorMessage(os.Args[2] + " needs the directory to write the pages to"))
+			fmt.Println(InfoMessage("Use: r2d2 help " + os.Args[2] + " <dir>"))
+			os.Exit(1)
+		case len(os.Args) > 2 && os.Args[2] == "static":
 			ShowHelpStatic()
-		} else {
+		case len(os.Args) > 3 && os.Args[2] == "lang":
+			topic, ok := findLangTopic(os.Args[3])
+			if !ok {
+				fmt.Println(ErrorMessage("Unknown language topic: " + os.Args[3]))
+				fmt.Println(markArgument(os.Args, 3))
+				if s := suggest(os.Args[3], langTopicNames()); s != "" {
+					fmt.Println(HelpMessage("Did you mean '" + s + "'?"))
+				}
+				fmt.Println(HelpMessage("Topics: " + strings.Join(langTopicNames(), ", ")))
+				os.Exit(1)
+			}
+			ShowLangTopic(topic)
+		case len(os.Args) > 2 && os.Args[2] == "lang":
+			ShowLangHelp()
+		case len(os.Args) > 2:
+			page, ok := findCommand(os.Args[2])
+			if !ok {
+				UnknownCommand(os.Args[2], 2)
+				os.Exit(1)
+			}
+			ShowCommandHelp(page)
+		default:
 			ShowHelp()
 		}
+		if err != nil {
+			os.Exit(1)
+		}
 
 	case "-version", "-v", "--version", "version", "--v":
-		ShowVersion()
+		verbose, asJSON := false, false
+		for _, arg := range os.Args[2:] {
+			switch arg {
+			case "--verbose", "-verbose":
+				verbose = true
+			case "--json":
+				asJSON = true
+			}
+		}
+
+		if !verbose && !asJSON {
+			ShowVersion()
+			break
+		}
+		err = ShowVersionVerbose(asJSON)
+		if err != nil {
+			os.Exit(1)
+		}
 
 	case "-b", "build":
 		if hasOutput {
@@ -151,6 +288,29 @@ func main() {
 		}
 
 	case "js":
+		outDirs := takeFlagValues("--outdir")
+		entries := takeFlagValues("--entry")
+
+		if len(entries) > 0 && !hasTreeInput() {
+			fmt.Println(ErrorMessage("--entry needs a directory or several files to pick the entries from"))
+			fmt.Println(InfoMessage("Use: r2d2 js <dir...> --outdir <dir> --entry <file.r2d2>"))
+			os.Exit(1)
+		}
+
+		// Directories and multiple files are compiled into an output tree
+		if len(outDirs) > 0 || hasTreeInput() {
+			outDir := "dist"
+			if len(outDirs) > 0 {
+				outDir = outDirs[0]
+			}
+
+			err = RunJsTree(os.Args[2:], outDir, entries)
+			if err != nil {
+				os.Exit(1)
+			}
+			break
+		}
+
 		if hasOutput {
 			err = BuildJs(readR2D2File(), outputFile)
 			if err != nil {
@@ -159,13 +319,214 @@ func main() {
 			break
 		}
 
+		// Code from stdin goes back to stdout, so the CLI can be used in pipelines
+		if readsStdin() {
+			err = WriteJs(readR2D2File(), os.Stdout)
+			if err != nil {
+				os.Exit(1)
+			}
+			break
+		}
+
 		err = BuildJs(readR2D2File(), getFilename())
 		if err != nil {
 			os.Exit(1)
 		}
+	case "check":
+		err = Check(readR2D2File())
+		if err != nil {
+			os.Exit(1)
+		}
+		fmt.Println(InfoMessage("No errors found"))
+
+	case "eval":
+		if len(os.Args) < 3 {
+			fmt.Println(ErrorMessage("Unsufficient number of arguments"))
+			fmt.Println(InfoMessage("Use: r2d2 eval '<code>'"))
+			os.Exit(1)
+		}
+
+		code := strings.Join(os.Args[2:], " ")
+		if readsStdin() {
+			code = readR2D2File()
+		}
+
+		err = Eval(code)
+		if err != nil {
+			os.Exit(1)
+		}
+
+	case "doc":
+		format := DocFormatMarkdown
+		if formats := takeFlagValues("--format"); len(formats) > 0 {
+			format = formats[0]
+		}
+		outDir := "docs/api"
+		if outs := takeFlagValues("--out"); len(outs) > 0 {
+			outDir = outs[0]
+		}
+
+		err = RunDoc(os.Args[2:], format, outDir)
+		if err != nil {
+			os.Exit(1)
+		}
+
+	case "repl":
+		err = Repl()
+		if err != nil {
+			os.Exit(1)
+		}
+
+	case "add":
+		names := takeFlagValues("--name")
+		if len(os.Args) < 3 {
+			fmt.Println(ErrorMessage("Unsufficient number of arguments"))
+			fmt.Println(InfoMessage("Use: r2d2 add <source>[@version] [--name <name>]"))
+			os.Exit(1)
+		}
+
+		name := ""
+		if len(names) > 0 {
+			name = names[0]
+		}
+
+		err = RunAdd(os.Args[2], name)
+		if err != nil {
+			os.Exit(1)
+		}
+
+	case "remove":
+		if len(os.Args) < 3 {
+			fmt.Println(ErrorMessage("Unsufficient number of arguments"))
+			fmt.Println(InfoMessage("Use: r2d2 remove <name>"))
+			os.Exit(1)
+		}
+
+		err = RunRemove(os.Args[2])
+		if err != nil {
+			os.Exit(1)
+		}
+
+	case "install":
+		err = RunInstall()
+		if err != nil {
+			os.Exit(1)
+		}
+
+	case "vendor":
+		verify := false
+		for _, arg := range os.Args[2:] {
+			if arg == "--verify" {
+				verify = true
+			}
+		}
+
+		err = RunVendor(verify)
+		if err != nil {
+			os.Exit(1)
+		}
+
+	case "doctor":
+		asJSON := false
+		for _, arg := range os.Args[2:] {
+			if arg == "--json" {
+				asJSON = true
+			}
+		}
+
+		err = Doctor(asJSON)
+		if err != nil {
+			os.Exit(1)
+		}
+
+	case "upgrade":
+		version := ""
+		if versions := takeFlagValues("--version"); len(versions) > 0 {
+			version = versions[0]
+		}
+		source := ""
+		if sources := takeFlagValues("--source"); len(sources) > 0 {
+			source = sources[0]
+		}
+		check := false
+		for _, arg := range os.Args[2:] {
+			if arg == "--check" {
+				check = true
+			}
+		}
+
+		err = Upgrade(version, check, source)
+		if err != nil {
+			os.Exit(1)
+		}
+
+	case "self":
+		action := ""
+		if len(os.Args) > 2 {
+			action = os.Args[2]
+		}
+
+		switch action {
+		case "uninstall":
+			dryRun := false
+			for _, arg := range os.Args[3:] {
+				if arg == "--dry-run" {
+					dryRun = true
+				}
+			}
+			err = SelfUninstall(dryRun)
+		case "repair":
+			source := ""
+			if sources := takeFlagValues("--source"); len(sources) > 0 {
+				source = sources[0]
+			}
+			err = SelfRepair(source)
+		default:
+			fmt.Println(ErrorMessage("Unknown self command: " + action))
+			if action != "" {
+				fmt.Println(markArgument(os.Args, 2))
+			}
+			if s := suggest(action, []string{"uninstall", "repair"}); s != "" {
+				fmt.Println(HelpMessage("Did you mean '" + s + "'?"))
+			}
+			fmt.Println(InfoMessage("Use: r2d2 self uninstall [--dry-run] | r2d2 self repair [--source <url>]"))
+			os.Exit(1)
+		}
+		if err != nil {
+			os.Exit(1)
+		}
+
+	case "completion":
+		shell := ""
+		if len(os.Args) > 2 {
+			shell = os.Args[2]
+		}
+
+		err = Completion(shell)
+		if err != nil {
+			os.Exit(1)
+		}
+
+	case "__complete":
+		// Values the completion scripts ask for, not meant to be run by hand
+		if len(os.Args) > 2 {
+			PrintCompletionValues(os.Args[2])
+		}
+
+	case "__transpile":
+		// Compiles stdin for the REPL, not meant to be run by hand
+		dir := ""
+		if len(os.Args) > 2 {
+			dir = os.Args[2]
+		}
+		if err = PrintTranspiled(dir); err != nil {
+			os.Exit(1)
+		}
+
 	case "new":
 		// MakeProject() - je nes se'est pas
 	default:
 		UnknownCommand(cmd, 1)
+		os.Exit(1)
 	}
 }
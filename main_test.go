@@ -35,6 +35,12 @@ func TestGetFilename(t *testing.T) {
 			expected:   "app.r2d2",
 			shouldExit: false,
 		},
+		{
+			name:       "Code from stdin",
+			args:       []string{"r2d2", "build", "-"},
+			expected:   "stdin.r2d2",
+			shouldExit: false,
+		},
 		{
 			name:       "Insufficient arguments",
 			args:       []string{"r2d2", "build"},
@@ -72,6 +78,37 @@ func TestGetFilename(t *testing.T) {
 	os.Args = originalArgs
 }
 
+// Test that "-" reads the code from stdin
+func TestReadR2D2FileStdin(t *testing.T) {
+	originalArgs := os.Args
+	originalStdin := os.Stdin
+	defer func() {
+		os.Args = originalArgs
+		os.Stdin = originalStdin
+	}()
+
+	code := "module Test { export fn main() { console.log(\"Hello\"); } }"
+
+	r, w, _ := os.Pipe()
+	w.Write([]byte(code))
+	w.Close()
+	os.Stdin = r
+
+	os.Args = []string{"r2d2", "run", "-"}
+	if !readsStdin() {
+		t.Fatal("readsStdin() should be true for '-'")
+	}
+
+	if result := readR2D2File(); result != code {
+		t.Errorf("readR2D2File() = %q, want %q", result, code)
+	}
+
+	os.Args = []string{"r2d2", "run", "hello.r2d2"}
+	if readsStdin() {
+		t.Error("readsStdin() should be false for a file path")
+	}
+}
+
 func TestParseOutputFlag(t *testing.T) {
 	// Save original args
 	originalArgs := os.Args
@@ -132,6 +169,28 @@ func TestParseOutputFlag(t *testing.T) {
 	os.Args = originalArgs
 }
 
+func TestTakeFlagValues(t *testing.T) {
+	originalArgs := os.Args
+	defer func() { os.Args = originalArgs }()
+
+	os.Args = []string{"r2d2", "js", "src", "--outdir", "dist", "--entry=a.r2d2,b.r2d2", "--entry", "c.r2d2"}
+
+	outDirs := takeFlagValues("--outdir")
+	if len(outDirs) != 1 || outDirs[0] != "dist" {
+		t.Errorf("takeFlagValues(--outdir) = %v, want [dist]", outDirs)
+	}
+
+	entries := takeFlagValues("--entry")
+	expected := []string{"a.r2d2", "b.r2d2", "c.r2d2"}
+	if strings.Join(entries, " ") != strings.Join(expected, " ") {
+		t.Errorf("takeFlagValues(--entry) = %v, want %v", entries, expected)
+	}
+
+	if strings.Join(os.Args, " ") != "r2d2 js src" {
+		t.Errorf("flags should be removed from os.Args, got %v", os.Args)
+	}
+}
+
 func TestRemoveOutputFlag(t *testing.T) {
 	// Save original args
 	originalArgs := os.Args
@@ -0,0 +1,736 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Files of an R2D2 project
+const (
+	manifestFile = "r2d2.json"
+	lockFile     = "r2d2.lock"
+)
+
+// Kinds of dependency sources
+const (
+	SourceGit     = "git"
+	SourceTarball = "tarball"
+	SourcePath    = "path"
+)
+
+// Manifest is the r2d2.json file at the root of a project
+type Manifest struct {
+	Name         string            `json:"name,omitempty"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+}
+
+// LockedPackage is a dependency resolved to an exact commit and content hash
+type LockedPackage struct {
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Version string `json:"version,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+	Hash    string `json:"hash"`
+}
+
+// Lockfile is the r2d2.lock file next to the manifest
+type Lockfile struct {
+	Version  int             `json:"version"`
+	Packages []LockedPackage `json:"packages"`
+}
+
+// Find returns the locked package with the given name, or nil
+func (l *Lockfile) Find(name string) *LockedPackage {
+	for i := range l.Packages {
+		if l.Packages[i].Name == name {
+			return &l.Packages[i]
+		}
+	}
+	return nil
+}
+
+// set adds or replaces a locked package, keeping them sorted by name
+func (l *Lockfile) set(pkg LockedPackage) {
+	if existing := l.Find(pkg.Name); existing != nil {
+		*existing = pkg
+		return
+	}
+	l.Packages = append(l.Packages, pkg)
+	sort.Slice(l.Packages, func(i, j int) bool {
+		return l.Packages[i].Name < l.Packages[j].Name
+	})
+}
+
+// remove drops a locked package
+func (l *Lockfile) remove(name string) {
+	for i := range l.Packages {
+		if l.Packages[i].Name == name {
+			l.Packages = append(l.Packages[:i], l.Packages[i+1:]...)
+			return
+		}
+	}
+}
+
+// findProjectRoot walks up from dir looking for r2d2.json
+func findProjectRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, manifestFile)); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("%s not found, run this inside an R2D2 project", manifestFile)
+		}
+		dir = parent
+	}
+}
+
+// loadManifest reads the manifest of a project root, an absent file gives an empty manifest
+func loadManifest(root string) (*Manifest, error) {
+	manifest := &Manifest{Dependencies: map[string]string{}}
+
+	content, err := os.ReadFile(filepath.Join(root, manifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", manifestFile, err)
+	}
+	if manifest.Dependencies == nil {
+		manifest.Dependencies = map[string]string{}
+	}
+	for name := range manifest.Dependencies {
+		if err := checkPackageName(name); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", manifestFile, err)
+		}
+	}
+	return manifest, nil
+}
+
+// loadLockfile reads the lockfile of a project root, an absent file gives an empty lockfile
+func loadLockfile(root string) (*Lockfile, error) {
+	lock := &Lockfile{Version: 1}
+
+	content, err := os.ReadFile(filepath.Join(root, lockFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, lock); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", lockFile, err)
+	}
+	if err := lock.checkNames(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", lockFile, err)
+	}
+	return lock, nil
+}
+
+// checkPackageName rejects names that can't be a directory of the cache and vendor/,
+// so a name like ../x can't point the cache or vendor/ outside of them
+func checkPackageName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return fmt.Errorf("invalid package name %q, it can't be empty or contain /, \\ or ..", name)
+	}
+	return nil
+}
+
+// checkNames checks the name of every locked package
+func (l *Lockfile) checkNames() error {
+	for _, pkg := range l.Packages {
+		if err := checkPackageName(pkg.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeJSONFile writes v as indented JSON
+func writeJSONFile(path string, v any) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0644)
+}
+
+// packageCacheDir returns the local module cache, R2D2_CACHE overrides the default
+func packageCacheDir() (string, error) {
+	if dir := os.Getenv("R2D2_CACHE"); dir != "" {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".r2d2", "cache"), nil
+}
+
+// packageDir returns where a locked package lives in the cache
+func packageDir(cacheDir string, pkg LockedPackage) string {
+	id := pkg.Commit
+	if id == "" {
+		id = strings.TrimPrefix(pkg.Hash, "sha256:")
+		if len(id) > 16 {
+			id = id[:16]
+		}
+	}
+	return filepath.Join(cacheDir, pkg.Name, id)
+}
+
+// parsePackageSpec splits "source@version", ignoring the @ of scp-like git URLs
+func parsePackageSpec(spec string) (string, string) {
+	at := strings.LastIndex(spec, "@")
+	if at > 0 && at > strings.LastIndexAny(spec, "/:") {
+		return spec[:at], spec[at+1:]
+	}
+	return spec, ""
+}
+
+// packageSourceKind tells git repositories, tarballs and local paths apart
+func packageSourceKind(source string) string {
+	lower := strings.ToLower(source)
+	isURL := strings.Contains(lower, "://") || strings.HasPrefix(lower, "git@")
+
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz"):
+		return SourceTarball
+	case strings.HasPrefix(lower, "git+") || strings.HasSuffix(lower, ".git") || isURL:
+		return SourceGit
+	default:
+		return SourcePath
+	}
+}
+
+// packageName derives a package name from its source
+func packageName(source string) string {
+	name := strings.TrimRight(filepath.ToSlash(source), "/")
+	if idx := strings.LastIndexAny(name, "/:"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	for _, suffix := range []string{".git", ".tar.gz", ".tgz"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	return name
+}
+
+// hashPackageDir hashes every file of a package by path and content, skipping .git
+func hashPackageDir(dir string) (string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, file := range files {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return "", err
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		fileHash := sha256.Sum256(content)
+		fmt.Fprintf(h, "%s  %s\n", hex.EncodeToString(fileHash[:]), filepath.ToSlash(rel))
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// copyDir copies a directory tree, skipping .git
+func copyDir(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0644)
+	})
+}
+
+// runGit runs a git command, returning its trimmed output
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %v\n%s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// fetchGit clones source at ref (a tag, branch or commit) into dir and returns the commit
+func fetchGit(source string, ref string, dir string) (string, error) {
+	url := strings.TrimPrefix(source, "git+")
+	if _, err := runGit("", "clone", "--quiet", url, dir); err != nil {
+		return "", err
+	}
+	if ref != "" {
+		if _, err := runGit(dir, "checkout", "--quiet", ref); err != nil {
+			return "", err
+		}
+	}
+	commit, err := runGit(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return commit, os.RemoveAll(filepath.Join(dir, ".git"))
+}
+
+// openURL opens an http(s) or file:// URL for reading
+func openURL(url string) (io.ReadCloser, error) {
+	if path, ok := strings.CutPrefix(url, "file://"); ok {
+		return os.Open(path)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// fetchTarball downloads and extracts a .tar.gz into dir.
+// A single top-level directory, as produced by most release tools, is stripped.
+func fetchTarball(url string, dir string) error {
+	body, err := openURL(url)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := extractTarGz(body, dir); err != nil {
+		return fmt.Errorf("extracting %s: %v", url, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		inner := filepath.Join(dir, entries[0].Name())
+		tmp := dir + ".strip"
+		if err := os.Rename(inner, tmp); err != nil {
+			return err
+		}
+		if err := os.Remove(dir); err != nil {
+			return err
+		}
+		return os.Rename(tmp, dir)
+	}
+	return nil
+}
+
+// extractTarGz extracts regular files and directories of a gzipped tar stream into dir
+func extractTarGz(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&0755|0644)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(file, tr); err != nil {
+				file.Close()
+				return err
+			}
+			file.Close()
+		}
+	}
+}
+
+// fetchPackage downloads a dependency into the cache and returns its lock entry.
+// With a locked entry, the locked commit is fetched and its hash verified.
+// Local paths are under the control of the project, so they are always copied again.
+func fetchPackage(root string, cacheDir string, name string, spec string, locked *LockedPackage) (LockedPackage, error) {
+	source, version := parsePackageSpec(spec)
+	pkg := LockedPackage{Name: name, Source: source, Version: version}
+
+	if locked != nil && (locked.Source != source || locked.Version != version || packageSourceKind(source) == SourcePath) {
+		locked = nil
+	}
+
+	// Already in the cache with the locked content
+	if locked != nil {
+		if hash, err := hashPackageDir(packageDir(cacheDir, *locked)); err == nil && hash == locked.Hash {
+			return *locked, nil
+		}
+	}
+
+	staging, err := os.MkdirTemp("", "r2d2-pkg-")
+	if err != nil {
+		return pkg, err
+	}
+	defer os.RemoveAll(staging)
+	dir := filepath.Join(staging, name)
+
+	switch packageSourceKind(source) {
+	case SourceGit:
+		ref := version
+		if locked != nil {
+			ref = locked.Commit
+		}
+		if pkg.Commit, err = fetchGit(source, ref, dir); err != nil {
+			return pkg, err
+		}
+
+	case SourceTarball:
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return pkg, err
+		}
+		if err := fetchTarball(source, dir); err != nil {
+			return pkg, err
+		}
+
+	case SourcePath:
+		path := source
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			return pkg, fmt.Errorf("local package %s is not a directory", source)
+		}
+		if err := copyDir(path, dir); err != nil {
+			return pkg, err
+		}
+	}
+
+	if pkg.Hash, err = hashPackageDir(dir); err != nil {
+		return pkg, err
+	}
+
+	if locked != nil && locked.Hash != pkg.Hash {
+		return pkg, fmt.Errorf("%s: content hash %s doesn't match %s in %s", name, pkg.Hash, locked.Hash, lockFile)
+	}
+
+	target := packageDir(cacheDir, pkg)
+	if err := os.RemoveAll(target); err != nil {
+		return pkg, err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return pkg, err
+	}
+	if err := os.Rename(dir, target); err != nil {
+		// The cache may be on another filesystem than the staging directory
+		if err := copyDir(dir, target); err != nil {
+			return pkg, err
+		}
+	}
+	return pkg, nil
+}
+
+// AddPackage adds a dependency to the project manifest and lockfile.
+func AddPackage(root string, spec string, name string) (LockedPackage, error) {
+	manifest, err := loadManifest(root)
+	if err != nil {
+		return LockedPackage{}, err
+	}
+	lock, err := loadLockfile(root)
+	if err != nil {
+		return LockedPackage{}, err
+	}
+	cacheDir, err := packageCacheDir()
+	if err != nil {
+		return LockedPackage{}, err
+	}
+
+	if name == "" {
+		source, _ := parsePackageSpec(spec)
+		name = packageName(source)
+	}
+	if err := checkPackageName(name); err != nil {
+		return LockedPackage{}, err
+	}
+
+	// Adding again re-resolves the version, so the current lock entry is ignored
+	pkg, err := fetchPackage(root, cacheDir, name, spec, nil)
+	if err != nil {
+		return pkg, err
+	}
+
+	manifest.Dependencies[name] = spec
+	lock.set(pkg)
+
+	if err := writeJSONFile(filepath.Join(root, manifestFile), manifest); err != nil {
+		return pkg, err
+	}
+	return pkg, writeJSONFile(filepath.Join(root, lockFile), lock)
+}
+
+// RemovePackage drops a dependency from the project manifest and lockfile.
+func RemovePackage(root string, name string) error {
+	manifest, err := loadManifest(root)
+	if err != nil {
+		return err
+	}
+	lock, err := loadLockfile(root)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := manifest.Dependencies[name]; !ok {
+		return fmt.Errorf("%s is not a dependency of this project", name)
+	}
+
+	delete(manifest.Dependencies, name)
+	lock.remove(name)
+
+	if err := writeJSONFile(filepath.Join(root, manifestFile), manifest); err != nil {
+		return err
+	}
+	return writeJSONFile(filepath.Join(root, lockFile), lock)
+}
+
+// InstallPackages fetches every dependency of the manifest, honoring the lockfile.
+func InstallPackages(root string) ([]LockedPackage, error) {
+	manifest, err := loadManifest(root)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := loadLockfile(root)
+	if err != nil {
+		return nil, err
+	}
+	cacheDir, err := packageCacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(manifest.Dependencies))
+	for name := range manifest.Dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	updated := &Lockfile{Version: 1}
+	for _, name := range names {
+		pkg, err := fetchPackage(root, cacheDir, name, manifest.Dependencies[name], lock.Find(name))
+		if err != nil {
+			return updated.Packages, err
+		}
+		updated.set(pkg)
+	}
+
+	return updated.Packages, writeJSONFile(filepath.Join(root, lockFile), updated)
+}
+
+// packageImportDirs maps every locked package of the project to its directory,
+// using vendor/ instead of the cache when the project has been vendored
+func packageImportDirs(root string) (map[string]string, error) {
+	if dirs, err := vendoredImportDirs(root); dirs != nil || err != nil {
+		return dirs, err
+	}
+
+	lock, err := loadLockfile(root)
+	if err != nil {
+		return nil, nil
+	}
+	cacheDir, err := packageCacheDir()
+	if err != nil {
+		return nil, nil
+	}
+
+	dirs := make(map[string]string)
+	for _, pkg := range lock.Packages {
+		dirs[pkg.Name] = packageDir(cacheDir, pkg)
+	}
+	return dirs, nil
+}
+
+// resolvePackageImports rewrites `use "pkgname/module"` to the file of the package in the cache
+// or vendor/, leaving every other import untouched. It fails when vendor/ is out of date.
+func resolvePackageImports(code string) (string, error) {
+	root, err := findProjectRoot(".")
+	if err != nil {
+		return code, nil
+	}
+
+	dirs, err := packageImportDirs(root)
+	if err != nil || len(dirs) == 0 {
+		return code, err
+	}
+	return rewritePackageImports(code, dirs), nil
+}
+
+// rewritePackageImports rewrites the package imports of code using the package directories.
+// Entries named after a file, like std.r2d2, replace imports of that file as a whole.
+func rewritePackageImports(code string, dirs map[string]string) string {
+	return useDeclPattern.ReplaceAllStringFunc(code, func(decl string) string {
+		match := useDeclPattern.FindStringSubmatch(decl)
+
+		if file, ok := dirs[match[1]]; ok && filepath.Ext(match[1]) == ".r2d2" {
+			return strings.Replace(decl, `"`+match[1]+`"`, fmt.Sprintf("%q", filepath.ToSlash(file)), 1)
+		}
+
+		name, module, ok := strings.Cut(match[1], "/")
+		dir, known := dirs[name]
+		if !ok || !known || module == "" {
+			return decl
+		}
+
+		if filepath.Ext(module) == "" {
+			module += ".r2d2"
+		}
+		path := filepath.ToSlash(filepath.Join(dir, filepath.FromSlash(module)))
+		return strings.Replace(decl, `"`+match[1]+`"`, fmt.Sprintf("%q", path), 1)
+	})
+}
+
+// RunAdd adds a dependency to the project in the current directory and reports it.
+func RunAdd(spec string, name string) error {
+	root, err := findProjectRoot(".")
+	if err != nil {
+		// The first dependency creates the manifest
+		root = "."
+	}
+
+	pkg, err := AddPackage(root, spec, name)
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+
+	fmt.Println(InfoMessage(fmt.Sprintf("Added %s %s", pkg.Name, describeLocked(pkg))))
+	return nil
+}
+
+// RunRemove removes a dependency from the project in the current directory.
+func RunRemove(name string) error {
+	root, err := findProjectRoot(".")
+	if err == nil {
+		err = RemovePackage(root, name)
+	}
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+
+	fmt.Println(InfoMessage("Removed " + name))
+	return nil
+}
+
+// RunInstall fetches the dependencies of the project in the current directory.
+func RunInstall() error {
+	root, err := findProjectRoot(".")
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+
+	packages, err := InstallPackages(root)
+	for _, pkg := range packages {
+		fmt.Println(InfoMessage(fmt.Sprintf("Installed %s %s", pkg.Name, describeLocked(pkg))))
+	}
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+
+	if len(packages) == 0 {
+		fmt.Println(InfoMessage("No dependencies to install"))
+	}
+	return nil
+}
+
+// describeLocked summarizes the resolved version of a locked package
+func describeLocked(pkg LockedPackage) string {
+	id := pkg.Hash
+	if pkg.Commit != "" {
+		id = pkg.Commit
+	}
+	if len(id) > 19 {
+		id = id[:19]
+	}
+	if pkg.Version != "" {
+		return fmt.Sprintf("%s (%s)", pkg.Version, id)
+	}
+	return "(" + id + ")"
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("error writing %s: %v", name, err)
+		}
+	}
+}
+
+// makeGitPackage creates a local git repository with the given files, tagged v1.0.0
+func makeGitPackage(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := filepath.Join(t.TempDir(), "strings")
+	writeFiles(t, dir, files)
+
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "initial"},
+		{"tag", "v1.0.0"},
+	} {
+		if _, err := runGit(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// commitGitPackage commits a change to a file of a package made by makeGitPackage
+func commitGitPackage(t *testing.T, dir string, name string, content string, tag string) {
+	t.Helper()
+	writeFiles(t, dir, map[string]string{name: content})
+
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "update"},
+		{"tag", tag},
+	} {
+		if _, err := runGit(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParsePackageSpec(t *testing.T) {
+	tests := []struct {
+		spec        string
+		wantSource  string
+		wantVersion string
+	}{
+		{"https://github.com/user/strings.git@v1.0.0", "https://github.com/user/strings.git", "v1.0.0"},
+		{"https://github.com/user/strings.git", "https://github.com/user/strings.git", ""},
+		{"git@github.com:user/strings.git", "git@github.com:user/strings.git", ""},
+		{"git@github.com:user/strings.git@main", "git@github.com:user/strings.git", "main"},
+		{"../shared", "../shared", ""},
+		{"file:///tmp/repo@abc123", "file:///tmp/repo", "abc123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			source, version := parsePackageSpec(tt.spec)
+			if source != tt.wantSource || version != tt.wantVersion {
+				t.Errorf("parsePackageSpec() = %q, %q, want %q, %q", source, version, tt.wantSource, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestPackageSourceKind(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"https://github.com/user/strings.git", SourceGit},
+		{"git@github.com:user/strings.git", SourceGit},
+		{"file:///tmp/repo", SourceGit},
+		{"https://example.com/strings.tar.gz", SourceTarball},
+		{"file:///tmp/strings.tgz", SourceTarball},
+		{"../shared", SourcePath},
+		{"/opt/libs/shared", SourcePath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if got := packageSourceKind(tt.source); got != tt.want {
+				t.Errorf("packageSourceKind() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/user/strings.git": "strings",
+		"git@github.com:user/json.git":        "json",
+		"https://example.com/math.tar.gz":     "math",
+		"../shared/":                          "shared",
+	}
+
+	for source, want := range tests {
+		if got := packageName(source); got != want {
+			t.Errorf("packageName(%q) = %q, want %q", source, got, want)
+		}
+	}
+}
+
+func TestInvalidPackageNames(t *testing.T) {
+	t.Setenv("R2D2_CACHE", t.TempDir())
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "shared.r2d2"), []byte("module Shared {}\n"), 0644)
+
+	for _, name := range []string{"../../x", "a/b", `a\b`, ".."} {
+		if _, err := AddPackage(root, root, name); err == nil {
+			t.Errorf("AddPackage() with the name %q didn't fail", name)
+		}
+		if err := checkPackageName(name); err == nil {
+			t.Errorf("checkPackageName(%q) didn't fail", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, manifestFile)); err == nil {
+		t.Error("AddPackage() with an invalid name wrote the manifest")
+	}
+
+	os.WriteFile(filepath.Join(root, manifestFile), []byte(`{"dependencies": {"../x": "../x"}}`), 0644)
+	if _, err := loadManifest(root); err == nil {
+		t.Error("loadManifest() accepted the name ../x")
+	}
+	os.WriteFile(filepath.Join(root, lockFile), []byte(`{"version": 1, "packages": [{"name": "", "source": "x"}]}`), 0644)
+	if _, err := loadLockfile(root); err == nil {
+		t.Error("loadLockfile() accepted an empty name")
+	}
+}
+
+func TestAddRemoveGitPackage(t *testing.T) {
+	t.Setenv("R2D2_CACHE", t.TempDir())
+	repo := makeGitPackage(t, map[string]string{"upper.r2d2": "module Upper {}\n"})
+	commitGitPackage(t, repo, "upper.r2d2", "module Upper { }\n", "v2.0.0")
+	root := t.TempDir()
+
+	pkg, err := AddPackage(root, "file://"+repo+"@v1.0.0", "")
+	if err != nil {
+		t.Fatalf("AddPackage() error = %v", err)
+	}
+	if pkg.Name != "strings" || pkg.Version != "v1.0.0" || len(pkg.Commit) != 40 {
+		t.Errorf("AddPackage() = %+v", pkg)
+	}
+
+	cacheDir, _ := packageCacheDir()
+	content, err := os.ReadFile(filepath.Join(packageDir(cacheDir, pkg), "upper.r2d2"))
+	if err != nil || string(content) != "module Upper {}\n" {
+		t.Errorf("cached file = %q, %v, want the v1.0.0 content", content, err)
+	}
+
+	manifest, _ := loadManifest(root)
+	if manifest.Dependencies["strings"] != "file://"+repo+"@v1.0.0" {
+		t.Errorf("manifest dependencies = %v", manifest.Dependencies)
+	}
+	lock, _ := loadLockfile(root)
+	if locked := lock.Find("strings"); locked == nil || locked.Hash != pkg.Hash {
+		t.Errorf("lockfile = %+v, want %+v", lock.Packages, pkg)
+	}
+
+	if err := RemovePackage(root, "strings"); err != nil {
+		t.Fatalf("RemovePackage() error = %v", err)
+	}
+	manifest, _ = loadManifest(root)
+	lock, _ = loadLockfile(root)
+	if len(manifest.Dependencies) != 0 || len(lock.Packages) != 0 {
+		t.Errorf("after remove: manifest %v, lock %v", manifest.Dependencies, lock.Packages)
+	}
+
+	if err := RemovePackage(root, "strings"); err == nil {
+		t.Error("RemovePackage() of a missing dependency should fail")
+	}
+}
+
+func TestInstallUsesLockedCommit(t *testing.T) {
+	t.Setenv("R2D2_CACHE", t.TempDir())
+	repo := makeGitPackage(t, map[string]string{"upper.r2d2": "module Upper {}\n"})
+	root := t.TempDir()
+
+	// Track the default branch, then move it forward
+	added, err := AddPackage(root, "file://"+repo, "")
+	if err != nil {
+		t.Fatalf("AddPackage() error = %v", err)
+	}
+	commitGitPackage(t, repo, "upper.r2d2", "module Upper { fn x() {} }\n", "v2.0.0")
+
+	// A fresh cache has to be filled from the locked commit, not the branch head
+	t.Setenv("R2D2_CACHE", t.TempDir())
+	installed, err := InstallPackages(root)
+	if err != nil {
+		t.Fatalf("InstallPackages() error = %v", err)
+	}
+	if len(installed) != 1 || installed[0].Commit != added.Commit || installed[0].Hash != added.Hash {
+		t.Errorf("InstallPackages() = %+v, want %+v", installed, added)
+	}
+}
+
+func TestAddTarballPackage(t *testing.T) {
+	if _, err := exec.LookPath("tar"); err != nil {
+		t.Skip("tar not installed")
+	}
+	t.Setenv("R2D2_CACHE", t.TempDir())
+
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{"json-1.0/parse.r2d2": "module Parse {}\n"})
+	archive := filepath.Join(t.TempDir(), "json.tar.gz")
+	if output, err := exec.Command("tar", "-czf", archive, "-C", src, "json-1.0").CombinedOutput(); err != nil {
+		t.Fatalf("tar: %v\n%s", err, output)
+	}
+
+	root := t.TempDir()
+	pkg, err := AddPackage(root, "file://"+archive, "")
+	if err != nil {
+		t.Fatalf("AddPackage() error = %v", err)
+	}
+	if pkg.Name != "json" || pkg.Commit != "" || !strings.HasPrefix(pkg.Hash, "sha256:") {
+		t.Errorf("AddPackage() = %+v", pkg)
+	}
+
+	// The single top-level directory of the archive is stripped
+	cacheDir, _ := packageCacheDir()
+	if _, err := os.Stat(filepath.Join(packageDir(cacheDir, pkg), "parse.r2d2")); err != nil {
+		t.Errorf("parse.r2d2 not found in the cache: %v", err)
+	}
+
+	// A tarball whose content changed behind the same URL no longer matches the lock
+	writeFiles(t, src, map[string]string{"json-1.0/parse.r2d2": "module Parse { }\n"})
+	if output, err := exec.Command("tar", "-czf", archive, "-C", src, "json-1.0").CombinedOutput(); err != nil {
+		t.Fatalf("tar: %v\n%s", err, output)
+	}
+	t.Setenv("R2D2_CACHE", t.TempDir())
+	_, err = InstallPackages(root)
+	if err == nil || !strings.Contains(err.Error(), "doesn't match") {
+		t.Errorf("InstallPackages() error = %v, want a hash mismatch", err)
+	}
+}
+
+func TestInstallRecopiesLocalPackages(t *testing.T) {
+	t.Setenv("R2D2_CACHE", t.TempDir())
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"libs/shared/util.r2d2": "module Util {}\n"})
+
+	added, err := AddPackage(root, "libs/shared", "")
+	if err != nil {
+		t.Fatalf("AddPackage() error = %v", err)
+	}
+
+	writeFiles(t, root, map[string]string{"libs/shared/util.r2d2": "module Util { }\n"})
+	installed, err := InstallPackages(root)
+	if err != nil {
+		t.Fatalf("InstallPackages() error = %v", err)
+	}
+	if len(installed) != 1 || installed[0].Hash == added.Hash {
+		t.Errorf("InstallPackages() = %+v, want a new hash for the edited package", installed)
+	}
+}
+
+func TestRewritePackageImports(t *testing.T) {
+	dirs := map[string]string{"strings": "/cache/strings/abc"}
+
+	code := `use "std.r2d2";
+use "strings/upper";
+use "strings/lower.r2d2";
+use "other/module";
+`
+	want := `use "std.r2d2";
+use "/cache/strings/abc/upper.r2d2";
+use "/cache/strings/abc/lower.r2d2";
+use "other/module";
+`
+	if got := rewritePackageImports(code, dirs); got != want {
+		t.Errorf("rewritePackageImports() =\n%s\nwant\n%s", got, want)
+	}
+}
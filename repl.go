@@ -0,0 +1,772 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Maximum number of entries kept in the history file
+const replHistoryLimit = 500
+
+// Kinds of REPL entries, deciding where they go in the implicit module
+type replEntryKind int
+
+const (
+	entryExpression  replEntryKind = iota // printed with console.log
+	entryStatement                        // executed once
+	entryDeclaration                      // var/let/const, kept alive in the runtime
+	entryFunction                         // fn, added to the implicit module
+	entryTopLevel                         // module/interface/type, added next to the implicit module
+	entryImport                           // use "file.r2d2";
+)
+
+// replSession holds everything the user declared so far
+type replSession struct {
+	imports      []string
+	topLevel     []string
+	functions    []string
+	declarations []string // their values live in the runtime, under replGlobal
+}
+
+// Object of the runtime's global scope holding the REPL variables between inputs
+const replGlobal = "globalThis.__r2d2_repl"
+
+// Function of the implicit module reading back a variable from replGlobal
+const replRestoreFn = `fn __r2d2_restore(name string) {
+@js << return (` + replGlobal + ` || {})[name]; >>;
+}`
+
+// Matches a single var/let/const declaration: keyword, name, type and initializer
+var replDeclPattern = regexp.MustCompile(`^(var|let|const)\s+([A-Za-z_]\w*)\s*([^=;]*?)\s*(?:=([\s\S]*?))?;?\s*$`)
+
+// Matches the signature of a function declaration: name, parameters and return type
+var replFnPattern = regexp.MustCompile(`^(?:export\s+)?(?:pseudo\s+)?fn\s+([A-Za-z_]\w*)\s*(\([^)]*\))\s*([^{;]*)`)
+
+// replDeclaration splits a declaration into its keyword, name and type
+func replDeclaration(decl string) (keyword, name, typ string, ok bool) {
+	match := replDeclPattern.FindStringSubmatch(strings.TrimSpace(decl))
+	if match == nil {
+		return "", "", "", false
+	}
+	return match[1], match[2], strings.TrimSpace(match[3]), true
+}
+
+// newReplSession creates a session, importing std.r2d2 when it can be found
+func newReplSession() *replSession {
+	s := &replSession{}
+	if _, err := os.Stat("std.r2d2"); err == nil {
+		s.imports = append(s.imports, `use "std.r2d2";`)
+	}
+	return s
+}
+
+// classifyReplInput decides how an input is wrapped into the implicit module
+func classifyReplInput(input string) replEntryKind {
+	trimmed := strings.TrimSpace(input)
+	firstWord := strings.FieldsFunc(trimmed, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '(' || r == '{'
+	})
+
+	if len(firstWord) > 0 {
+		switch firstWord[0] {
+		case "use":
+			return entryImport
+		case "module", "interface", "type":
+			return entryTopLevel
+		case "fn", "pseudo":
+			return entryFunction
+		case "export":
+			if len(firstWord) > 1 && (firstWord[1] == "fn" || firstWord[1] == "pseudo") {
+				return entryFunction
+			}
+			return entryTopLevel
+		case "var", "let", "const":
+			return entryDeclaration
+		}
+	}
+
+	if strings.HasSuffix(trimmed, ";") || strings.HasSuffix(trimmed, "}") {
+		return entryStatement
+	}
+	return entryExpression
+}
+
+// program wraps body into the implicit module together with the session state.
+// Variables declared earlier are read back from the runtime instead of running
+// their initializers again, and every variable is saved back after body,
+// including fresh, the one body declares.
+func (s *replSession) program(body string, fresh string) string {
+	var sb strings.Builder
+
+	for _, imp := range s.imports {
+		sb.WriteString(imp + "\n")
+	}
+	for _, decl := range s.topLevel {
+		sb.WriteString(decl + "\n")
+	}
+
+	var names, restores []string
+	for _, decl := range s.declarations {
+		keyword, name, typ, ok := replDeclaration(decl)
+		if !ok || name == fresh {
+			continue
+		}
+		names = append(names, name)
+		restores = append(restores, strings.Join(strings.Fields(fmt.Sprintf("%s %s %s", keyword, name, typ)), " ")+
+			fmt.Sprintf(" = __r2d2_restore(%q);", name))
+	}
+	if fresh != "" {
+		names = append(names, fresh)
+	}
+
+	sb.WriteString("module repl {\n")
+	for _, fn := range s.functions {
+		sb.WriteString(fn + "\n")
+	}
+	if len(restores) > 0 {
+		sb.WriteString(replRestoreFn + "\n")
+	}
+	sb.WriteString("export fn main() {\n")
+	for _, restore := range restores {
+		sb.WriteString(restore + "\n")
+	}
+	sb.WriteString(body + "\n")
+	if len(names) > 0 {
+		sb.WriteString("@js <<\n" + replGlobal + " = " + replGlobal + " || {};\n")
+		for _, name := range names {
+			sb.WriteString(fmt.Sprintf("%s.%s = %s;\n", replGlobal, name, name))
+		}
+		sb.WriteString(">>;\n")
+	}
+	sb.WriteString("}\n}\n")
+
+	return sb.String()
+}
+
+// source returns the program that evaluates an input of the given kind, and the session
+// that results from accepting it
+func (s *replSession) source(input string, kind replEntryKind) (string, *replSession) {
+	input = strings.TrimSpace(input)
+	next := *s
+
+	switch kind {
+	case entryExpression:
+		return s.program("console.log("+strings.TrimSuffix(input, ";")+");", ""), s
+	case entryImport:
+		next.imports = append(append([]string{}, s.imports...), input)
+	case entryTopLevel:
+		next.topLevel = append(append([]string{}, s.topLevel...), input)
+	case entryFunction:
+		next.functions = append(append([]string{}, s.functions...), input)
+	case entryDeclaration:
+		// The initializer runs once here, later inputs read the saved value
+		_, name, _, _ := replDeclaration(input)
+		next.declarations = nil
+		for _, decl := range s.declarations {
+			if _, other, _, _ := replDeclaration(decl); other != name {
+				next.declarations = append(next.declarations, decl)
+			}
+		}
+		next.declarations = append(next.declarations, input)
+		return s.program(input, name), &next
+	default:
+		return s.program(input, ""), s
+	}
+
+	// Imports, functions and top-level declarations only need to compile
+	return next.program("", ""), &next
+}
+
+// declaredType returns the type written for a variable, or the signature of a
+// function, when expr is the name of one declared in the session
+func (s *replSession) declaredType(expr string) (string, bool) {
+	expr = strings.TrimSuffix(strings.TrimSpace(expr), ";")
+
+	for i := len(s.declarations) - 1; i >= 0; i-- {
+		if _, name, typ, ok := replDeclaration(s.declarations[i]); ok && name == expr && typ != "" {
+			return typ, true
+		}
+	}
+	for i := len(s.functions) - 1; i >= 0; i-- {
+		match := replFnPattern.FindStringSubmatch(strings.TrimSpace(s.functions[i]))
+		if match != nil && match[1] == expr {
+			return strings.TrimSpace("fn" + match[2] + " " + strings.TrimSpace(match[3])), true
+		}
+	}
+	return "", false
+}
+
+// typeSource returns a program that prints the runtime type of an expression,
+// for values without a declared type
+func (s *replSession) typeSource(expr string) string {
+	return s.program(fmt.Sprintf(`var __r2d2_value = (%s);
+@js <<
+  console.log(__r2d2_value === null || __r2d2_value === undefined ? "void" : Array.isArray(__r2d2_value) ? "array" : typeof __r2d2_value);
+>>;`, strings.TrimSuffix(strings.TrimSpace(expr), ";")), "")
+}
+
+// Matches the @js of a JS block at the start of a statement, right before its <<
+var replJsBlockPattern = regexp.MustCompile(`(^|[;{}\n]|=>)\s*@js\s*$`)
+
+// isCompleteInput reports whether every block, string and JS block of the input is closed,
+// so Enter can submit it instead of starting a new line
+func isCompleteInput(input string) bool {
+	depth := 0
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case strings.HasPrefix(string(runes[i:]), "//"):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(string(runes[i:]), "/*"):
+			end := strings.Index(string(runes[i+2:]), "*/")
+			if end < 0 {
+				return false
+			}
+			i += 2 + len([]rune(string(runes[i+2:])[:end])) + 1
+		case strings.HasPrefix(string(runes[i:]), "<<") && replJsBlockPattern.MatchString(string(runes[:i])):
+			end := strings.Index(string(runes[i+2:]), ">>")
+			if end < 0 {
+				return false
+			}
+			i += 2 + len([]rune(string(runes[i+2:])[:end])) + 1
+		case strings.HasPrefix(string(runes[i:]), `"""`):
+			end := strings.Index(string(runes[i+3:]), `"""`)
+			if end < 0 {
+				return false
+			}
+			i += 3 + len([]rune(string(runes[i+3:])[:end])) + 2
+		case runes[i] == '"':
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				if runes[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(runes) {
+				return false
+			}
+		case runes[i] == '{' || runes[i] == '(' || runes[i] == '[':
+			depth++
+		case runes[i] == '}' || runes[i] == ')' || runes[i] == ']':
+			depth--
+		}
+	}
+
+	return depth <= 0
+}
+
+// replResultMsg is sent when an evaluation finishes
+type replResultMsg struct {
+	input   string
+	output  string
+	js      string
+	err     error
+	session *replSession
+	job     *replJob
+}
+
+// replInterruptedMsg is sent once an evaluation stopped by Ctrl+C is cleaned up
+type replInterruptedMsg struct {
+	input     string
+	restarted bool // the runtime was running the input and had to be restarted
+	err       error
+}
+
+// replJob is an evaluation running in the background, which Ctrl+C can stop
+type replJob struct {
+	mu         sync.Mutex
+	input      string
+	ctx        context.Context // cancelled by Ctrl+C, killing the compiler
+	cancel     context.CancelFunc
+	cancelled  bool
+	evaluating bool // compiled and handed to the runtime
+}
+
+// startEval marks the job as running in the runtime, unless it was interrupted while compiling
+func (j *replJob) startEval() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.cancelled {
+		return false
+	}
+	j.evaluating = true
+	return true
+}
+
+// interrupt stops the compiler and tells whether the runtime is already running the job
+func (j *replJob) interrupt() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.cancelled = true
+	j.cancel()
+	return j.evaluating
+}
+
+// ReplModel represents the REPL state
+type ReplModel struct {
+	session     *replSession
+	runtime     *jsRuntime
+	input       string
+	history     []string
+	historyIdx  int
+	historyFile string
+	lastJs      string
+	busy        bool
+	job         *replJob // the evaluation running, nil while the runtime restarts
+	width       int
+}
+
+// Styles for the REPL (using colors from styles.go)
+var (
+	replPromptStyle = lipgloss.NewStyle().
+			Foreground(highlightColor).
+			Bold(true)
+
+	replOutputStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("252"))
+
+	replJsStyle = lipgloss.NewStyle().
+			Foreground(subtleColor)
+)
+
+const (
+	replPrompt         = "r2d2> "
+	replContinuePrompt = "  ... "
+	replHelp           = `Type statements or expressions, blocks continue on the next line.
+  :load <file.r2d2>  load a file and run it
+  :js                show the JavaScript generated for the last input
+  :type <expr>       show the declared type of a variable or function,
+                     or the runtime type of an expression
+  :reset             forget every declaration
+  :help              show this message
+  :quit              leave the REPL (also Ctrl+D)
+Ctrl+C stops an input that's still running.`
+)
+
+func (m ReplModel) Init() tea.Cmd {
+	return tea.Println(InfoMessage("R2D2 REPL - version "+Version) + "\n" + statusMessageStyle.Render("Type :help for help, :quit to leave"))
+}
+
+func (m ReplModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+
+	case replResultMsg:
+		// The result of an interrupted evaluation comes too late
+		if msg.job != m.job {
+			return m, nil
+		}
+		m.busy, m.job = false, nil
+		if msg.js != "" {
+			m.lastJs = msg.js
+		}
+		if msg.err == nil && msg.session != nil {
+			m.session = msg.session
+		}
+		return m, tea.Println(formatReplResult(msg))
+
+	case replInterruptedMsg:
+		m.busy = false
+		text := "Interrupted"
+		if msg.restarted {
+			// Variables lived in the old runtime, functions and imports are compiled again
+			next := *m.session
+			next.declarations = nil
+			m.session = &next
+			text = "Interrupted, the runtime was restarted and the variables cleared"
+		}
+		result := replPromptStyle.Render(replPrompt) + highlightR2D2(msg.input) + "\n" + InfoMessage(text)
+		if msg.err != nil {
+			result += "\n" + ErrorMessage("Couldn't restart the runtime: "+msg.err.Error())
+		}
+		return m, tea.Println(result)
+
+	case tea.KeyMsg:
+		if m.busy {
+			if msg.Type == tea.KeyCtrlC && m.job != nil {
+				job := m.job
+				m.job = nil
+				return m, interruptRepl(m.runtime, job)
+			}
+			return m, nil
+		}
+
+		switch msg.Type {
+		case tea.KeyCtrlD:
+			return m, tea.Quit
+
+		case tea.KeyCtrlC:
+			if m.input == "" {
+				return m, tea.Quit
+			}
+			m.input = ""
+			return m, nil
+
+		case tea.KeyEnter:
+			if !isCompleteInput(m.input) {
+				m.input += "\n"
+				return m, nil
+			}
+			return m.submit()
+
+		case tea.KeyBackspace:
+			if m.input != "" {
+				runes := []rune(m.input)
+				m.input = string(runes[:len(runes)-1])
+			}
+
+		case tea.KeyUp:
+			if m.historyIdx > 0 {
+				m.historyIdx--
+				m.input = m.history[m.historyIdx]
+			}
+
+		case tea.KeyDown:
+			if m.historyIdx < len(m.history)-1 {
+				m.historyIdx++
+				m.input = m.history[m.historyIdx]
+			} else {
+				m.historyIdx = len(m.history)
+				m.input = ""
+			}
+
+		case tea.KeyTab:
+			m.input += "  "
+
+		case tea.KeySpace:
+			m.input += " "
+
+		case tea.KeyRunes:
+			m.input += string(msg.Runes)
+		}
+	}
+
+	return m, nil
+}
+
+// submit handles the current input, running it in the background when it needs the compiler
+func (m ReplModel) submit() (tea.Model, tea.Cmd) {
+	input := strings.TrimSpace(m.input)
+	m.input = ""
+	if input == "" {
+		return m, nil
+	}
+
+	m.history = append(m.history, input)
+	m.historyIdx = len(m.history)
+	appendReplHistory(m.historyFile, input)
+
+	echo := replPromptStyle.Render(replPrompt) + highlightR2D2(input)
+
+	if strings.HasPrefix(input, ":") {
+		command, arg, _ := strings.Cut(input, " ")
+		arg = strings.TrimSpace(arg)
+
+		switch command {
+		case ":quit", ":q", ":exit":
+			return m, tea.Quit
+		case ":help", ":h":
+			return m, tea.Println(echo + "\n" + replHelp)
+		case ":reset":
+			m.session = newReplSession()
+			return m, tea.Println(echo + "\n" + InfoMessage("Session cleared"))
+		case ":js":
+			if m.lastJs == "" {
+				return m, tea.Println(echo + "\n" + InfoMessage("Nothing compiled yet"))
+			}
+			return m, tea.Println(echo + "\n" + replJsStyle.Render(strings.TrimRight(m.lastJs, "\n")))
+		case ":type", ":t":
+			if arg == "" {
+				return m, tea.Println(echo + "\n" + ErrorMessage("Usage: :type <expr>"))
+			}
+			if typ, ok := m.session.declaredType(arg); ok {
+				return m, tea.Println(echo + "\n" + replOutputStyle.Render(typ))
+			}
+			return m.start(input, func(job *replJob) tea.Cmd {
+				return evalReplSource(job, m.runtime, input, m.session.typeSource(arg), "", nil)
+			})
+		case ":load", ":l":
+			if arg == "" {
+				return m, tea.Println(echo + "\n" + ErrorMessage("Usage: :load <file.r2d2>"))
+			}
+			return m.start(input, func(job *replJob) tea.Cmd {
+				return loadReplFile(job, m.runtime, m.session, input, arg)
+			})
+		default:
+			return m, tea.Println(echo + "\n" + ErrorMessage("Unknown REPL command: "+command))
+		}
+	}
+
+	kind := classifyReplInput(input)
+	source, next := m.session.source(input, kind)
+
+	// Functions, imports and top-level declarations are only compiled here
+	runtime := m.runtime
+	if kind == entryFunction || kind == entryTopLevel || kind == entryImport {
+		runtime = nil
+	}
+
+	return m.start(input, func(job *replJob) tea.Cmd {
+		return evalReplSource(job, runtime, input, source, "", next)
+	})
+}
+
+// start runs the command of a new job, the REPL being busy until it finishes or is interrupted
+func (m ReplModel) start(input string, run func(job *replJob) tea.Cmd) (tea.Model, tea.Cmd) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.job = &replJob{input: input, ctx: ctx, cancel: cancel}
+	m.busy = true
+	return m, run(m.job)
+}
+
+// interruptRepl stops a job: the compiler is killed, and the runtime restarted
+// when it was already running the code, since a loop in it can't be stopped otherwise
+func interruptRepl(rt *jsRuntime, job *replJob) tea.Cmd {
+	evaluating := job.interrupt()
+	return func() tea.Msg {
+		if !evaluating || rt == nil {
+			return replInterruptedMsg{input: job.input}
+		}
+		return replInterruptedMsg{input: job.input, restarted: true, err: rt.Restart()}
+	}
+}
+
+// evalReplSource compiles source as a file of dir, or as typed input when dir is empty,
+// and, with a runtime, evaluates the result
+func evalReplSource(job *replJob, rt *jsRuntime, input string, source string, dir string, next *replSession) tea.Cmd {
+	return func() tea.Msg {
+		js, err := transpileJsProcess(job.ctx, source, dir)
+		if err != nil {
+			return replResultMsg{input: input, err: err, job: job}
+		}
+
+		if rt == nil {
+			return replResultMsg{input: input, js: js, session: next, job: job}
+		}
+
+		output, _, err := rt.evalIf(job.startEval, js)
+		return replResultMsg{input: input, output: output, js: js, err: err, session: next, job: job}
+	}
+}
+
+// transpileJsProcess compiles source as a file of dir in a child 'r2d2 __transpile',
+// reading the JavaScript and what the compiler prints from its pipes, so nothing
+// reaches the terminal of the REPL and the child can be killed
+func transpileJsProcess(ctx context.Context, source string, dir string) (string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"__transpile"}
+	if dir != "" {
+		args = append(args, dir)
+	}
+	cmd := exec.CommandContext(ctx, self, args...)
+	cmd.Stdin = strings.NewReader(source)
+	var stdout, stderr strings.Builder
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", errors.New(message)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// PrintTranspiled is the child of transpileJsProcess: it compiles stdin as a file of dir,
+// or as typed input when dir is empty, writing the JavaScript to stdout, and what the
+// compiler prints and errors to stderr
+func PrintTranspiled(dir string) error {
+	code, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+
+	// Nothing else runs in this process, the compiler's messages can go to stderr
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	var js string
+	resolved, err := resolvePackageImports(string(code))
+	if err == nil {
+		js, err = transpileJsIn(resolved, dir)
+	}
+	os.Stdout = stdout
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	_, err = io.WriteString(os.Stdout, js)
+	return err
+}
+
+// loadReplFile imports a file into the session and runs it once. The file is
+// compiled in its own directory so its `use` paths resolve like with 'r2d2 run'.
+func loadReplFile(job *replJob, rt *jsRuntime, session *replSession, input string, path string) tea.Cmd {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return func() tea.Msg {
+			return replResultMsg{input: input, err: err, job: job}
+		}
+	}
+
+	next := *session
+	next.imports = append(append([]string{}, session.imports...), fmt.Sprintf("use %q;", filepath.ToSlash(path)))
+
+	return func() tea.Msg {
+		msg := evalReplSource(job, rt, input, string(content), filepath.Dir(path), nil)().(replResultMsg)
+		if msg.err == nil {
+			msg.session = &next
+		}
+		return msg
+	}
+}
+
+// formatReplResult renders the echo of an input followed by its output or error
+func formatReplResult(msg replResultMsg) string {
+	var sb strings.Builder
+
+	lines := strings.Split(msg.input, "\n")
+	for i, line := range lines {
+		prompt := replContinuePrompt
+		if i == 0 {
+			prompt = replPrompt
+		}
+		sb.WriteString(replPromptStyle.Render(prompt) + highlightR2D2(line))
+		if i < len(lines)-1 {
+			sb.WriteString("\n")
+		}
+	}
+
+	if output := strings.TrimRight(msg.output, "\n"); output != "" {
+		sb.WriteString("\n" + replOutputStyle.Render(output))
+	}
+	if msg.err != nil {
+		sb.WriteString("\n" + ErrorMessage(msg.err.Error()))
+	}
+
+	return sb.String()
+}
+
+func (m ReplModel) View() string {
+	if m.busy {
+		return statusMessageStyle.Render("evaluating...")
+	}
+
+	var sb strings.Builder
+	lines := strings.Split(m.input, "\n")
+	for i, line := range lines {
+		prompt := replContinuePrompt
+		if i == 0 {
+			prompt = replPrompt
+		}
+		sb.WriteString(replPromptStyle.Render(prompt) + highlightR2D2(line))
+		if i == len(lines)-1 {
+			sb.WriteString("█")
+		} else {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// replHistoryPath returns the file the REPL history is kept in
+func replHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".r2d2_history")
+}
+
+// loadReplHistory reads the history file, one JSON string per line
+func loadReplHistory(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var history []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry string
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			history = append(history, entry)
+		}
+	}
+
+	if len(history) > replHistoryLimit {
+		history = history[len(history)-replHistoryLimit:]
+	}
+	return history
+}
+
+// appendReplHistory adds an entry to the history file, ignoring failures
+func appendReplHistory(path string, entry string) {
+	if path == "" {
+		return
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	encoded, _ := json.Marshal(entry)
+	file.Write(append(encoded, '\n'))
+}
+
+// Repl starts the interactive R2D2 shell.
+func Repl() (err error) {
+	rt, err := newJSRuntime()
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+	defer rt.Close()
+
+	historyFile := replHistoryPath()
+	history := loadReplHistory(historyFile)
+
+	p := tea.NewProgram(ReplModel{
+		session:     newReplSession(),
+		runtime:     rt,
+		history:     history,
+		historyIdx:  len(history),
+		historyFile: historyFile,
+	})
+
+	if _, err := p.Run(); err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+	return nil
+}
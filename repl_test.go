@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Test how REPL inputs are classified
+func TestClassifyReplInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected replEntryKind
+	}{
+		{"1 + 2", entryExpression},
+		{"std.len(\"abc\")", entryExpression},
+		{"console.log(\"hi\");", entryStatement},
+		{"if x > 1 { console.log(x); }", entryStatement},
+		{"var x = 10;", entryDeclaration},
+		{"let y string = \"a\";", entryDeclaration},
+		{"const z = true;", entryDeclaration},
+		{"fn double(n number) number { return n * 2; }", entryFunction},
+		{"export fn main() { }", entryFunction},
+		{"pseudo fn later();", entryFunction},
+		{"module Other { }", entryTopLevel},
+		{"interface Shape { fn area(); }", entryTopLevel},
+		{"use \"std.r2d2\";", entryImport},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := classifyReplInput(tt.input); got != tt.expected {
+				t.Errorf("classifyReplInput(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+// Test that Enter only submits inputs with every block closed
+func TestIsCompleteInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 + 2", true},
+		{"if x > 1 {", false},
+		{"if x > 1 {\n  console.log(x);\n}", true},
+		{"console.log(\"{\");", true},
+		{"console.log(\"unterminated", false},
+		{"// comment with {", true},
+		{"/* open comment {", false},
+		{"@js <<\n  let a = {};\n", false},
+		{"@js <<\n  let a = {;\n>>;", true},
+		{"var s = \"\"\"multi", false},
+		{"var s = \"\"\"multi\nline\"\"\";", true},
+		{"std.len([1, 2", false},
+		{"1 << 2", true},
+		{"var x = 1 << 2;", true},
+		{"fn f() {\n  @js << return 1; >>;\n}", true},
+		{"if x => @js << console.log(x);", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := isCompleteInput(tt.input); got != tt.expected {
+				t.Errorf("isCompleteInput(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+// Test that inputs are wrapped into the implicit module with the session state
+func TestReplSessionSource(t *testing.T) {
+	session := &replSession{}
+
+	source, next := session.source("var x = 10;", entryDeclaration)
+	if next == session {
+		t.Fatal("a declaration should produce a new session")
+	}
+	if len(session.declarations) != 0 {
+		t.Error("the original session should not be modified")
+	}
+	if !strings.Contains(source, "var x = 10;") || !strings.Contains(source, "module repl {") {
+		t.Errorf("declaration source should contain the declaration inside the module, got:\n%s", source)
+	}
+
+	// Later inputs read the variable back from the runtime instead of running the initializer again
+	source, _ = next.source("x = x + 1;", entryStatement)
+	if strings.Contains(source, "var x = 10;") {
+		t.Errorf("the initializer should only run when declared, got:\n%s", source)
+	}
+	for _, expected := range []string{`var x = __r2d2_restore("x");`, "x = x + 1;", replGlobal + ".x = x;"} {
+		if !strings.Contains(source, expected) {
+			t.Errorf("statement source should contain %q, got:\n%s", expected, source)
+		}
+	}
+
+	source, after := next.source("fn double(n number) number { return n * 2; }", entryFunction)
+	if len(after.functions) != 1 || !strings.Contains(source, "fn double") {
+		t.Errorf("function should be added to the session, got:\n%s", source)
+	}
+
+	source, same := after.source("double(x)", entryExpression)
+	if same != after {
+		t.Error("an expression should not change the session")
+	}
+	for _, expected := range []string{`var x = __r2d2_restore("x");`, "fn double", "console.log(double(x));", "export fn main()"} {
+		if !strings.Contains(source, expected) {
+			t.Errorf("expression source should contain %q, got:\n%s", expected, source)
+		}
+	}
+
+	// Functions go before main so they aren't nested inside it
+	if strings.Index(source, "fn double") > strings.Index(source, "export fn main()") {
+		t.Errorf("functions should come before main, got:\n%s", source)
+	}
+
+	source, _ = after.source("use \"lib.r2d2\";", entryImport)
+	if !strings.HasPrefix(source, "use \"lib.r2d2\";") {
+		t.Errorf("imports should be at the top of the program, got:\n%s", source)
+	}
+}
+
+// Test that declaring a variable again replaces the earlier declaration
+func TestReplSessionRedeclare(t *testing.T) {
+	session := &replSession{}
+	_, session = session.source("var x number = 1;", entryDeclaration)
+	_, session = session.source("let y = 2;", entryDeclaration)
+
+	source, next := session.source("var x string = \"a\";", entryDeclaration)
+	if strings.Contains(source, `__r2d2_restore("x")`) || !strings.Contains(source, `let y = __r2d2_restore("y");`) {
+		t.Errorf("the new declaration should replace the restore of x only, got:\n%s", source)
+	}
+	if len(next.declarations) != 2 || next.declarations[1] != "var x string = \"a\";" {
+		t.Errorf("declarations = %q", next.declarations)
+	}
+}
+
+// Test that :type answers with the types written in the session
+func TestReplDeclaredType(t *testing.T) {
+	session := &replSession{
+		declarations: []string{"var count number = 0;", "let names []string = [];", "var loose = 1;"},
+		functions:    []string{"fn double(n number) number { return n * 2; }", "export pseudo fn greet(name string);"},
+	}
+
+	tests := []struct {
+		expr string
+		want string
+		ok   bool
+	}{
+		{"count", "number", true},
+		{"names", "[]string", true},
+		{"double", "fn(n number) number", true},
+		{"greet", "fn(name string)", true},
+		{"loose", "", false},
+		{"count + 1", "", false},
+	}
+
+	for _, tt := range tests {
+		if got, ok := session.declaredType(tt.expr); got != tt.want || ok != tt.ok {
+			t.Errorf("declaredType(%q) = %q, %v, want %q, %v", tt.expr, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// Test that code compiled for a directory leaves no files behind in it
+func TestTranspileJsIn(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := transpileJsIn("module Test { export fn main() { } }", dir); err != nil {
+		t.Fatalf("transpileJsIn() error = %v", err)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("transpileJsIn() left %d file(s) in %s", len(entries), dir)
+	}
+}
+
+// Test that the history file keeps multi-line entries intact
+func TestReplHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	entries := []string{"var x = 1;", "if x > 0 {\n  console.log(x);\n}", ":js"}
+	for _, entry := range entries {
+		appendReplHistory(path, entry)
+	}
+
+	history := loadReplHistory(path)
+	if len(history) != len(entries) {
+		t.Fatalf("loadReplHistory() returned %d entries, want %d", len(history), len(entries))
+	}
+	for i := range entries {
+		if history[i] != entries[i] {
+			t.Errorf("history[%d] = %q, want %q", i, history[i], entries[i])
+		}
+	}
+
+	if loadReplHistory(filepath.Join(t.TempDir(), "missing")) != nil {
+		t.Error("a missing history file should give an empty history")
+	}
+}
+
+// Test that highlighting keeps the source text
+func TestHighlightR2D2(t *testing.T) {
+	src := "module Test { export fn main() { var s string = \"hi\"; // done\n} }"
+	result := highlightR2D2(src)
+
+	for _, word := range []string{"module", "Test", "export", "fn", "main", "string", "\"hi\"", "// done"} {
+		if !strings.Contains(result, word) {
+			t.Errorf("highlightR2D2() should contain %q, got: %q", word, result)
+		}
+	}
+}
+
+// Test that output without a trailing newline doesn't hide the end of an evaluation
+func TestJSRuntimeEvalMarkers(t *testing.T) {
+	tests := []struct {
+		stdout  string
+		want    string
+		wantErr string
+	}{
+		{"hi\n" + jsRuntimeDone + "\n", "hi\n", ""},
+		{"no newline" + jsRuntimeDone + "\n", "no newline", ""},
+		{"partial" + jsRuntimeError + "boom\n" + jsRuntimeDone + "\n", "partial", "boom"},
+	}
+
+	for _, tt := range tests {
+		rt := &jsRuntime{stdin: nopWriteCloser{}, stdout: bufio.NewReader(strings.NewReader(tt.stdout))}
+		output, err := rt.Eval("ignored")
+		if output != tt.want {
+			t.Errorf("Eval() output = %q, want %q", output, tt.want)
+		}
+		if (err == nil) != (tt.wantErr == "") || (err != nil && err.Error() != tt.wantErr) {
+			t.Errorf("Eval() error = %v, want %q", err, tt.wantErr)
+		}
+	}
+}
+
+// nopWriteCloser stands in for the stdin of the runtime process
+type nopWriteCloser struct{}
+
+func (nopWriteCloser) Write(p []byte) (int, error) { return len(p), nil }
+func (nopWriteCloser) Close() error                { return nil }
+
+// Test that Ctrl+C stops a busy evaluation and keeps the REPL open
+func TestReplInterrupt(t *testing.T) {
+	m := ReplModel{session: &replSession{declarations: []string{"var x = 1;"}}}
+	model, _ := m.start("loop { }", func(job *replJob) tea.Cmd { return nil })
+	m = model.(ReplModel)
+	job := m.job
+
+	model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	m = model.(ReplModel)
+	if cmd == nil || m.job != nil {
+		t.Fatal("Ctrl+C didn't interrupt the job")
+	}
+	if job.ctx.Err() == nil {
+		t.Error("Ctrl+C didn't cancel the compiler")
+	}
+	if job.startEval() {
+		t.Error("an interrupted job can still start evaluating")
+	}
+
+	msg := cmd()
+	if _, ok := msg.(tea.QuitMsg); ok {
+		t.Fatal("Ctrl+C quit the REPL")
+	}
+	model, _ = m.Update(msg)
+	m = model.(ReplModel)
+	if m.busy || len(m.session.declarations) != 1 {
+		t.Errorf("after the interrupt busy = %v, declarations = %v", m.busy, m.session.declarations)
+	}
+
+	// The result of the interrupted job is dropped when it comes in
+	model, _ = m.Update(replResultMsg{input: "loop { }", output: "late", js: "late", job: job})
+	if model.(ReplModel).lastJs == "late" {
+		t.Error("the result of an interrupted job was used")
+	}
+
+	// Once the runtime runs the job, it's restarted and the variables are gone
+	model, _ = m.Update(replInterruptedMsg{input: "loop { }", restarted: true})
+	if declarations := model.(ReplModel).session.declarations; len(declarations) != 0 {
+		t.Errorf("declarations after a restart = %v", declarations)
+	}
+}
+
+// Test the persistent JavaScript runtime keeps globals between evaluations
+func TestJSRuntime(t *testing.T) {
+	if _, err := exec.LookPath("deno"); err != nil {
+		t.Skip("deno not found in PATH")
+	}
+
+	rt, err := newJSRuntime()
+	if err != nil {
+		t.Fatalf("newJSRuntime() error = %v", err)
+	}
+	defer rt.Close()
+
+	if _, err := rt.Eval("globalThis.counter = 41;"); err != nil {
+		t.Fatalf("Eval() error = %v", err)
+	}
+
+	output, err := rt.Eval("const m = 1; console.log(globalThis.counter + m);")
+	if err != nil || output != "42\n" {
+		t.Errorf("Eval() = %q, %v, want \"42\\n\"", output, err)
+	}
+
+	// Declaring the same const again must work, like a module emitted twice
+	if _, err := rt.Eval("const m = 2;"); err != nil {
+		t.Errorf("Eval() redeclaration error = %v", err)
+	}
+
+	if _, err := rt.Eval("throw new Error(\"boom\");"); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Eval() should report the exception, got %v", err)
+	}
+
+	// Restart stops an endless evaluation and the new runtime works
+	done := make(chan error)
+	go func() {
+		_, err := rt.Eval("while (true) {}")
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err := rt.Restart(); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	if err := <-done; err == nil {
+		t.Error("the endless evaluation didn't fail")
+	}
+	if output, err := rt.Eval("console.log(typeof globalThis.counter);"); err != nil || output != "undefined\n" {
+		t.Errorf("Eval() after Restart() = %q, %v", output, err)
+	}
+}
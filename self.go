@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Receipt written by r2d2-installer, see installer/receipt.go. The format is pinned
+// by installer/testdata/install-receipt.json, which the tests of both read.
+const installReceiptFile = "install-receipt.json"
+
+// PathEdit is a line the installer added to a shell startup file
+type PathEdit struct {
+	File string `json:"file"`
+	Line string `json:"line"`
+}
+
+// InstallReceipt records what the installer put on the system
+type InstallReceipt struct {
+	InstallerVersion string     `json:"installerVersion"`
+	Version          string     `json:"version"`
+	Binary           string     `json:"binary"`
+	Source           string     `json:"source,omitempty"`
+	PathEdits        []PathEdit `json:"pathEdits,omitempty"`
+	TempDirs         []string   `json:"tempDirs,omitempty"`
+	Toolchain        string     `json:"toolchain,omitempty"`
+	Completion       string     `json:"completion,omitempty"`
+	CompletionEdit   *PathEdit  `json:"completionEdit,omitempty"`
+	InstalledAt      time.Time  `json:"installedAt"`
+}
+
+// installReceiptPath returns ~/.r2d2/install-receipt.json
+func installReceiptPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".r2d2", installReceiptFile), nil
+}
+
+// loadInstallReceipt reads the receipt of the last installation
+func loadInstallReceipt() (InstallReceipt, error) {
+	var receipt InstallReceipt
+	path, err := installReceiptPath()
+	if err != nil {
+		return receipt, err
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return receipt, fmt.Errorf("no install receipt at %s, r2d2 wasn't installed with r2d2-installer", path)
+	}
+	if err != nil {
+		return receipt, err
+	}
+	if err := json.Unmarshal(content, &receipt); err != nil {
+		return receipt, fmt.Errorf("invalid install receipt %s: %v", path, err)
+	}
+	return receipt, nil
+}
+
+// hasLine checks if file holds line
+func hasLine(file string, line string) bool {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	for _, l := range strings.Split(string(content), "\n") {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+// removeLine deletes every occurrence of line from file. A missing file or line
+// is left alone, the same as removePathEdit in the installer.
+func removeLine(file string, line string) error {
+	content, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var kept []string
+	removed := false
+	for _, l := range strings.Split(string(content), "\n") {
+		if l == line {
+			removed = true
+			continue
+		}
+		kept = append(kept, l)
+	}
+	if !removed {
+		return nil
+	}
+	return os.WriteFile(file, []byte(strings.Join(kept, "\n")), 0644)
+}
+
+// SelfUninstall removes what the install receipt lists: the binary, the PATH
+// setup and the temporary directories. With dryRun it only lists them.
+func SelfUninstall(dryRun bool) error {
+	receipt, err := loadInstallReceipt()
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+
+	var failed []string
+	remove := func(what string, action func() error) {
+		if dryRun {
+			fmt.Println(InfoMessage("Would remove " + what))
+			return
+		}
+		if err := action(); err != nil {
+			fmt.Println(ErrorMessage(fmt.Sprintf("Couldn't remove %s: %v", what, err)))
+			failed = append(failed, what)
+			return
+		}
+		fmt.Println(InfoMessage("Removed " + what))
+	}
+
+	if _, err := os.Stat(receipt.Binary); err == nil {
+		remove(receipt.Binary, func() error { return os.Remove(receipt.Binary) })
+	}
+	for _, edit := range receipt.PathEdits {
+		if hasLine(edit.File, edit.Line) {
+			remove("PATH setup from "+edit.File, func() error { return removeLine(edit.File, edit.Line) })
+		}
+	}
+	// Shell completion script, and the startup file line loading it for zsh and PowerShell
+	if receipt.Completion != "" {
+		if _, err := os.Stat(receipt.Completion); err == nil {
+			remove(receipt.Completion, func() error { return os.Remove(receipt.Completion) })
+		}
+	}
+	if edit := receipt.CompletionEdit; edit != nil && hasLine(edit.File, edit.Line) {
+		remove("completion setup from "+edit.File, func() error { return removeLine(edit.File, edit.Line) })
+	}
+	for _, dir := range receipt.TempDirs {
+		if _, err := os.Stat(dir); err == nil {
+			remove(dir, func() error { return os.RemoveAll(dir) })
+		}
+	}
+	// Go and Deno the installer downloaded because no package manager could
+	if receipt.Toolchain != "" {
+		if _, err := os.Stat(receipt.Toolchain); err == nil {
+			remove(receipt.Toolchain, func() error { return os.RemoveAll(receipt.Toolchain) })
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("couldn't remove %s", strings.Join(failed, ", "))
+	}
+	if !dryRun {
+		path, _ := installReceiptPath()
+		os.Remove(path)
+		fmt.Println(InfoMessage("R2D2 uninstalled"))
+	}
+	return nil
+}
+
+// SelfRepair restores the PATH setup from the install receipt and, when the
+// installed binary is missing or doesn't run, reinstalls the release it recorded,
+// or the latest one when that can't be downloaded
+func SelfRepair(source string) error {
+	receipt, err := loadInstallReceipt()
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+
+	for _, edit := range receipt.PathEdits {
+		if hasLine(edit.File, edit.Line) {
+			continue
+		}
+		file, err := os.OpenFile(edit.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "\n%s\n", edit.Line)
+			file.Close()
+		}
+		if err != nil {
+			fmt.Println(ErrorMessage(fmt.Sprintf("Couldn't restore PATH setup in %s: %v", edit.File, err)))
+			return err
+		}
+		fmt.Println(InfoMessage("Restored PATH setup in " + edit.File))
+	}
+
+	binDir := filepath.Dir(receipt.Binary)
+	if check := checkInstallDirInPath(binDir, os.Getenv("PATH")); check.Status != CheckPass && len(receipt.PathEdits) == 0 {
+		fmt.Println(ErrorMessage(check.Detail))
+		fmt.Println(InfoMessage(check.Fix))
+	}
+
+	if err := exec.Command(receipt.Binary, "version").Run(); err == nil {
+		fmt.Println(InfoMessage(receipt.Binary + " works"))
+		return nil
+	}
+
+	source = releaseSource(source)
+	version := receipt.Version
+	if version == "" {
+		if version, err = latestRelease(source); err != nil {
+			fmt.Println(ErrorMessage(fmt.Sprintf("Repair failed: %v", err)))
+			return err
+		}
+	}
+	fmt.Println(InfoMessage(fmt.Sprintf("%s is missing or broken, reinstalling %s", receipt.Binary, version)))
+	binary, err := downloadRelease(source, version)
+	// Releases before 0.2.4 only archived the installer
+	if err != nil && receipt.Version != "" {
+		if latest, latestErr := latestRelease(source); latestErr == nil && latest != version {
+			fmt.Println(InfoMessage(fmt.Sprintf("Release %s can't be downloaded (%v), reinstalling %s", version, err, latest)))
+			binary, err = downloadRelease(source, latest)
+		}
+	}
+	if err == nil {
+		err = os.MkdirAll(binDir, 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(receipt.Binary, binary, 0755)
+	}
+	if err != nil {
+		fmt.Println(ErrorMessage(fmt.Sprintf("Repair failed: %v", err)))
+		return err
+	}
+
+	fmt.Println(InfoMessage("Reinstalled " + receipt.Binary))
+	return nil
+}
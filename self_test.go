@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeInstallReceipt installs a fake binary, PATH edit, completion and temp dir
+// and records them
+func writeInstallReceipt(t *testing.T) InstallReceipt {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+
+	receipt := InstallReceipt{
+		Version:    "0.2.3",
+		Binary:     filepath.Join(dir, "bin", "r2d2"),
+		PathEdits:  []PathEdit{{File: filepath.Join(home, ".bashrc"), Line: `export PATH="` + filepath.Join(dir, "bin") + `:$PATH"`}},
+		TempDirs:   []string{filepath.Join(dir, "work")},
+		Completion: filepath.Join(dir, "completions", "_r2d2"),
+	}
+	receipt.CompletionEdit = &PathEdit{File: filepath.Join(home, ".zshrc"), Line: "source " + receipt.Completion}
+	writeFiles(t, dir, map[string]string{"bin/r2d2": "binary", "work/go.mod": "module x\n", "completions/_r2d2": "#compdef r2d2\n"})
+	os.WriteFile(receipt.PathEdits[0].File, []byte("set -o vi\n"+receipt.PathEdits[0].Line+"\n"), 0644)
+	os.WriteFile(receipt.CompletionEdit.File, []byte(receipt.CompletionEdit.Line+"\n"), 0644)
+
+	content, _ := json.Marshal(receipt)
+	path, _ := installReceiptPath()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return receipt
+}
+
+// The receipt written by the installer, see TestReceiptFormat in installer/receipt_test.go
+func TestInstallReceiptFormat(t *testing.T) {
+	const fixture = "installer/testdata/install-receipt.json"
+	content, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var receipt InstallReceipt
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&receipt); err != nil {
+		t.Fatalf("%s doesn't match InstallReceipt: %v", fixture, err)
+	}
+
+	// Every field the installer writes is read back
+	value := reflect.ValueOf(receipt)
+	for i := 0; i < value.NumField(); i++ {
+		if value.Field(i).IsZero() {
+			t.Errorf("%s doesn't set %s", fixture, value.Type().Field(i).Name)
+		}
+	}
+	encoded, _ := json.MarshalIndent(receipt, "", "  ")
+	if !bytes.Equal(append(encoded, '\n'), content) {
+		t.Errorf("InstallReceipt encodes as:\n%s\nwant %s:\n%s", encoded, fixture, content)
+	}
+}
+
+func TestRemoveLine(t *testing.T) {
+	dir := t.TempDir()
+	rc := filepath.Join(dir, ".bashrc")
+	os.WriteFile(rc, []byte("set -o vi\nexport PATH=x\n"), 0644)
+
+	if err := removeLine(rc, "export PATH=x"); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(rc); string(content) != "set -o vi\n" {
+		t.Errorf(".bashrc = %q", content)
+	}
+
+	// Like the installer, a missing file or line isn't an error
+	if err := removeLine(filepath.Join(dir, "missing"), "export PATH=x"); err != nil {
+		t.Errorf("removeLine() of a missing file = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Error("removeLine() created the missing file")
+	}
+}
+
+func TestSelfUninstall(t *testing.T) {
+	receipt := writeInstallReceipt(t)
+
+	if err := SelfUninstall(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(receipt.Binary); err != nil {
+		t.Fatalf("dry run removed the binary")
+	}
+
+	if err := SelfUninstall(false); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{receipt.Binary, receipt.TempDirs[0], receipt.Completion} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s wasn't removed", path)
+		}
+	}
+	if content, _ := os.ReadFile(receipt.PathEdits[0].File); string(content) != "set -o vi\n" {
+		t.Errorf(".bashrc after uninstall = %q", content)
+	}
+	if content, _ := os.ReadFile(receipt.CompletionEdit.File); string(content) != "" {
+		t.Errorf(".zshrc after uninstall = %q", content)
+	}
+	if _, err := loadInstallReceipt(); err == nil {
+		t.Errorf("receipt wasn't removed")
+	}
+}
+
+func TestSelfRepairRestoresPathEdit(t *testing.T) {
+	receipt := writeInstallReceipt(t)
+	os.WriteFile(receipt.PathEdits[0].File, []byte("set -o vi\n"), 0644)
+
+	// The fake binary doesn't run and the source has no releases, so only the PATH is fixed
+	SelfRepair(t.TempDir())
+
+	if !hasLine(receipt.PathEdits[0].File, receipt.PathEdits[0].Line) {
+		t.Errorf("PATH edit wasn't restored")
+	}
+}
+
+func TestSelfRepairReinstallsBinary(t *testing.T) {
+	receipt := writeInstallReceipt(t)
+
+	// The receipt's 0.2.3 isn't on the server, like the releases that only archived the installer
+	archive := makeReleaseArchive(t, []byte("new binary"))
+	sum := sha256.Sum256(archive)
+	server := newReleaseServer(t, "9.9.9", archive, hex.EncodeToString(sum[:]))
+
+	if err := SelfRepair(server.URL + "/releases"); err != nil {
+		t.Fatalf("SelfRepair() error = %v", err)
+	}
+	if content, _ := os.ReadFile(receipt.Binary); string(content) != "new binary" {
+		t.Errorf("binary = %q, want the one of the latest release", content)
+	}
+}
+
+func TestSelfWithoutReceipt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := SelfUninstall(false); err == nil {
+		t.Error("SelfUninstall() without a receipt should fail")
+	}
+	if err := SelfRepair(""); err == nil {
+		t.Error("SelfRepair() without a receipt should fail")
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Style of the argument an error points at
+var badArgStyle = lipgloss.NewStyle().
+	Foreground(errorColor).
+	Bold(true).
+	Underline(true)
+
+// editDistance counts the insertions, deletions, substitutions and swaps of
+// neighbouring letters that turn a into b
+func editDistance(a string, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(min(d[i-1][j]+1, d[i][j-1]+1), d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+// suggest returns the candidate input was most likely meant to be, or "" when
+// none is close: a few typos away, or starting with what was typed
+func suggest(input string, candidates []string) string {
+	input = strings.ToLower(input)
+
+	best, bestDistance := "", 0
+	for _, candidate := range candidates {
+		distance := editDistance(input, candidate)
+		if distance > max(1, min(2, len([]rune(candidate))/3)) {
+			continue
+		}
+		if best == "" || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if best != "" {
+		return best
+	}
+
+	// A prefix that isn't just the dashes of a flag
+	if len(strings.TrimLeft(input, "-")) >= 2 {
+		for _, candidate := range candidates {
+			if strings.HasPrefix(candidate, input) {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// markArgument returns the command line with args[index] highlighted and
+// underlined with carets, or "" when there's no such argument
+func markArgument(args []string, index int) string {
+	if index < 1 || index >= len(args) {
+		return ""
+	}
+
+	line := "  r2d2"
+	offset := 0
+	for i, arg := range args[1:] {
+		if i+1 == index {
+			offset = lipgloss.Width(line) + 1
+			arg = badArgStyle.Render(arg)
+		}
+		line += " " + arg
+	}
+
+	carets := strings.Repeat("^", max(1, lipgloss.Width(args[index])))
+	return line + "\n" + strings.Repeat(" ", offset) + lipgloss.NewStyle().Foreground(errorColor).Render(carets)
+}
+
+// commandNames lists the names of every command
+func commandNames() []string {
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.name
+	}
+	return names
+}
+
+// hasFlag checks if the command takes the flag. The single-dash spelling of a
+// long flag passes too, some commands have always taken it.
+func (c Command) hasFlag(name string) bool {
+	if name == "--help" || name == "-h" {
+		return true
+	}
+	for _, flag := range c.flags {
+		if flag.name == name || "-"+name == flag.name {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownFlag returns the index in os.Args of the first flag the command doesn't
+// take, or -1. Values of --flag=value are left out.
+func unknownFlag(cmd Command, args []string) int {
+	if cmd.takesCode {
+		return -1
+	}
+	for i := 2; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(arg, "=")
+		if !cmd.hasFlag(name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// UnknownFlag shows an error message when a command is given a flag it doesn't
+// take, pointing at it and at the closest flag it does take.
+func UnknownFlag(cmd Command, errArgIndex int) {
+	flag, _, _ := strings.Cut(os.Args[errArgIndex], "=")
+	fmt.Println(ErrorMessage(fmt.Sprintf("Unknown flag for %s: %s", cmd.name, flag)))
+	fmt.Println(markArgument(os.Args, errArgIndex))
+
+	names := make([]string, len(cmd.flags))
+	for i, f := range cmd.flags {
+		names[i] = f.name
+	}
+	if s := suggest(flag, names); s != "" {
+		fmt.Println(HelpMessage("Did you mean '" + s + "'?"))
+	}
+	fmt.Println(HelpMessage("Run 'r2d2 " + cmd.name + " --help' for usage."))
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"build", "build", 0},
+		{"biuld", "build", 1},
+		{"buld", "build", 1},
+		{"buildd", "build", 1},
+		{"verison", "version", 1},
+		{"rn", "run", 1},
+		{"", "js", 2},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"biuld", "build"},
+		{"Build", "build"},
+		{"rnu", "run"},
+		{"verison", "version"},
+		{"ver", "version"},
+		{"vendro", "vendor"},
+		{"deploy", ""},
+		{"x", ""},
+	}
+
+	for _, tt := range tests {
+		if got := suggest(tt.input, commandNames()); got != tt.want {
+			t.Errorf("suggest(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	if got := suggest("--outdri", []string{"-o", "--outdir", "--entry"}); got != "--outdir" {
+		t.Errorf("suggest(--outdri) = %q, want --outdir", got)
+	}
+	if got := suggest("--", []string{"--outdir"}); got != "" {
+		t.Errorf("suggest(--) = %q, want nothing", got)
+	}
+}
+
+func TestMarkArgument(t *testing.T) {
+	got := markArgument([]string{"/usr/bin/r2d2", "js", "a.r2d2", "--outdri", "dist"}, 3)
+	lines := strings.Split(got, "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "r2d2 js a.r2d2") {
+		t.Fatalf("markArgument() = %q", got)
+	}
+	if offset := strings.Index(lines[1], "^"); offset != strings.Index(lines[0], "--outdri") || strings.Count(lines[1], "^") != len("--outdri") {
+		t.Errorf("carets don't underline the argument:\n%s", got)
+	}
+
+	if got := markArgument([]string{"r2d2"}, 1); got != "" {
+		t.Errorf("markArgument() out of range = %q", got)
+	}
+}
+
+func TestUnknownFlag(t *testing.T) {
+	js, _ := findCommand("js")
+	version, _ := findCommand("version")
+	eval, _ := findCommand("eval")
+
+	tests := []struct {
+		name string
+		cmd  Command
+		args []string
+		want int
+	}{
+		{"known flags", js, []string{"r2d2", "js", "src/", "--outdir", "dist", "--entry=src/main.r2d2", "-o", "a.js"}, -1},
+		{"stdin", js, []string{"r2d2", "js", "-"}, -1},
+		{"typo", js, []string{"r2d2", "js", "src/", "--outdri", "dist"}, 3},
+		{"help", js, []string{"r2d2", "js", "-h"}, -1},
+		{"single dash", version, []string{"r2d2", "version", "-verbose"}, -1},
+		{"other command's flag", version, []string{"r2d2", "version", "--outdir"}, 2},
+		{"code", eval, []string{"r2d2", "eval", "-1", "+", "2"}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unknownFlag(tt.cmd, tt.args); got != tt.want {
+				t.Errorf("unknownFlag() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Where releases are published, R2D2_RELEASE_URL or --source override it.
+// The layout follows GitHub releases: <source>/latest redirects to <source>/tag/<version>
+// and assets are served from <source>/download/<version>/<file>.
+const defaultReleaseSource = "https://github.com/ArturC03/r2d2-cli/releases"
+
+// Name of the checksums file goreleaser publishes with each release
+const releaseChecksumsFile = "checksums.txt"
+
+// releaseSource returns the release source to use, flag first, then environment, then default
+func releaseSource(flagValue string) string {
+	if flagValue != "" {
+		return strings.TrimRight(flagValue, "/")
+	}
+	if env := os.Getenv("R2D2_RELEASE_URL"); env != "" {
+		return strings.TrimRight(env, "/")
+	}
+	return defaultReleaseSource
+}
+
+// releaseArchiveName returns the archive goreleaser builds for a platform
+func releaseArchiveName(goos string, goarch string) string {
+	return fmt.Sprintf("r2d2-cli_%s_%s.tar.gz", goos, goarch)
+}
+
+// releaseTag turns a version into its release tag
+func releaseTag(version string) string {
+	return "v" + strings.TrimPrefix(version, "v")
+}
+
+// compareVersions compares two dotted versions numerically, ignoring a leading v.
+// It returns -1, 0 or 1.
+func compareVersions(a string, b string) int {
+	partsA := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	partsB := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var numA, numB int
+		if i < len(partsA) {
+			numA, _ = strconv.Atoi(strings.SplitN(partsA[i], "-", 2)[0])
+		}
+		if i < len(partsB) {
+			numB, _ = strconv.Atoi(strings.SplitN(partsB[i], "-", 2)[0])
+		}
+		switch {
+		case numA < numB:
+			return -1
+		case numA > numB:
+			return 1
+		}
+	}
+	return 0
+}
+
+// latestRelease asks the release source for the newest version
+func latestRelease(source string) (string, error) {
+	resp, err := http.Get(source + "/latest")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("checking the latest release: %s", resp.Status)
+	}
+
+	// The latest release redirects to the page of its tag
+	tag := path.Base(resp.Request.URL.Path)
+	if tag == "latest" || tag == "" {
+		return "", fmt.Errorf("couldn't find the latest release at %s", source)
+	}
+	return strings.TrimPrefix(tag, "v"), nil
+}
+
+// downloadBytes fetches a whole release asset
+func downloadBytes(url string) ([]byte, error) {
+	body, err := openURL(url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// parseChecksums reads a goreleaser checksums.txt ("<sha256>  <file>" per line)
+func parseChecksums(content []byte) map[string]string {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+		}
+	}
+	return sums
+}
+
+// extractBinary returns the r2d2 executable inside a release archive
+func extractBinary(archive []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in the release archive", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeReg && path.Base(header.Name) == name {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// downloadRelease fetches the archive of a version for the running platform,
+// verifies it against checksums.txt and returns the r2d2 binary inside it
+func downloadRelease(source string, version string) ([]byte, error) {
+	base := source + "/download/" + releaseTag(version) + "/"
+	archiveName := releaseArchiveName(runtime.GOOS, runtime.GOARCH)
+
+	checksums, err := downloadBytes(base + releaseChecksumsFile)
+	if err != nil {
+		return nil, err
+	}
+	want, ok := parseChecksums(checksums)[archiveName]
+	if !ok {
+		return nil, fmt.Errorf("%s has no entry for %s", releaseChecksumsFile, archiveName)
+	}
+
+	archive, err := downloadBytes(base + archiveName)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(archive)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("checksum mismatch for %s: got %s, want %s", archiveName, got, want)
+	}
+
+	binaryName := "r2d2"
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+	return extractBinary(archive, binaryName)
+}
+
+// replaceExecutable swaps the binary at target for binary. The old binary is kept
+// until the new one answers `version`, and is restored if anything fails.
+func replaceExecutable(target string, binary []byte) (err error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+
+	// Written next to the target so the final rename stays on one filesystem
+	newPath := target + ".new"
+	oldPath := target + ".old"
+	if err := os.WriteFile(newPath, binary, info.Mode().Perm()|0111); err != nil {
+		return err
+	}
+	defer os.Remove(newPath)
+
+	if err := os.Rename(target, oldPath); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(target)
+			if rollbackErr := os.Rename(oldPath, target); rollbackErr != nil {
+				err = fmt.Errorf("%v (rollback failed: %v)", err, rollbackErr)
+			}
+			return
+		}
+		os.Remove(oldPath)
+	}()
+
+	if err := os.Rename(newPath, target); err != nil {
+		return err
+	}
+
+	if output, err := exec.Command(target, "version").CombinedOutput(); err != nil {
+		return fmt.Errorf("new binary failed to start: %v\n%s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Upgrade replaces the running binary with the given version, or the latest one.
+// With check, it only reports whether an upgrade is available.
+func Upgrade(version string, check bool, source string) error {
+	source = releaseSource(source)
+
+	target := version
+	if target == "" {
+		latest, err := latestRelease(source)
+		if err != nil {
+			fmt.Println(ErrorMessage(err.Error()))
+			return err
+		}
+		target = latest
+	}
+	target = strings.TrimPrefix(target, "v")
+
+	if check {
+		if compareVersions(Version, target) < 0 {
+			fmt.Println(InfoMessage(fmt.Sprintf("Version %s is available (current: %s). Run 'r2d2 upgrade' to install it.", target, Version)))
+		} else {
+			fmt.Println(InfoMessage(fmt.Sprintf("R2D2 %s is up to date", Version)))
+		}
+		return nil
+	}
+
+	// Without an explicit version only newer releases are installed
+	if version == "" && compareVersions(Version, target) >= 0 {
+		fmt.Println(InfoMessage(fmt.Sprintf("R2D2 %s is up to date", Version)))
+		return nil
+	}
+
+	binary, err := downloadRelease(source, target)
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err == nil {
+		err = replaceExecutable(exe, binary)
+	}
+	if err != nil {
+		fmt.Println(ErrorMessage(fmt.Sprintf("Upgrade failed, keeping %s: %v", Version, err)))
+		return err
+	}
+
+	fmt.Println(InfoMessage(fmt.Sprintf("Upgraded R2D2 from %s to %s", Version, target)))
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"0.2.3", "0.2.4", -1},
+		{"0.2.4", "0.2.3", 1},
+		{"v1.0.0", "1.0.0", 0},
+		{"0.10.0", "0.9.9", 1},
+		{"1.0", "1.0.1", -1},
+		{"1.2.0-rc1", "1.2.0", 0},
+	}
+
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	content := []byte("ABC123  r2d2-cli_linux_amd64.tar.gz\ndef456 *r2d2-cli_darwin_arm64.tar.gz\n\nbroken line here\n")
+	sums := parseChecksums(content)
+
+	if sums["r2d2-cli_linux_amd64.tar.gz"] != "abc123" || sums["r2d2-cli_darwin_arm64.tar.gz"] != "def456" || len(sums) != 2 {
+		t.Errorf("parseChecksums() = %v", sums)
+	}
+}
+
+// makeReleaseArchive builds a release tar.gz holding an r2d2 binary
+func makeReleaseArchive(t *testing.T, binary []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string][]byte{"README.md": []byte("readme"), "r2d2": binary} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// newReleaseServer serves a GitHub-like release source with one version
+func newReleaseServer(t *testing.T, version string, archive []byte, checksum string) *httptest.Server {
+	t.Helper()
+	archiveName := releaseArchiveName(runtime.GOOS, runtime.GOARCH)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/releases/tag/v"+version, http.StatusFound)
+	})
+	mux.HandleFunc("/releases/tag/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "release page")
+	})
+	mux.HandleFunc("/releases/download/v"+version+"/"+releaseChecksumsFile, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s  %s\n", checksum, archiveName)
+	})
+	mux.HandleFunc("/releases/download/v"+version+"/"+archiveName, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDownloadRelease(t *testing.T) {
+	archive := makeReleaseArchive(t, []byte("new binary"))
+	sum := sha256.Sum256(archive)
+	server := newReleaseServer(t, "9.9.9", archive, hex.EncodeToString(sum[:]))
+	source := server.URL + "/releases"
+
+	latest, err := latestRelease(source)
+	if err != nil || latest != "9.9.9" {
+		t.Fatalf("latestRelease() = %q, %v, want 9.9.9", latest, err)
+	}
+
+	binary, err := downloadRelease(source, latest)
+	if err != nil {
+		t.Fatalf("downloadRelease() error = %v", err)
+	}
+	if string(binary) != "new binary" {
+		t.Errorf("downloadRelease() = %q, want the binary from the archive", binary)
+	}
+}
+
+func TestDownloadReleaseChecksumMismatch(t *testing.T) {
+	archive := makeReleaseArchive(t, []byte("new binary"))
+	server := newReleaseServer(t, "9.9.9", archive, strings.Repeat("0", 64))
+
+	_, err := downloadRelease(server.URL+"/releases", "9.9.9")
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("downloadRelease() error = %v, want a checksum mismatch", err)
+	}
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as binaries")
+	}
+
+	tests := []struct {
+		name      string
+		newBinary string
+		wantErr   bool
+		wantFinal string
+	}{
+		{"working binary", "#!/bin/sh\necho new\n", false, "#!/bin/sh\necho new\n"},
+		{"broken binary rolls back", "#!/bin/sh\nexit 1\n", true, "#!/bin/sh\necho old\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := filepath.Join(t.TempDir(), "r2d2")
+			if err := os.WriteFile(target, []byte("#!/bin/sh\necho old\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := replaceExecutable(target, []byte(tt.newBinary))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("replaceExecutable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			content, _ := os.ReadFile(target)
+			if string(content) != tt.wantFinal {
+				t.Errorf("binary after replaceExecutable() = %q, want %q", content, tt.wantFinal)
+			}
+			for _, leftover := range []string{target + ".new", target + ".old"} {
+				if _, err := os.Stat(leftover); err == nil {
+					t.Errorf("%s left behind", filepath.Base(leftover))
+				}
+			}
+		})
+	}
+}
+
+func TestReleaseSource(t *testing.T) {
+	t.Setenv("R2D2_RELEASE_URL", "")
+	if got := releaseSource(""); got != defaultReleaseSource {
+		t.Errorf("releaseSource() = %q, want the default", got)
+	}
+
+	t.Setenv("R2D2_RELEASE_URL", "http://localhost:8080/releases/")
+	if got := releaseSource(""); got != "http://localhost:8080/releases" {
+		t.Errorf("releaseSource() = %q, want the environment value", got)
+	}
+	if got := releaseSource("http://mirror/r"); got != "http://mirror/r" {
+		t.Errorf("releaseSource() = %q, want the flag value", got)
+	}
+}
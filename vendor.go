@@ -0,0 +1,284 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Layout of a vendored project
+const (
+	vendorDir         = "vendor"
+	vendorModulesFile = "modules.json"
+	stdLibraryFile    = "std.r2d2"
+)
+
+// vendorModulesPath returns the file recording what was vendored in a project
+func vendorModulesPath(root string) string {
+	return filepath.Join(root, vendorDir, vendorModulesFile)
+}
+
+// loadVendorModules reads vendor/modules.json, returning nil when the project isn't vendored
+func loadVendorModules(root string) (*Lockfile, error) {
+	content, err := os.ReadFile(vendorModulesPath(root))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	modules := &Lockfile{}
+	if err := json.Unmarshal(content, modules); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", filepath.Join(vendorDir, vendorModulesFile), err)
+	}
+	if err := modules.checkNames(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", filepath.Join(vendorDir, vendorModulesFile), err)
+	}
+	return modules, nil
+}
+
+// vendoredImportDirs maps the vendored packages of a project to their directory in vendor/,
+// or returns nil when the project isn't vendored. A vendor/ that doesn't match the lock
+// file is an error, so a stale vendor/ can't silently drop or pin a dependency.
+func vendoredImportDirs(root string) (map[string]string, error) {
+	modules, err := loadVendorModules(root)
+	if err != nil || modules == nil {
+		return nil, err
+	}
+	lock, err := loadLockfile(root)
+	if err != nil {
+		return nil, err
+	}
+	if problems := vendorInconsistencies(lock, modules); len(problems) > 0 {
+		return nil, fmt.Errorf("%s is out of date, run 'r2d2 vendor':\n  %s",
+			filepath.Join(vendorDir, vendorModulesFile), strings.Join(problems, "\n  "))
+	}
+
+	dirs := make(map[string]string)
+	for _, pkg := range modules.Packages {
+		dirs[pkg.Name] = filepath.Join(root, vendorDir, pkg.Name)
+	}
+	return dirs, nil
+}
+
+// vendorInconsistencies compares the lock file with vendor/modules.json, like Go's
+// vendor consistency check. std.r2d2 is only vendored, it has no lock entry.
+func vendorInconsistencies(lock *Lockfile, modules *Lockfile) []string {
+	var problems []string
+	for _, pkg := range lock.Packages {
+		vendored := modules.Find(pkg.Name)
+		switch {
+		case vendored == nil:
+			problems = append(problems, fmt.Sprintf("%s is in %s but not vendored", pkg.Name, lockFile))
+		case vendored.Hash != pkg.Hash || vendored.Version != pkg.Version:
+			problems = append(problems, fmt.Sprintf("%s is locked at %s but vendored at %s", pkg.Name, lockedVersion(pkg), lockedVersion(*vendored)))
+		}
+	}
+	for _, vendored := range modules.Packages {
+		if vendored.Name != stdLibraryFile && lock.Find(vendored.Name) == nil {
+			problems = append(problems, fmt.Sprintf("%s is vendored but not in %s", vendored.Name, lockFile))
+		}
+	}
+	return problems
+}
+
+// lockedVersion describes the version of a locked package, or its hash when it has none
+func lockedVersion(pkg LockedPackage) string {
+	if pkg.Version != "" {
+		return pkg.Version
+	}
+	return pkg.Hash
+}
+
+// vendorSource records where std.r2d2 was vendored from without the machine-specific part
+// of the path: relative to the project, or to the home directory
+func vendorSource(root string, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") && !filepath.IsAbs(rel) {
+		return filepath.ToSlash(rel)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if rel, err := filepath.Rel(home, path); err == nil && !strings.HasPrefix(rel, "..") && !filepath.IsAbs(rel) {
+			return "~/" + filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(path)
+}
+
+// findStdLibrary looks for std.r2d2 in R2D2_STD, the project, next to the executable and in ~/.r2d2
+func findStdLibrary(root string) string {
+	candidates := []string{os.Getenv("R2D2_STD"), filepath.Join(root, stdLibraryFile)}
+	if exe, err := os.Executable(); err == nil {
+		candidates = append(candidates, filepath.Join(filepath.Dir(exe), stdLibraryFile))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".r2d2", stdLibraryFile))
+	}
+
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// hashFile hashes the content of a single file
+func hashFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// VendorPackages copies every locked dependency of the project, and std.r2d2 when it
+// can be found, into vendor/. The dependencies are installed first if needed.
+func VendorPackages(root string) ([]LockedPackage, error) {
+	packages, err := InstallPackages(root)
+	if err != nil {
+		return nil, err
+	}
+	cacheDir, err := packageCacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	vendor := filepath.Join(root, vendorDir)
+	if err := os.RemoveAll(vendor); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(vendor, 0755); err != nil {
+		return nil, err
+	}
+
+	modules := &Lockfile{Version: 1}
+	for _, pkg := range packages {
+		if err := copyDir(packageDir(cacheDir, pkg), filepath.Join(vendor, pkg.Name)); err != nil {
+			return modules.Packages, err
+		}
+		modules.set(pkg)
+	}
+
+	if std := findStdLibrary(root); std != "" {
+		content, err := os.ReadFile(std)
+		if err != nil {
+			return modules.Packages, err
+		}
+		if err := os.WriteFile(filepath.Join(vendor, stdLibraryFile), content, 0644); err != nil {
+			return modules.Packages, err
+		}
+
+		hash, err := hashFile(std)
+		if err != nil {
+			return modules.Packages, err
+		}
+		modules.set(LockedPackage{Name: stdLibraryFile, Source: vendorSource(root, std), Hash: hash})
+	}
+
+	return modules.Packages, writeJSONFile(vendorModulesPath(root), modules)
+}
+
+// VerifyVendor checks that vendor/ holds exactly the locked dependencies, unmodified.
+// Every problem found is returned.
+func VerifyVendor(root string) ([]string, error) {
+	lock, err := loadLockfile(root)
+	if err != nil {
+		return nil, err
+	}
+	modules, err := loadVendorModules(root)
+	if err != nil {
+		return nil, err
+	}
+	if modules == nil {
+		return nil, fmt.Errorf("%s not found, run 'r2d2 vendor' first", filepath.Join(vendorDir, vendorModulesFile))
+	}
+
+	var problems []string
+	for _, pkg := range lock.Packages {
+		dir := filepath.Join(root, vendorDir, pkg.Name)
+		if _, err := os.Stat(dir); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: missing from %s", pkg.Name, vendorDir))
+			continue
+		}
+
+		hash, err := hashPackageDir(dir)
+		if err != nil {
+			return problems, err
+		}
+		if hash != pkg.Hash {
+			problems = append(problems, fmt.Sprintf("%s: vendored hash %s doesn't match %s in %s", pkg.Name, hash, pkg.Hash, lockFile))
+		}
+	}
+
+	for _, vendored := range modules.Packages {
+		if vendored.Name == stdLibraryFile {
+			hash, err := hashFile(filepath.Join(root, vendorDir, stdLibraryFile))
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", stdLibraryFile, err))
+			} else if hash != vendored.Hash {
+				problems = append(problems, fmt.Sprintf("%s: vendored hash %s doesn't match %s", stdLibraryFile, hash, vendored.Hash))
+			}
+			continue
+		}
+
+		if lock.Find(vendored.Name) == nil {
+			problems = append(problems, fmt.Sprintf("%s: vendored but not in %s", vendored.Name, lockFile))
+		}
+	}
+
+	return problems, nil
+}
+
+// RunVendor vendors the dependencies of the project in the current directory,
+// or only verifies them with verify.
+func RunVendor(verify bool) error {
+	root, err := findProjectRoot(".")
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+
+	if verify {
+		problems, err := VerifyVendor(root)
+		if err != nil {
+			fmt.Println(ErrorMessage(err.Error()))
+			return err
+		}
+		for _, problem := range problems {
+			fmt.Println(ErrorMessage(problem))
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%d vendored module(s) don't match", len(problems))
+		}
+
+		fmt.Println(InfoMessage("Vendored modules match " + lockFile))
+		return nil
+	}
+
+	vendored, err := VendorPackages(root)
+	if err != nil {
+		fmt.Println(ErrorMessage(err.Error()))
+		return err
+	}
+
+	names := make([]string, 0, len(vendored))
+	for _, pkg := range vendored {
+		names = append(names, pkg.Name)
+	}
+	if len(names) == 0 {
+		fmt.Println(InfoMessage("Nothing to vendor"))
+		return nil
+	}
+	fmt.Println(InfoMessage(fmt.Sprintf("Vendored %s into %s/", strings.Join(names, ", "), vendorDir)))
+	return nil
+}
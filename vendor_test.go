@@ -0,0 +1,161 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVendorPackages(t *testing.T) {
+	t.Setenv("R2D2_CACHE", t.TempDir())
+	t.Setenv("R2D2_STD", "")
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"std.r2d2":              "module std {}\n",
+		"libs/shared/util.r2d2": "module Util {}\n",
+	})
+
+	if _, err := AddPackage(root, "libs/shared", ""); err != nil {
+		t.Fatalf("AddPackage() error = %v", err)
+	}
+
+	vendored, err := VendorPackages(root)
+	if err != nil {
+		t.Fatalf("VendorPackages() error = %v", err)
+	}
+	if len(vendored) != 2 || vendored[0].Name != "shared" || vendored[1].Name != stdLibraryFile {
+		t.Errorf("VendorPackages() = %+v, want shared and std.r2d2", vendored)
+	}
+
+	for _, file := range []string{"shared/util.r2d2", stdLibraryFile, vendorModulesFile} {
+		if _, err := os.Stat(filepath.Join(root, vendorDir, file)); err != nil {
+			t.Errorf("vendor/%s not written: %v", file, err)
+		}
+	}
+
+	// Imports resolve to vendor/ instead of the cache
+	dirs, err := packageImportDirs(root)
+	if err != nil {
+		t.Fatalf("packageImportDirs() error = %v", err)
+	}
+	code := rewritePackageImports("use \"std.r2d2\";\nuse \"shared/util\";\n", dirs)
+	want := filepath.ToSlash(filepath.Join(root, vendorDir))
+	if strings.Count(code, want) != 2 {
+		t.Errorf("imports not resolved to vendor/:\n%s", code)
+	}
+
+	problems, err := VerifyVendor(root)
+	if err != nil || len(problems) != 0 {
+		t.Errorf("VerifyVendor() = %v, %v, want no problems", problems, err)
+	}
+}
+
+func TestVerifyVendorDetectsChanges(t *testing.T) {
+	t.Setenv("R2D2_CACHE", t.TempDir())
+	t.Setenv("R2D2_STD", "")
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"std.r2d2":              "module std {}\n",
+		"libs/shared/util.r2d2": "module Util {}\n",
+		"libs/extra/extra.r2d2": "module Extra {}\n",
+	})
+
+	for _, path := range []string{"libs/shared", "libs/extra"} {
+		if _, err := AddPackage(root, path, ""); err != nil {
+			t.Fatalf("AddPackage() error = %v", err)
+		}
+	}
+	if _, err := VendorPackages(root); err != nil {
+		t.Fatalf("VendorPackages() error = %v", err)
+	}
+
+	writeFiles(t, filepath.Join(root, vendorDir), map[string]string{
+		"shared/util.r2d2": "module Util { }\n",
+		stdLibraryFile:     "module std { }\n",
+	})
+	if err := os.RemoveAll(filepath.Join(root, vendorDir, "extra")); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := VerifyVendor(root)
+	if err != nil {
+		t.Fatalf("VerifyVendor() error = %v", err)
+	}
+
+	wants := []string{"shared: vendored hash", "extra: missing", "std.r2d2: vendored hash"}
+	joined := strings.Join(problems, "\n")
+	for _, want := range wants {
+		if !strings.Contains(joined, want) {
+			t.Errorf("VerifyVendor() problems = %v, want one containing %q", problems, want)
+		}
+	}
+}
+
+func TestVerifyVendorWithoutVendor(t *testing.T) {
+	if _, err := VerifyVendor(t.TempDir()); err == nil {
+		t.Error("VerifyVendor() of a project without vendor/ should fail")
+	}
+}
+
+func TestVendoredImportDirsStale(t *testing.T) {
+	t.Setenv("R2D2_CACHE", t.TempDir())
+	t.Setenv("R2D2_STD", "")
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"std.r2d2":              "module std {}\n",
+		"libs/shared/util.r2d2": "module Util {}\n",
+		"libs/extra/extra.r2d2": "module Extra {}\n",
+	})
+
+	if _, err := AddPackage(root, "libs/shared", ""); err != nil {
+		t.Fatalf("AddPackage() error = %v", err)
+	}
+	if _, err := VendorPackages(root); err != nil {
+		t.Fatalf("VendorPackages() error = %v", err)
+	}
+
+	// std.r2d2 is recorded relative to the project, not with the path of this machine
+	modules, _ := loadVendorModules(root)
+	if std := modules.Find(stdLibraryFile); std == nil || std.Source != stdLibraryFile {
+		t.Errorf("std.r2d2 vendored from %+v, want source %q", std, stdLibraryFile)
+	}
+
+	// A dependency added after vendoring must not silently stop resolving
+	if _, err := AddPackage(root, "libs/extra", ""); err != nil {
+		t.Fatalf("AddPackage() error = %v", err)
+	}
+	_, err := vendoredImportDirs(root)
+	if err == nil || !strings.Contains(err.Error(), "extra is in r2d2.lock but not vendored") || !strings.Contains(err.Error(), "r2d2 vendor") {
+		t.Errorf("vendoredImportDirs() error = %v, want one asking to run r2d2 vendor", err)
+	}
+
+	if _, err := VendorPackages(root); err != nil {
+		t.Fatalf("VendorPackages() error = %v", err)
+	}
+	if dirs, err := vendoredImportDirs(root); err != nil || len(dirs) != 3 {
+		t.Errorf("vendoredImportDirs() after r2d2 vendor = %v, %v", dirs, err)
+	}
+}
+
+func TestVendorSource(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	root := filepath.Join(home, "project")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(root, "std.r2d2"), "std.r2d2"},
+		{filepath.Join(root, "lib", "std.r2d2"), "lib/std.r2d2"},
+		{filepath.Join(home, ".r2d2", "std.r2d2"), "~/.r2d2/std.r2d2"},
+		{filepath.Join(string(filepath.Separator), "opt", "r2d2", "std.r2d2"), "std.r2d2"},
+	}
+
+	for _, tt := range tests {
+		if got := vendorSource(root, tt.path); got != tt.want {
+			t.Errorf("vendorSource(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// Set at release time with -ldflags "-X main.commit=... -X main.buildDate=...".
+// Builds from a git checkout fall back to the VCS information embedded by go build.
+var (
+	commit    = ""
+	buildDate = ""
+)
+
+// Module paths of the libraries reported by version --verbose
+const (
+	compilerModule = "github.com/ArturC03/r2d2"
+	stylesModule   = "github.com/ArturC03/r2d2Styles"
+)
+
+// JS runtimes that can run the generated code, in order of preference
+var jsRuntimes = []string{"deno", "node", "bun"}
+
+// RuntimeInfo describes a JS runtime found on the PATH
+type RuntimeInfo struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Version string `json:"version"`
+}
+
+// BuildInfo is everything version --verbose reports
+type BuildInfo struct {
+	Version         string        `json:"version"`
+	CompilerVersion string        `json:"compilerVersion"`
+	StylesVersion   string        `json:"stylesVersion"`
+	Commit          string        `json:"commit"`
+	BuildDate       string        `json:"buildDate"`
+	GoVersion       string        `json:"goVersion"`
+	OS              string        `json:"os"`
+	Arch            string        `json:"arch"`
+	Runtimes        []RuntimeInfo `json:"runtimes"`
+}
+
+// collectBuildInfo gathers the versions of the CLI, its libraries and the JS runtimes
+func collectBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:         Version,
+		CompilerVersion: "unknown",
+		StylesVersion:   "unknown",
+		Commit:          commit,
+		BuildDate:       buildDate,
+		GoVersion:       runtime.Version(),
+		OS:              runtime.GOOS,
+		Arch:            runtime.GOARCH,
+		Runtimes:        detectRuntimes(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		applyBuildInfo(&info, build)
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
+
+// applyBuildInfo fills the library versions and VCS details from the embedded build info
+func applyBuildInfo(info *BuildInfo, build *debug.BuildInfo) {
+	for _, dep := range build.Deps {
+		version := dep.Version
+		if dep.Replace != nil {
+			version = dep.Replace.Version
+			if version == "" {
+				version = "(replaced by " + dep.Replace.Path + ")"
+			}
+		}
+
+		switch dep.Path {
+		case compilerModule:
+			info.CompilerVersion = version
+		case stylesModule:
+			info.StylesVersion = version
+		}
+	}
+
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.BuildDate == "" {
+				info.BuildDate = setting.Value
+			}
+		case "vcs.modified":
+			if setting.Value == "true" && info.Commit != "" && !strings.HasSuffix(info.Commit, "-dirty") {
+				info.Commit += "-dirty"
+			}
+		}
+	}
+}
+
+// detectRuntimes finds the JS runtimes on the PATH and asks them for their version
+func detectRuntimes() []RuntimeInfo {
+	found := []RuntimeInfo{}
+	for _, name := range jsRuntimes {
+		if rt, ok := detectRuntime(name); ok {
+			found = append(found, rt)
+		}
+	}
+	return found
+}
+
+// detectRuntime looks up one JS runtime
+func detectRuntime(name string) (RuntimeInfo, bool) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return RuntimeInfo{}, false
+	}
+
+	rt := RuntimeInfo{Name: name, Path: path, Version: "unknown"}
+
+	// A broken runtime shouldn't hang the version command
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "--version").Output()
+	if version := parseRuntimeVersion(string(output)); err == nil && version != "" {
+		rt.Version = version
+	}
+	return rt, true
+}
+
+// parseRuntimeVersion extracts the version from the --version output of a runtime,
+// like "deno 2.1.4 (stable, ...)" or "v22.3.0"
+func parseRuntimeVersion(output string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	for _, field := range strings.Fields(line) {
+		field = strings.TrimPrefix(field, "v")
+		if field != "" && field[0] >= '0' && field[0] <= '9' {
+			return field
+		}
+	}
+	return ""
+}
+
+// ShowVersionVerbose displays the version with build metadata and the JS runtimes found.
+// With asJSON it prints a JSON document instead.
+func ShowVersionVerbose(asJSON bool) error {
+	info := collectBuildInfo()
+
+	if asJSON {
+		content, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(content))
+		return nil
+	}
+
+	fmt.Println(InfoMessage("R2D2 Language - version " + info.Version))
+	fmt.Println()
+
+	rows := [][2]string{
+		{"Compiler", info.CompilerVersion},
+		{"Styles", info.StylesVersion},
+		{"Commit", info.Commit},
+		{"Built", info.BuildDate},
+		{"Go", info.GoVersion},
+		{"Platform", info.OS + "/" + info.Arch},
+	}
+	for _, row := range rows {
+		fmt.Printf("  %s %s\n", categoryStyle.Render(fmt.Sprintf("%-10s", row[0])), row[1])
+	}
+
+	fmt.Println()
+	if len(info.Runtimes) == 0 {
+		fmt.Println(ErrorMessage("No JavaScript runtime found (r2d2 run, eval and repl need deno)"))
+		return nil
+	}
+	for _, rt := range info.Runtimes {
+		fmt.Printf("  %s %s %s\n", categoryStyle.Render(fmt.Sprintf("%-10s", rt.Name)), rt.Version, exampleStyle.Render(rt.Path))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestParseRuntimeVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"deno 2.1.4 (stable, release, x86_64-unknown-linux-gnu)\nv8 13.0.245.12\ntypescript 5.6.2\n", "2.1.4"},
+		{"v22.3.0\n", "22.3.0"},
+		{"1.1.38\n", "1.1.38"},
+		{"", ""},
+		{"command not found", ""},
+	}
+
+	for _, tt := range tests {
+		if got := parseRuntimeVersion(tt.output); got != tt.want {
+			t.Errorf("parseRuntimeVersion(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestApplyBuildInfo(t *testing.T) {
+	build := &debug.BuildInfo{
+		Deps: []*debug.Module{
+			{Path: compilerModule, Version: "v0.2.4"},
+			{Path: stylesModule, Version: "v0.1.0", Replace: &debug.Module{Path: "../r2d2Styles"}},
+			{Path: "github.com/charmbracelet/lipgloss", Version: "v1.0.0"},
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "4c3c753"},
+			{Key: "vcs.time", Value: "2025-06-01T10:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	info := BuildInfo{}
+	applyBuildInfo(&info, build)
+
+	if info.CompilerVersion != "v0.2.4" {
+		t.Errorf("CompilerVersion = %q, want v0.2.4", info.CompilerVersion)
+	}
+	if info.StylesVersion != "(replaced by ../r2d2Styles)" {
+		t.Errorf("StylesVersion = %q, want the replacement", info.StylesVersion)
+	}
+	if info.Commit != "4c3c753-dirty" || info.BuildDate != "2025-06-01T10:00:00Z" {
+		t.Errorf("Commit, BuildDate = %q, %q", info.Commit, info.BuildDate)
+	}
+
+	// Values set with -ldflags win over the VCS information
+	info = BuildInfo{Commit: "abcdef", BuildDate: "release"}
+	applyBuildInfo(&info, &debug.BuildInfo{Settings: build.Settings[:2]})
+	if info.Commit != "abcdef" || info.BuildDate != "release" {
+		t.Errorf("ldflags values overwritten: %q, %q", info.Commit, info.BuildDate)
+	}
+}
+
+func TestDetectRuntimes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as runtimes")
+	}
+
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho 'v20.1.0'\n"
+	if err := os.WriteFile(filepath.Join(dir, "node"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+
+	runtimes := detectRuntimes()
+	if len(runtimes) != 1 {
+		t.Fatalf("detectRuntimes() = %+v, want only node", runtimes)
+	}
+	if runtimes[0].Name != "node" || runtimes[0].Version != "20.1.0" || runtimes[0].Path != filepath.Join(dir, "node") {
+		t.Errorf("detectRuntimes() = %+v", runtimes[0])
+	}
+}